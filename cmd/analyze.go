@@ -12,23 +12,52 @@ import (
 	"github.com/Heman10x-NGU/threadgraph/internal/reporter"
 )
 
+var (
+	flagFrom          string
+	flagTo            string
+	flagRegion        string
+	flagGoroutineDump string
+)
+
 var analyzeCmd = &cobra.Command{
-	Use:   "analyze <trace.out>",
-	Short: "Analyze an existing Go execution trace file",
+	Use:   "analyze <trace.out> | --goroutine-dump <dump.txt>",
+	Short: "Analyze an existing Go execution trace file or goroutine dump",
+	Long: `Analyze reads a Go execution trace written by 'go test -trace' or
+runtime/trace and reports concurrency issues.
+
+With --goroutine-dump it instead reads a goroutine dump, such as the output
+of a test that hit 'panic: test timed out', a SIGQUIT dump, or
+/debug/pprof/goroutine?debug=2. A dump is a single snapshot, so it supports
+leak, mutex-contention and WaitGroup deadlock detection; use it when the
+trace is truncated or missing.`,
 	Example: `  threadgraph analyze ./trace.out
   threadgraph analyze ./trace.out --format json --output findings.json
   threadgraph analyze ./trace.out --no-llm
-  threadgraph analyze ./trace.out --debug-filtered`,
-	Args: cobra.ExactArgs(1),
+  threadgraph analyze ./trace.out --debug-filtered
+  threadgraph analyze ./trace.out --from 2.5s --to 9s
+  threadgraph analyze ./trace.out --region TestCheckout/payment
+  go test -timeout 30s ./pkg/server > out.txt 2>&1; threadgraph analyze --goroutine-dump out.txt`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runAnalyze,
 }
 
 func init() {
 	rootCmd.AddCommand(analyzeCmd)
+	analyzeCmd.Flags().StringVar(&flagFrom, "from", "", "Analyze only events from this offset into the trace (e.g. 2.5s)")
+	analyzeCmd.Flags().StringVar(&flagTo, "to", "", "Analyze only events up to this offset into the trace; blocked goroutines are evaluated here")
+	analyzeCmd.Flags().StringVar(&flagRegion, "region", "", "Analyze only the first runtime/trace user region with this name")
+	analyzeCmd.Flags().StringVar(&flagGoroutineDump, "goroutine-dump", "", "Analyze a goroutine dump (test timeout output, SIGQUIT, debug=2) instead of a trace")
 }
 
 func runAnalyze(cmd *cobra.Command, args []string) error {
-	tracePath := args[0]
+	switch {
+	case flagGoroutineDump != "" && len(args) > 0:
+		return fmt.Errorf("give either a trace file or --goroutine-dump, not both")
+	case flagGoroutineDump == "" && len(args) == 0:
+		return fmt.Errorf("missing trace file (or --goroutine-dump <file>)")
+	case flagGoroutineDump != "" && (flagFrom != "" || flagTo != "" || flagRegion != ""):
+		return fmt.Errorf("--from/--to/--region apply to traces, not goroutine dumps")
+	}
 
 	minBlock, err := time.ParseDuration(flagMinBlock)
 	if err != nil {
@@ -38,28 +67,72 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 	opts := detector.Options{
 		MinBlock:      minBlock,
 		DebugFiltered: flagDebugFiltered,
+		Streaming:     flagStreaming,
+		Timeline:      flagFormat == "html",
+		Region:        flagRegion,
+	}
+	applyConfig(&opts)
+	if flagRegion != "" && (flagFrom != "" || flagTo != "") {
+		return fmt.Errorf("--region cannot be combined with --from/--to")
+	}
+	if flagFrom != "" {
+		if opts.From, err = time.ParseDuration(flagFrom); err != nil {
+			return fmt.Errorf("--from: %w", err)
+		}
+	}
+	if flagTo != "" {
+		if opts.To, err = time.ParseDuration(flagTo); err != nil {
+			return fmt.Errorf("--to: %w", err)
+		}
+		if opts.To <= opts.From {
+			return fmt.Errorf("--to (%s) must be after --from (%s)", opts.To, opts.From)
+		}
 	}
 
-	result, err := detector.Analyze(tracePath, opts)
+	var result *detector.Result
+	if flagGoroutineDump != "" {
+		result, err = detector.AnalyzeGoroutineDump(flagGoroutineDump, opts)
+	} else {
+		result, err = detector.Analyze(args[0], opts)
+	}
 	if err != nil {
 		return fmt.Errorf("analyze: %w", err)
 	}
 
-	explanation := ""
-	if !flagNoLLM {
-		apiKey := os.Getenv("ANTHROPIC_API_KEY")
-		if apiKey != "" && len(result.Findings) > 0 {
-			exp, err := llm.Explain(result.Findings, apiKey)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "warn: LLM explanation failed: %v\n", err)
-			} else {
-				explanation = exp
-			}
-		}
-	}
+	applySuppressions(result)
+
+	explanation := explainFindings(result)
 
 	baselineErr := applyBaseline(result)
 
+	if err := writeReport(result, explanation); err != nil {
+		return err
+	}
+
+	return baselineErr
+}
+
+// explainFindings asks Claude to explain result's findings unless --no-llm is
+// set or ANTHROPIC_API_KEY is missing. Failures are reported as warnings.
+func explainFindings(result *detector.Result) string {
+	if flagNoLLM {
+		return ""
+	}
+	apiKey := os.Getenv("ANTHROPIC_API_KEY")
+	if apiKey == "" || len(result.Findings) == 0 {
+		return ""
+	}
+	exp, err := llm.Explain(result.Findings, apiKey)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warn: LLM explanation failed: %v\n", err)
+		return ""
+	}
+	return exp
+}
+
+// writeReport renders result in the --format format to the --output
+// destination.
+func writeReport(result *detector.Result, explanation string) error {
 	out, cleanup, err := outputWriter()
 	if err != nil {
 		return err
@@ -68,14 +141,19 @@ func runAnalyze(cmd *cobra.Command, args []string) error {
 
 	switch flagFormat {
 	case "json":
-		if err := reporter.WriteJSON(out, result, explanation); err != nil {
-			return err
-		}
+		return reporter.WriteJSON(out, result, explanation)
+	case "sarif":
+		return reporter.WriteSARIF(out, result)
+	case "junit":
+		return reporter.WriteJUnit(out, result, explanation)
+	case "html":
+		return reporter.WriteHTML(out, result, explanation)
+	case "markdown":
+		return reporter.WriteMarkdown(out, result, explanation, flagLinkBase)
 	default:
 		reporter.WriteTerminal(out, result, explanation)
 	}
-
-	return baselineErr
+	return nil
 }
 
 // outputWriter returns a writer for the output destination (file or stdout).
@@ -0,0 +1,217 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/live"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagAttachDuration string
+	flagSnapshots      int
+	flagServiceRoots   []string
+	flagNoTrace        bool
+)
+
+var attachCmd = &cobra.Command{
+	Use:   "attach <http://host:port>",
+	Short: "Analyze a running service through its net/http/pprof endpoints",
+	Long: `Attach samples a running service that serves net/http/pprof: it records
+an execution trace for --duration from /debug/pprof/trace and takes
+--snapshots goroutine dumps from /debug/pprof/goroutine?debug=2 spread over
+the same period, then runs the detectors over both.
+
+A service has no test goroutines, so instead of the test-ownership filter
+only goroutines whose stacks contain a function matching --service-root (and
+their descendants) are reported. Without --service-root every non-runtime
+goroutine is considered. Goroutines already waiting when attach starts, such
+as idle workers, are only reported once the runtime prints their wait, after a
+minute. Dump findings are only kept if the goroutine was blocked at the same
+place in every snapshot, and they are added to the trace findings when the
+trace did not already report the same issue.`,
+	Example: `  threadgraph attach http://localhost:6060
+  threadgraph attach localhost:6060 --duration 10s --snapshots 3
+  threadgraph attach http://10.0.0.5:6060 --service-root '^github.com/acme/api/'
+  threadgraph attach http://localhost:6060 --no-trace --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runAttach,
+}
+
+func init() {
+	rootCmd.AddCommand(attachCmd)
+	attachCmd.Flags().StringVar(&flagAttachDuration, "duration", "5s", "How long to trace the service; snapshots are spread over this period")
+	attachCmd.Flags().IntVar(&flagSnapshots, "snapshots", 2, "Number of goroutine dumps to take")
+	attachCmd.Flags().StringArrayVar(&flagServiceRoots, "service-root", nil, "Regexp matching functions whose goroutines (and descendants) are analyzed (repeatable)")
+	attachCmd.Flags().BoolVar(&flagNoTrace, "no-trace", false, "Only take goroutine dumps; do not record an execution trace")
+}
+
+func runAttach(cmd *cobra.Command, args []string) error {
+	client := live.NewClient(args[0])
+
+	duration, err := time.ParseDuration(flagAttachDuration)
+	if err != nil {
+		return fmt.Errorf("--duration: %w", err)
+	}
+	if flagSnapshots < 1 {
+		return fmt.Errorf("--snapshots must be at least 1")
+	}
+
+	minBlock, err := time.ParseDuration(flagMinBlock)
+	if err != nil {
+		return fmt.Errorf("--min-block: %w", err)
+	}
+
+	opts := detector.Options{
+		MinBlock:      minBlock,
+		DebugFiltered: flagDebugFiltered,
+		Streaming:     flagStreaming,
+		Timeline:      flagFormat == "html",
+		Service:       true,
+	}
+	applyConfig(&opts)
+	if opts.ServiceRoots, err = serviceRoots(); err != nil {
+		return err
+	}
+
+	result, err := attachResult(context.Background(), client, duration, flagSnapshots, !flagNoTrace, opts)
+	if err != nil {
+		return err
+	}
+
+	applySuppressions(result)
+
+	explanation := explainFindings(result)
+
+	baselineErr := applyBaseline(result)
+
+	if err := writeReport(result, explanation); err != nil {
+		return err
+	}
+
+	return baselineErr
+}
+
+// attachResult samples the service behind client for d, taking n goroutine
+// dumps and, if withTrace is set, recording an execution trace at the same
+// time, and analyzes them with opts. Dump findings are added where the trace
+// did not report the same issue; if the trace fails, only the dumps are used.
+func attachResult(ctx context.Context, client *live.Client, d time.Duration, n int, withTrace bool, opts detector.Options) (*detector.Result, error) {
+	// Record the trace in the background while the snapshots are taken.
+	var traceFile string
+	traceErr := make(chan error, 1)
+	traceCtx, cancelTrace := context.WithCancel(ctx)
+	defer cancelTrace()
+	if !withTrace {
+		traceErr <- nil
+	} else {
+		f, err := os.CreateTemp("", "threadgraph-attach-*.out")
+		if err != nil {
+			return nil, fmt.Errorf("create trace file: %w", err)
+		}
+		traceFile = f.Name()
+		f.Close()
+		defer os.Remove(traceFile)
+
+		fmt.Fprintf(os.Stderr, "Tracing %s for %s...\n", client.BaseURL, d)
+		go func() { traceErr <- client.Trace(traceCtx, d, traceFile) }()
+	}
+
+	dumps, err := takeSnapshots(ctx, client, n, d)
+	if err != nil {
+		// Stop the trace and let it finish with traceFile before it is removed.
+		cancelTrace()
+		<-traceErr
+		return nil, err
+	}
+
+	var result *detector.Result
+	if err := <-traceErr; err != nil {
+		fmt.Fprintf(os.Stderr, "warn: trace: %v; using goroutine dumps only\n", err)
+	} else if traceFile != "" {
+		traceOpts := opts
+		if len(dumps) > 1 {
+			// The first dump was taken as tracing started.
+			traceOpts.StartDump = dumps[0]
+		}
+		result, err = detector.Analyze(traceFile, traceOpts)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: analyze trace: %v; using goroutine dumps only\n", err)
+		}
+	}
+
+	snapResult, err := detector.SnapshotsResult(dumps, d, opts)
+	if err != nil {
+		return nil, fmt.Errorf("analyze goroutine dumps: %w", err)
+	}
+	if result == nil {
+		result = snapResult
+	} else {
+		result.Findings = mergeMissing(result.Findings, snapResult.Findings)
+	}
+	result.TraceFile = client.BaseURL
+	return result, nil
+}
+
+// serviceRoots compiles the --service-root patterns.
+func serviceRoots() ([]*regexp.Regexp, error) {
+	var roots []*regexp.Regexp
+	for _, expr := range flagServiceRoots {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("--service-root %q: %w", expr, err)
+		}
+		roots = append(roots, re)
+	}
+	return roots, nil
+}
+
+// takeSnapshots fetches n goroutine dumps evenly spread over d: at the start
+// and end of d when n >= 2, at its end when n == 1.
+func takeSnapshots(ctx context.Context, client *live.Client, n int, d time.Duration) ([]string, error) {
+	var gap time.Duration
+	if n > 1 {
+		gap = d / time.Duration(n-1)
+	} else {
+		time.Sleep(d)
+	}
+
+	dumps := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			time.Sleep(gap)
+		}
+		fmt.Fprintf(os.Stderr, "Taking goroutine dump %d/%d...\n", i+1, n)
+		dump, err := client.GoroutineDump(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("goroutine dump: %w", err)
+		}
+		dumps = append(dumps, dump)
+	}
+	return dumps, nil
+}
+
+// mergeMissing appends the findings in extra whose (kind, location) is not
+// already reported in findings.
+func mergeMissing(findings, extra []detector.Finding) []detector.Finding {
+	type key struct {
+		kind     detector.Kind
+		location string
+	}
+	seen := make(map[key]bool, len(findings))
+	for _, f := range findings {
+		seen[key{f.Kind, f.Location}] = true
+	}
+	for _, f := range extra {
+		if !seen[key{f.Kind, f.Location}] {
+			seen[key{f.Kind, f.Location}] = true
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/http/pprof"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/live"
+)
+
+// attachService serves net/http/pprof for the test binary itself, which
+// runs an idle worker pool from before attach starts and leaks a goroutine
+// while it traces.
+type attachService struct {
+	client *live.Client
+	jobs   chan int
+	leaked chan int
+	leak   sync.Once
+}
+
+func newAttachService(t *testing.T) *attachService {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	srv := httptest.NewServer(mux)
+
+	s := &attachService{client: live.NewClient(srv.URL), jobs: make(chan int), leaked: make(chan int)}
+	for i := 0; i < 4; i++ {
+		go idleWorker(s.jobs)
+	}
+	t.Cleanup(func() {
+		srv.Close()
+		close(s.jobs)
+		close(s.leaked)
+	})
+	return s
+}
+
+// idleWorker waits for jobs that never come during the test; it is not a leak.
+func idleWorker(jobs <-chan int) {
+	for range jobs {
+	}
+}
+
+// leakyWorker waits on a channel nothing sends on until the test ends.
+func leakyWorker(c <-chan int) {
+	<-c
+}
+
+// attach runs attachResult against s, starting the leak once tracing has
+// begun.
+func (s *attachService) attach(t *testing.T, roots ...string) *detector.Result {
+	opts := detector.Options{MinBlock: time.Minute, Service: true}
+	for _, r := range roots {
+		opts.ServiceRoots = append(opts.ServiceRoots, regexp.MustCompile(r))
+	}
+	timer := time.AfterFunc(200*time.Millisecond, func() {
+		s.leak.Do(func() { go leakyWorker(s.leaked) })
+	})
+	defer timer.Stop()
+
+	result, err := attachResult(context.Background(), s.client, time.Second, 2, true, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+func TestAttachReportsLeakNotIdleWorkers(t *testing.T) {
+	s := newAttachService(t)
+	result := s.attach(t)
+
+	var leak bool
+	for _, f := range result.Findings {
+		if strings.Contains(f.Function, "idleWorker") {
+			t.Errorf("idle worker reported: %s %s at %s", f.Kind, f.BlockedOn, f.Location)
+		}
+		if f.Kind == detector.KindGoroutineLeak && strings.HasSuffix(f.Function, ".leakyWorker") {
+			leak = true
+		}
+	}
+	if !leak {
+		t.Errorf("leak in leakyWorker not reported; findings: %+v", result.Findings)
+	}
+}
+
+func TestAttachServiceRoots(t *testing.T) {
+	s := newAttachService(t)
+
+	result := s.attach(t, `\.leakyWorker$`)
+	if len(result.Findings) != 1 || !strings.HasSuffix(result.Findings[0].Function, ".leakyWorker") {
+		t.Errorf("with the leaky root, want only the leakyWorker finding; got %+v", result.Findings)
+	}
+
+	result = s.attach(t, `\.idleWorker$`)
+	if len(result.Findings) != 0 {
+		t.Errorf("with the idle root, want no findings; got %+v", result.Findings)
+	}
+}
+
+func TestAttachSnapshotErrorStopsTrace(t *testing.T) {
+	traceDone := make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/goroutine", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "profiling disabled", http.StatusForbidden)
+	})
+	mux.HandleFunc("/debug/pprof/trace", func(w http.ResponseWriter, r *http.Request) {
+		// Block like a real trace until the client goes away.
+		<-r.Context().Done()
+		close(traceDone)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	_, err := attachResult(context.Background(), live.NewClient(srv.URL), time.Minute, 2, true, detector.Options{})
+	if err == nil || !strings.Contains(err.Error(), "goroutine dump") {
+		t.Fatalf("error = %v, want the goroutine dump failure", err)
+	}
+	select {
+	case <-traceDone:
+	case <-time.After(5 * time.Second):
+		t.Error("trace request still running after attachResult returned")
+	}
+}
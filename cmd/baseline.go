@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"text/tabwriter"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/baseline"
+	"github.com/spf13/cobra"
+)
+
+var flagDryRun bool
+
+var baselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Inspect and maintain --baseline files",
+	Long: `Baseline inspects and rewrites the files written by --save-baseline.
+
+update and prune take a report written with --format json (by any command,
+with or without --baseline) as the current state. Entries still present in
+the report are kept, with their location refreshed; entries the report no
+longer contains are resolved and dropped. update also adds the report's new
+findings, prune never does. Every rewrite is recorded in the file's audit
+log, with the entries it added and removed, and replaces the file
+atomically.
+
+Only give a report that covers everything the baseline covers: entries for
+packages the report did not analyze would be dropped as resolved.`,
+	Example: `  threadgraph run ./... --format json --output report.json --baseline threadgraph-baseline.json
+  threadgraph baseline prune threadgraph-baseline.json report.json
+  threadgraph baseline update threadgraph-baseline.json report.json --dry-run
+  threadgraph baseline show threadgraph-baseline.json`,
+}
+
+var baselineShowCmd = &cobra.Command{
+	Use:   "show <baseline.json>",
+	Short: "List a baseline's entries and audit log",
+	Args:  cobra.ExactArgs(1),
+	RunE:  runBaselineShow,
+}
+
+var baselineUpdateCmd = &cobra.Command{
+	Use:   "update <baseline.json> <report.json>",
+	Short: "Make a baseline record exactly the findings of a JSON report",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rewriteBaseline(args[0], args[1], baseline.Update, true)
+	},
+}
+
+var baselinePruneCmd = &cobra.Command{
+	Use:   "prune <baseline.json> <report.json>",
+	Short: "Drop the baseline entries a JSON report no longer contains",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return rewriteBaseline(args[0], args[1], baseline.Prune, false)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(baselineCmd)
+	baselineCmd.AddCommand(baselineShowCmd, baselineUpdateCmd, baselinePruneCmd)
+	for _, c := range []*cobra.Command{baselineUpdateCmd, baselinePruneCmd} {
+		c.Flags().BoolVar(&flagDryRun, "dry-run", false, "Print the changes without writing the baseline")
+	}
+}
+
+// rewriteBaseline applies update or prune to the baseline at path with the
+// findings of the report at reportPath. If create is set, a missing baseline
+// is treated as empty.
+func rewriteBaseline(path, reportPath string, rewrite func(*baseline.Baseline, []baseline.Entry, string) baseline.AuditNote, create bool) error {
+	current, err := baseline.ReadReport(reportPath)
+	if err != nil {
+		return fmt.Errorf("read report: %w", err)
+	}
+
+	b, err := baseline.Load(path)
+	switch {
+	case create && errors.Is(err, fs.ErrNotExist):
+		b = &baseline.Baseline{}
+	case err != nil:
+		return fmt.Errorf("load baseline: %w", err)
+	}
+
+	note := rewrite(b, current, reportPath)
+	for _, e := range note.Removed {
+		fmt.Printf("- resolved  %-15s %s\n", e.Kind, entryWhere(e))
+	}
+	for _, e := range note.Added {
+		fmt.Printf("+ new       %-15s %s\n", e.Kind, entryWhere(e))
+	}
+	summary := fmt.Sprintf("%s: %d kept (%d refreshed), %d added, %d removed",
+		path, note.Kept, note.Refreshed, len(note.Added), len(note.Removed))
+
+	switch {
+	case !note.Changed() && b.Migrated == 0:
+		fmt.Printf("%s: up to date (%d entries)\n", path, note.Kept)
+		return nil
+	case flagDryRun:
+		fmt.Println(summary + " (dry run, not written)")
+		return nil
+	}
+	if err := baseline.Write(b, path); err != nil {
+		return fmt.Errorf("write baseline: %w", err)
+	}
+	fmt.Println(summary)
+	return nil
+}
+
+func runBaselineShow(cmd *cobra.Command, args []string) error {
+	b, err := baseline.Load(args[0])
+	if err != nil {
+		return err
+	}
+	version := fmt.Sprintf("version %d", b.Version)
+	if b.Migrated != 0 {
+		version = fmt.Sprintf("version %d, read as %d", b.Migrated, b.Version)
+	}
+	fmt.Printf("%s (%s) · created %s · %d entries\n\n", args[0], version, b.Created, len(b.Entries))
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "KIND\tLOCATION\tFUNCTION\tFINGERPRINT")
+	for _, e := range b.Entries {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", e.Kind, e.Location, e.Function, e.Fingerprint)
+	}
+	tw.Flush()
+
+	if len(b.Audit) > 0 {
+		fmt.Println("\nAudit log:")
+		for _, n := range b.Audit {
+			fmt.Printf("  %s  %-6s kept %d, added %d, removed %d", n.Time, n.Action, n.Kept, len(n.Added), len(n.Removed))
+			if n.Report != "" {
+				fmt.Printf("  (from %s)", n.Report)
+			}
+			fmt.Println()
+			for _, e := range n.Removed {
+				fmt.Printf("    - %-15s %s\n", e.Kind, entryWhere(e))
+			}
+			for _, e := range n.Added {
+				fmt.Printf("    + %-15s %s\n", e.Kind, entryWhere(e))
+			}
+		}
+	}
+	return nil
+}
+
+// entryWhere names a baseline entry by location, or by function for
+// findings without one.
+func entryWhere(e baseline.Entry) string {
+	if e.Location != "" {
+		return e.Location
+	}
+	return e.Function
+}
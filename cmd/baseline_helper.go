@@ -11,9 +11,11 @@ import (
 // applyBaseline handles --save-baseline and --baseline logic.
 //
 // If --save-baseline is set, it writes the current findings to the given file.
-// If --baseline is set, it loads the file, filters out known findings, updates
-// result.Findings to only the new ones, and returns a non-nil error if any new
-// findings were found (so the process exits 1 in CI).
+// If --baseline is set, it loads the file, moves known findings to
+// result.Baseline.Persisting, lists the entries that no longer occur in
+// result.Baseline.Resolved, leaves only the new findings in result.Findings,
+// and returns a non-nil error if any new findings were found (so the process
+// exits 1 in CI).
 //
 // The two flags can be combined: save first, then compare (no new findings).
 func applyBaseline(result *detector.Result) error {
@@ -30,15 +32,16 @@ func applyBaseline(result *detector.Result) error {
 		if err != nil {
 			fmt.Fprintf(os.Stderr, "warn: load baseline: %v\n", err)
 		} else {
-			newFindings := baseline.FilterNew(result.Findings, b)
-			known := len(result.Findings) - len(newFindings)
-			if known > 0 {
-				fmt.Fprintf(os.Stderr, "Baseline: %d known finding(s) suppressed, %d new\n", known, len(newFindings))
+			if b.Migrated != 0 {
+				fmt.Fprintf(os.Stderr, "note: baseline %s is version %d and matches findings by location only; re-save it or run 'threadgraph baseline update' to add fingerprints\n", flagBaseline, b.Migrated)
 			}
-			result.Findings = newFindings
-			if len(newFindings) > 0 {
+			baseline.Apply(result, b, flagBaseline)
+			cmp := result.Baseline
+			fmt.Fprintf(os.Stderr, "Baseline: %d known finding(s) suppressed, %d new, %d resolved\n",
+				len(cmp.Persisting), len(result.Findings), len(cmp.Resolved))
+			if len(result.Findings) > 0 {
 				// Defer the exit-1 signal: output is written before the caller returns this error.
-				return fmt.Errorf("%d new finding(s) detected (not in baseline %s)", len(newFindings), flagBaseline)
+				return fmt.Errorf("%d new finding(s) detected (not in baseline %s)", len(result.Findings), flagBaseline)
 			}
 		}
 	}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/config"
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/suppress"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+)
+
+var (
+	flagConfig   string
+	flagNoConfig bool
+)
+
+// projectConfig is the loaded .threadgraph.yaml, or nil if there is none.
+var projectConfig *config.Config
+
+// loadConfig finds and loads the config file before any command runs and
+// applies its flag defaults to the flags not given on the command line.
+func loadConfig(cmd *cobra.Command, args []string) error {
+	if flagNoConfig {
+		return nil
+	}
+	path := flagConfig
+	if path == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return err
+		}
+		if path, err = config.Find(wd); err != nil {
+			return fmt.Errorf("find %s: %w", config.FileName, err)
+		}
+		if path == "" {
+			return nil
+		}
+	}
+
+	c, err := config.Load(path)
+	if err != nil {
+		return fmt.Errorf("config: %w", err)
+	}
+	projectConfig = c
+
+	for name := range c.Defaults {
+		f := cmd.Flags().Lookup(name)
+		if f == nil {
+			if !anyCommandHasFlag(rootCmd, name) {
+				return fmt.Errorf("config: %s: defaults.%s: no such flag", path, name)
+			}
+			continue // belongs to another command
+		}
+		if f.Changed {
+			continue
+		}
+		for _, v := range c.DefaultValues(name) {
+			if err := f.Value.Set(v); err != nil {
+				return fmt.Errorf("config: %s: defaults.%s: %w", path, name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// anyCommandHasFlag reports whether cmd or any of its subcommands defines
+// the flag name.
+func anyCommandHasFlag(cmd *cobra.Command, name string) bool {
+	found := false
+	cmd.Flags().VisitAll(func(f *pflag.Flag) { found = found || f.Name == name })
+	cmd.PersistentFlags().VisitAll(func(f *pflag.Flag) { found = found || f.Name == name })
+	if found {
+		return true
+	}
+	for _, sub := range cmd.Commands() {
+		if anyCommandHasFlag(sub, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyConfig sets the config file's thresholds and disabled detectors on
+// opts.
+func applyConfig(opts *detector.Options) {
+	if projectConfig != nil {
+		projectConfig.Apply(opts)
+	}
+}
+
+// unsuppressed returns findings without those the config file's ignore rules
+// or //threadgraph:ignore comments suppress, for Options.Unsuppressed.
+func unsuppressed(findings []detector.Finding) []detector.Finding {
+	result := &detector.Result{Findings: findings}
+	if projectConfig != nil && projectConfig.Suppressions().Len() > 0 {
+		projectConfig.Suppressions().Apply(result)
+	}
+	suppress.ApplyInline(result)
+	return result.Findings
+}
+
+// applySuppressions moves the findings matched by the config file's ignore
+// rules, or by //threadgraph:ignore comments in the source, from
+// result.Findings to result.Suppressed.
+func applySuppressions(result *detector.Result) {
+	if projectConfig != nil && projectConfig.Suppressions().Len() > 0 {
+		projectConfig.Suppressions().Apply(result)
+		if n := len(result.Suppressed); n > 0 {
+			fmt.Fprintf(os.Stderr, "Suppressed %d finding(s) per %s\n", n, projectConfig.Path)
+		}
+	}
+
+	before := len(result.Suppressed)
+	for _, w := range suppress.ApplyInline(result) {
+		fmt.Fprintf(os.Stderr, "warn: %s\n", w)
+	}
+	if n := len(result.Suppressed) - before; n > 0 {
+		fmt.Fprintf(os.Stderr, "Suppressed %d finding(s) per %s comments\n", n, suppress.Directive)
+	}
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/graph"
+	"github.com/Heman10x-NGU/threadgraph/internal/static"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagGraphKind   string
+	flagFlaggedOnly bool
+)
+
+var graphCmd = &cobra.Command{
+	Use:   "graph <trace.out> | --kind lockorder <packages>",
+	Short: "Export the spawn tree or lock graphs as Graphviz DOT or Mermaid",
+	Long: `Graph exports the structures behind the findings as a Graphviz DOT
+(--format dot, the default) or Mermaid (--format mermaid) graph:
+
+  spawn      the goroutine spawn tree of a trace: which goroutine created
+             which. Goroutines with findings are highlighted in red, with the
+             path that created them; exited goroutines are dashed. Siblings
+             with identical subtrees are drawn once, with a count.
+  lockwait   the runtime lock-wait graph of a trace: an edge from the site a
+             goroutine last locked at to the site it is blocked at. Cycles
+             are the deadlocks the lock_cycles detector reports.
+  lockorder  the static lock-ordering graph of packages, as built by
+             'run --static': an edge from each lock to the locks acquired
+             while holding it. Cycles are the lock_order findings.
+
+Render DOT with 'dot -Tsvg graph.dot -o graph.svg'; paste Mermaid into a
+` + "```mermaid" + ` block of a GitHub or GitLab issue or pull request.`,
+	Example: `  threadgraph graph ./trace.out | dot -Tsvg -o spawn.svg
+  threadgraph graph ./trace.out --flagged-only --format mermaid
+  threadgraph graph ./trace.out --kind lockwait --output lockwait.dot
+  threadgraph graph --kind lockorder ./pkg/...`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runGraph,
+}
+
+func init() {
+	rootCmd.AddCommand(graphCmd)
+	graphCmd.Flags().StringVar(&flagGraphKind, "kind", "spawn", "Graph to export: spawn, lockwait or lockorder")
+	graphCmd.Flags().BoolVar(&flagFlaggedOnly, "flagged-only", false, "spawn: draw only goroutines with findings and their ancestors")
+}
+
+func runGraph(cmd *cobra.Command, args []string) error {
+	// --format defaults to "terminal" for the other commands.
+	format := flagFormat
+	if !cmd.Flags().Changed("format") && format != "mermaid" {
+		format = "dot"
+	}
+	if format != "dot" && format != "mermaid" {
+		return fmt.Errorf("graph supports --format dot or mermaid, not %q", format)
+	}
+
+	var g *graph.Graph
+	switch flagGraphKind {
+	case "spawn", "lockwait":
+		if len(args) != 1 {
+			return fmt.Errorf("--kind %s takes one trace file", flagGraphKind)
+		}
+		minBlock, err := time.ParseDuration(flagMinBlock)
+		if err != nil {
+			return fmt.Errorf("--min-block: %w", err)
+		}
+		opts := detector.Options{
+			MinBlock:  minBlock,
+			Streaming: flagStreaming,
+			Timeline:  flagGraphKind == "spawn",
+			LockWait:  flagGraphKind == "lockwait",
+		}
+		applyConfig(&opts)
+		result, err := detector.Analyze(args[0], opts)
+		if err != nil {
+			return fmt.Errorf("analyze: %w", err)
+		}
+		if flagGraphKind == "spawn" {
+			g = graph.Spawn(result.Timeline, flagFlaggedOnly)
+		} else {
+			g = graph.LockWait(result.LockWait)
+		}
+	case "lockorder":
+		lg, err := static.LockOrderGraph(args)
+		if err != nil {
+			return fmt.Errorf("static analysis: %w", err)
+		}
+		g = graph.LockOrder(lg)
+	default:
+		return fmt.Errorf("unknown --kind %q (want spawn, lockwait or lockorder)", flagGraphKind)
+	}
+	if len(g.Nodes) == 0 {
+		fmt.Fprintf(os.Stderr, "warn: the %s graph is empty\n", flagGraphKind)
+	}
+
+	out, cleanup, err := outputWriter()
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+	if format == "mermaid" {
+		return graph.WriteMermaid(out, g)
+	}
+	return graph.WriteDOT(out, g)
+}
@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/live"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagInterval   string
+	flagSamples    int
+	flagMinGrowth  int
+	flagSteadiness float64
+)
+
+var monitorCmd = &cobra.Command{
+	Use:   "monitor <http://host:port>",
+	Short: "Watch a running service for steadily growing goroutine counts",
+	Long: `Monitor polls a service's /debug/pprof/goroutine?debug=2 endpoint every
+--interval and groups blocked goroutines by creation site and blocking
+stack. A group whose size grows by at least --min-growth over the run, and
+does not shrink in at least --steadiness of the intervals, is reported as a
+goroutine leak.
+
+This finds slow leaks that attach cannot: no single goroutine needs to be
+blocked for long, only more and more of them piling up at the same place.
+Press Ctrl-C to stop early and report on the samples taken so far.`,
+	Example: `  threadgraph monitor http://localhost:6060
+  threadgraph monitor localhost:6060 --interval 1m --samples 30 --min-growth 20
+  threadgraph monitor http://localhost:6060 --service-root '^github.com/acme/api/' --format json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runMonitor,
+}
+
+func init() {
+	rootCmd.AddCommand(monitorCmd)
+	monitorCmd.Flags().StringVar(&flagInterval, "interval", "30s", "Time between goroutine samples")
+	monitorCmd.Flags().IntVar(&flagSamples, "samples", 10, "Number of samples to take")
+	monitorCmd.Flags().IntVar(&flagMinGrowth, "min-growth", 5, "Minimum increase in a group's goroutine count between the first and last sample")
+	monitorCmd.Flags().Float64Var(&flagSteadiness, "steadiness", 0.8, "Fraction of intervals in which a group's count must not fall (0-1]")
+	monitorCmd.Flags().StringArrayVar(&flagServiceRoots, "service-root", nil, "Regexp matching functions whose goroutines (and descendants) are analyzed (repeatable)")
+}
+
+func runMonitor(cmd *cobra.Command, args []string) error {
+	client := live.NewClient(args[0])
+
+	interval, err := time.ParseDuration(flagInterval)
+	if err != nil {
+		return fmt.Errorf("--interval: %w", err)
+	}
+	switch {
+	case interval <= 0:
+		return fmt.Errorf("--interval must be positive")
+	case flagSamples < 2:
+		return fmt.Errorf("--samples must be at least 2")
+	case flagMinGrowth < 1:
+		return fmt.Errorf("--min-growth must be at least 1")
+	case flagSteadiness <= 0 || flagSteadiness > 1:
+		return fmt.Errorf("--steadiness must be in (0, 1]")
+	}
+
+	opts := detector.Options{
+		MinGrowth:  flagMinGrowth,
+		Steadiness: flagSteadiness,
+	}
+	applyConfig(&opts)
+	if opts.ServiceRoots, err = serviceRoots(); err != nil {
+		return err
+	}
+	tracker := detector.NewGrowthTracker(opts)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	fmt.Fprintf(os.Stderr, "Sampling %s every %s (%d samples)...\n", client.BaseURL, interval, flagSamples)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for i := 0; i < flagSamples; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+			case <-ticker.C:
+			}
+		}
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "Interrupted after %d samples\n", tracker.Samples())
+			break
+		}
+		dump, err := client.GoroutineDump(ctx)
+		if err == nil {
+			var n int
+			if n, err = tracker.Add(dump, time.Now()); err == nil {
+				fmt.Fprintf(os.Stderr, "Sample %d/%d: %d goroutines\n", i+1, flagSamples, n)
+				continue
+			}
+		}
+		if ctx.Err() != nil {
+			fmt.Fprintf(os.Stderr, "Interrupted after %d samples\n", tracker.Samples())
+			break
+		}
+		fmt.Fprintf(os.Stderr, "warn: sample %d: %v\n", i+1, err)
+	}
+	if tracker.Samples() < 2 {
+		return fmt.Errorf("need at least 2 samples, got %d", tracker.Samples())
+	}
+
+	result := tracker.Result()
+	result.TraceFile = client.BaseURL
+
+	applySuppressions(result)
+
+	explanation := explainFindings(result)
+
+	baselineErr := applyBaseline(result)
+
+	if err := writeReport(result, explanation); err != nil {
+		return err
+	}
+
+	return baselineErr
+}
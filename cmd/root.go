@@ -14,6 +14,8 @@ var (
 	flagRace          bool
 	flagSaveBaseline  string
 	flagBaseline      string
+	flagStreaming     bool
+	flagLinkBase      string
 )
 
 var rootCmd = &cobra.Command{
@@ -24,7 +26,10 @@ var rootCmd = &cobra.Command{
   - Deadlocks (goroutines stuck on mutex)
   - Long-blocking operations
 
-Run 'threadgraph analyze <trace.out>' or 'threadgraph run ./...' to get started.`,
+Run 'threadgraph analyze <trace.out>' or 'threadgraph run ./...' to get started.
+
+Settings are read from the first .threadgraph.yaml found in the working
+directory or its parents (see --config).`,
 }
 
 // Execute runs the root command.
@@ -33,7 +38,8 @@ func Execute() error {
 }
 
 func init() {
-	rootCmd.PersistentFlags().StringVar(&flagFormat, "format", "terminal", "Output format: terminal or json")
+	rootCmd.PersistentPreRunE = loadConfig
+	rootCmd.PersistentFlags().StringVar(&flagFormat, "format", "terminal", "Output format: terminal, json, sarif, junit, html or markdown (graph: dot or mermaid)")
 	rootCmd.PersistentFlags().StringVar(&flagOutput, "output", "", "Write output to file instead of stdout")
 	rootCmd.PersistentFlags().BoolVar(&flagNoLLM, "no-llm", false, "Skip LLM explanation (faster, works without API key)")
 	rootCmd.PersistentFlags().StringVar(&flagMinBlock, "min-block", "1s", "Minimum block duration to flag as a long block (e.g. 500ms, 2s)")
@@ -42,4 +48,8 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&flagRace, "race", false, "Also run go test -race to detect data races (requires CGO)")
 	rootCmd.PersistentFlags().StringVar(&flagSaveBaseline, "save-baseline", "", "Save current findings as a baseline to file (for future --baseline comparisons)")
 	rootCmd.PersistentFlags().StringVar(&flagBaseline, "baseline", "", "Compare findings against baseline file; exit 1 only if NEW findings are detected")
+	rootCmd.PersistentFlags().StringVar(&flagConfig, "config", "", "Config file to use instead of the nearest .threadgraph.yaml")
+	rootCmd.PersistentFlags().BoolVar(&flagNoConfig, "no-config", false, "Ignore .threadgraph.yaml")
+	rootCmd.PersistentFlags().BoolVar(&flagStreaming, "streaming", false, "Bounded-memory analysis for very large traces (drops state of exited goroutines)")
+	rootCmd.PersistentFlags().StringVar(&flagLinkBase, "link-base", "", "markdown: URL prefix of source links (e.g. https://github.com/org/repo/blob/<sha>/); links are relative to the working directory otherwise")
 }
@@ -4,29 +4,48 @@ import (
 	"fmt"
 	"os"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/Heman10x-NGU/threadgraph/internal/detector"
-	"github.com/Heman10x-NGU/threadgraph/internal/llm"
-	"github.com/Heman10x-NGU/threadgraph/internal/reporter"
 	"github.com/Heman10x-NGU/threadgraph/internal/static"
 	"github.com/Heman10x-NGU/threadgraph/internal/tracer"
 )
 
 var (
 	flagDuration string
+	flagParallel int
+	flagRepeat   int
+	flagMinHits  int
 )
 
 var runCmd = &cobra.Command{
-	Use:   "run [go test args...]",
+	Use:   "run <packages...> [-- go test flags...]",
 	Short: "Auto-instrument a Go package, capture trace, and analyze",
-	Long: `Run executes 'go test -trace <tmpfile> -timeout <duration> <args...>',
-then analyzes the captured trace for concurrency issues.`,
+	Long: `Run executes 'go test -trace <tmpfile> -timeout <duration> <flags...> <pkg>'
+for every package matched by the given patterns, then analyzes the captured
+traces for concurrency issues.
+
+Arguments after '--' are passed through to go test (e.g. -run, -count, -tags,
+-cpu, -short); they apply to the traced run, the GOMAXPROCS retries and the
+--race run alike. Use --duration instead of -timeout.
+
+With --repeat N the tests are traced N times: first as given, then under
+GOMAXPROCS=1, 2 and 4 in turn. Findings are matched across runs by
+fingerprint and report how many runs they appeared in. Findings seen in
+fewer than --min-hits runs are listed as flaky and do not count. Any other
+finding makes the command exit 1, as do new findings when --baseline is set.
+Without --repeat, a run with no findings is retried under the GOMAXPROCS
+values until one finds something.`,
 	Example: `  threadgraph run ./...
   threadgraph run ./... --duration 30s
   threadgraph run ./pkg/server/... --duration 60s --no-llm
-  threadgraph run ./... --static`,
+  threadgraph run ./... --static
+  threadgraph run ./... --parallel 4
+  threadgraph run ./pkg/server --repeat 5 --min-hits 3
+  threadgraph run ./pkg/server -- -run TestHandler -count 3
+  threadgraph run ./... -- -tags integration -short`,
 	Args: cobra.MinimumNArgs(1),
 	RunE: runRun,
 }
@@ -34,9 +53,24 @@ then analyzes the captured trace for concurrency issues.`,
 func init() {
 	rootCmd.AddCommand(runCmd)
 	runCmd.Flags().StringVar(&flagDuration, "duration", "10s", "Test timeout / trace duration (e.g. 10s, 30s, 60s)")
+	runCmd.Flags().IntVar(&flagParallel, "parallel", 1, "Number of packages to trace concurrently in multi-package runs")
+	runCmd.Flags().IntVar(&flagRepeat, "repeat", 1, "Trace the tests N times, varying GOMAXPROCS, and merge the findings")
+	runCmd.Flags().IntVar(&flagMinHits, "min-hits", 0, "With --repeat, only report findings seen in at least K runs (default: all N)")
 }
 
 func runRun(cmd *cobra.Command, args []string) error {
+	args, testFlags, err := splitTestArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+
+	if flagRepeat < 1 {
+		return fmt.Errorf("--repeat must be at least 1")
+	}
+	if flagMinHits != 0 && flagRepeat == 1 {
+		return fmt.Errorf("--min-hits requires --repeat")
+	}
+
 	duration, err := time.ParseDuration(flagDuration)
 	if err != nil {
 		return fmt.Errorf("--duration: %w", err)
@@ -50,58 +84,35 @@ func runRun(cmd *cobra.Command, args []string) error {
 	opts := detector.Options{
 		MinBlock:      minBlock,
 		DebugFiltered: flagDebugFiltered,
+		Streaming:     flagStreaming,
+		Timeline:      flagFormat == "html",
 	}
-
-	fmt.Fprintf(os.Stderr, "Running: go test -trace <tmpfile> -timeout %s %s\n", flagDuration, joinArgs(args))
-
-	runResult, err := tracer.Run(args, duration)
-	if err != nil {
-		return fmt.Errorf("trace: %w", err)
-	}
-	traceToClean := runResult.TraceFile
-
-	if runResult.Output != "" {
-		fmt.Fprintln(os.Stderr, "--- go test output ---")
-		fmt.Fprint(os.Stderr, runResult.Output)
-		fmt.Fprintln(os.Stderr, "--- end output ---")
+	applyConfig(&opts)
+	traceOpts := tracer.Options{
+		Duration:  duration,
+		Parallel:  flagParallel,
+		TestFlags: testFlags,
 	}
 
-	result, err := detector.Analyze(runResult.TraceFile, opts)
-	if err != nil {
-		os.Remove(traceToClean)
-		return fmt.Errorf("analyze: %w", err)
+	cmdLine := joinArgs(args)
+	if len(testFlags) > 0 {
+		cmdLine = joinArgs(testFlags) + " " + cmdLine
 	}
+	fmt.Fprintf(os.Stderr, "Running: go test -trace <tmpfile> -timeout %s %s\n", flagDuration, cmdLine)
 
-	// Schedule diversity retry loop.
-	// If no findings on the first pass, try increasingly constrained GOMAXPROCS values
-	// to expose scheduling-dependent bugs. Order: GOMAXPROCS=1, GOMAXPROCS=2, GOMAXPROCS=4.
-	// Each retry serializes goroutine scheduling differently, catching different interleavings.
-	gomaxprocsRetries := scheduleDiversityValues()
-	for _, gmp := range gomaxprocsRetries {
-		if len(result.Findings) > 0 {
-			break
-		}
-		env := fmt.Sprintf("GOMAXPROCS=%d", gmp)
-		fmt.Fprintf(os.Stderr, "No findings; retrying with %s...\n", env)
-		r2, err2 := tracer.Run(args, duration, env)
-		if err2 != nil {
-			continue
-		}
-		if runResult.Output != "" {
-			fmt.Fprintln(os.Stderr, "--- go test output ---")
-			fmt.Fprint(os.Stderr, r2.Output)
-			fmt.Fprintln(os.Stderr, "--- end output ---")
-		}
-		res2, err2 := detector.Analyze(r2.TraceFile, opts)
-		if err2 == nil && len(res2.Findings) > 0 {
-			os.Remove(traceToClean)
-			traceToClean = r2.TraceFile
-			result = res2
-		} else {
-			os.Remove(r2.TraceFile)
+	var result *detector.Result
+	if flagRepeat > 1 {
+		result, err = traceRepeated(args, traceOpts, opts)
+	} else {
+		var runResult *tracer.RunResult
+		result, runResult, err = traceWithRetries(args, traceOpts, opts)
+		if runResult != nil {
+			defer runResult.Remove()
 		}
 	}
-	defer os.Remove(traceToClean)
+	if err != nil {
+		return err
+	}
 
 	// Optional: go/ssa static analysis bundle (--static flag).
 	if flagStatic {
@@ -164,7 +175,7 @@ func runRun(cmd *cobra.Command, args []string) error {
 	// Optional: data race detection (--race flag).
 	if flagRace {
 		fmt.Fprintln(os.Stderr, "Running data race detection (go test -race)...")
-		raceOut, rerr := tracer.RunRace(args, duration)
+		raceOut, rerr := tracer.RunRace(args, traceOpts)
 		if rerr != nil {
 			fmt.Fprintf(os.Stderr, "warn: race detection: %v\n", rerr)
 		} else {
@@ -173,37 +184,216 @@ func runRun(cmd *cobra.Command, args []string) error {
 		}
 	}
 
-	explanation := ""
-	if !flagNoLLM {
-		apiKey := os.Getenv("ANTHROPIC_API_KEY")
-		if apiKey != "" && len(result.Findings) > 0 {
-			exp, err := llm.Explain(result.Findings, apiKey)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "warn: LLM explanation failed: %v\n", err)
-			} else {
-				explanation = exp
+	applySuppressions(result)
+
+	explanation := explainFindings(result)
+
+	baselineErr := applyBaseline(result)
+
+	if err := writeReport(result, explanation); err != nil {
+		return err
+	}
+
+	if baselineErr == nil && flagBaseline == "" && result.Repeat != nil {
+		// Only findings that reached --min-hits are left; static and race
+		// findings, which were not repeated, do not gate.
+		reproduced := 0
+		for _, f := range result.Findings {
+			if f.Runs > 0 {
+				reproduced++
 			}
 		}
+		if reproduced > 0 {
+			return fmt.Errorf("%d finding(s) reproduced in at least %d of %d runs", reproduced, result.Repeat.MinHits, result.Repeat.Runs)
+		}
 	}
 
-	baselineErr := applyBaseline(result)
+	return baselineErr
+}
 
-	out, cleanup, err := outputWriter()
+// traceWithRetries traces the tests once and analyzes the traces. The caller
+// removes the returned traces.
+func traceWithRetries(args []string, traceOpts tracer.Options, opts detector.Options) (*detector.Result, *tracer.RunResult, error) {
+	runResult, err := tracer.Run(args, traceOpts)
 	if err != nil {
-		return err
+		return nil, nil, fmt.Errorf("trace: %w", err)
+	}
+	printTestOutput(runResult.Output)
+
+	result, err := analyzeRun(runResult, opts)
+	if err != nil {
+		runResult.Remove()
+		return nil, nil, fmt.Errorf("analyze: %w", err)
+	}
+
+	// Schedule diversity retry loop.
+	// If no findings on the first pass, try increasingly constrained GOMAXPROCS values
+	// to expose scheduling-dependent bugs. Order: GOMAXPROCS=1, GOMAXPROCS=2, GOMAXPROCS=4.
+	// Each retry serializes goroutine scheduling differently, catching different interleavings.
+	gomaxprocsRetries := scheduleDiversityValues()
+	for _, gmp := range gomaxprocsRetries {
+		if len(result.Findings) > 0 {
+			break
+		}
+		env := fmt.Sprintf("GOMAXPROCS=%d", gmp)
+		fmt.Fprintf(os.Stderr, "No findings; retrying with %s...\n", env)
+		r2, err2 := tracer.Run(args, traceOpts, env)
+		if err2 != nil {
+			continue
+		}
+		printTestOutput(r2.Output)
+		res2, err2 := analyzeRun(r2, opts)
+		if err2 == nil && len(res2.Findings) > 0 {
+			runResult.Remove()
+			runResult = r2
+			result = res2
+		} else {
+			r2.Remove()
+		}
+	}
+	return result, runResult, nil
+}
+
+// traceRepeated traces the tests --repeat times, the first time in the
+// default environment and then under each scheduleDiversityValues GOMAXPROCS
+// in turn, and merges the findings with detector.MergeRuns. Runs that fail to
+// trace or analyze are left out.
+func traceRepeated(args []string, traceOpts tracer.Options, opts detector.Options) (*detector.Result, error) {
+	minHits := flagMinHits
+	if minHits == 0 {
+		minHits = flagRepeat
+	}
+	if minHits < 1 || minHits > flagRepeat {
+		return nil, fmt.Errorf("--min-hits must be between 1 and --repeat (%d)", flagRepeat)
+	}
+
+	gomaxprocs := scheduleDiversityValues()
+	var results []*detector.Result
+	var envs []string
+	for i := 0; i < flagRepeat; i++ {
+		var env []string
+		desc := "default environment"
+		if i > 0 {
+			env = []string{fmt.Sprintf("GOMAXPROCS=%d", gomaxprocs[(i-1)%len(gomaxprocs)])}
+			desc = env[0]
+		}
+		fmt.Fprintf(os.Stderr, "Run %d/%d (%s)...\n", i+1, flagRepeat, desc)
+		rr, err := tracer.Run(args, traceOpts, env...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: run %d: trace: %v\n", i+1, err)
+			continue
+		}
+		printTestOutput(rr.Output)
+		res, err := analyzeRun(rr, opts)
+		rr.Remove()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "warn: run %d: analyze: %v\n", i+1, err)
+			continue
+		}
+		results = append(results, res)
+		envs = append(envs, strings.Join(env, " "))
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("none of the %d runs could be analyzed", flagRepeat)
+	}
+	if len(results) < minHits {
+		fmt.Fprintf(os.Stderr, "warn: only %d of %d runs could be analyzed; no finding can reach --min-hits %d\n", len(results), flagRepeat, minHits)
+	}
+	return detector.MergeRuns(results, envs, minHits), nil
+}
+
+// printTestOutput echoes go test's output to stderr.
+func printTestOutput(output string) {
+	if output != "" {
+		fmt.Fprintln(os.Stderr, "--- go test output ---")
+		fmt.Fprint(os.Stderr, output)
+		fmt.Fprintln(os.Stderr, "--- end output ---")
 	}
-	defer cleanup()
+}
 
-	switch flagFormat {
-	case "json":
-		if err := reporter.WriteJSON(out, result, explanation); err != nil {
-			return err
+// splitTestArgs separates package patterns from go test flags. Everything
+// after a literal "--" is a go test flag. Flags that ThreadGraph itself
+// controls (-trace, -timeout, also spelled -test.trace, -test.timeout) are
+// rejected.
+func splitTestArgs(cmd *cobra.Command, args []string) (pkgs, testFlags []string, err error) {
+	dash := cmd.ArgsLenAtDash()
+	if dash < 0 {
+		return args, nil, nil
+	}
+	pkgs, testFlags = args[:dash], args[dash:]
+	if len(pkgs) == 0 {
+		return nil, nil, fmt.Errorf("no package patterns given before '--'")
+	}
+	for _, f := range testFlags {
+		name := strings.TrimLeft(f, "-")
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name = name[:eq]
+		}
+		// The test binary also accepts its flags as -test.name.
+		switch strings.TrimPrefix(name, "test.") {
+		case "trace":
+			return nil, nil, fmt.Errorf("go test flag %s is set by threadgraph and cannot be passed through", f)
+		case "timeout":
+			return nil, nil, fmt.Errorf("go test flag %s cannot be passed through; use --duration", f)
 		}
-	default:
-		reporter.WriteTerminal(out, result, explanation)
 	}
+	return pkgs, testFlags, nil
+}
 
-	return baselineErr
+// analyzeRun analyzes every package trace in rr separately and merges the
+// per-package results into one, so a leak in any package is reported — not
+// only in the package with the largest trace.
+//
+// A package whose trace is missing or unreadable but whose output holds a
+// goroutine dump (e.g. after "panic: test timed out") is analyzed from the
+// dump instead.
+func analyzeRun(rr *tracer.RunResult, opts detector.Options) (*detector.Result, error) {
+	results := make([]*detector.Result, 0, len(rr.Packages))
+	for _, pt := range rr.Packages {
+		res, err := analyzePackage(pt, opts)
+		if err != nil {
+			if len(rr.Packages) == 1 {
+				return nil, err
+			}
+			fmt.Fprintf(os.Stderr, "warn: analyze %s: %v\n", pt.Package, err)
+			continue
+		}
+		res.Package = pt.Package
+		results = append(results, res)
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("no package trace could be analyzed")
+	}
+	return detector.Merge(results), nil
+}
+
+// analyzePackage analyzes one package's trace, falling back to the goroutine
+// dump in its output when there is no usable trace.
+func analyzePackage(pt tracer.PackageTrace, opts detector.Options) (*detector.Result, error) {
+	var err error
+	if pt.TraceFile != "" {
+		var res *detector.Result
+		if res, err = detector.Analyze(pt.TraceFile, opts); err == nil {
+			return res, nil
+		}
+	}
+	if !detector.IsGoroutineDump(pt.Output) {
+		if err == nil {
+			err = fmt.Errorf("no trace file and no goroutine dump in output")
+		}
+		return nil, err
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warn: %s: trace unusable (%v); analyzing goroutine dump from test output\n", pt.Package, err)
+	} else {
+		fmt.Fprintf(os.Stderr, "warn: %s: no trace written; analyzing goroutine dump from test output\n", pt.Package)
+	}
+	res, derr := detector.GoroutineDumpResult(pt.Output, opts)
+	if derr != nil {
+		return nil, derr
+	}
+	res.TraceFile = "(goroutine dump)"
+	return res, nil
 }
 
 // scheduleDiversityValues returns the GOMAXPROCS values to retry with when no
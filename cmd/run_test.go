@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestSplitTestArgs(t *testing.T) {
+	for _, tc := range []struct {
+		args      []string
+		pkgs      string
+		testFlags string
+		err       string
+	}{
+		{args: []string{"./..."}, pkgs: "./..."},
+		{args: []string{"./a", "./b", "--", "-run", "TestX", "-count=2"}, pkgs: "./a ./b", testFlags: "-run TestX -count=2"},
+		{args: []string{"./...", "--", "-test.run=TestX"}, pkgs: "./...", testFlags: "-test.run=TestX"},
+		{args: []string{"./...", "--", "-trace=x.out"}, err: "set by threadgraph"},
+		{args: []string{"./...", "--", "--trace", "x.out"}, err: "set by threadgraph"},
+		{args: []string{"./...", "--", "-test.trace=x.out"}, err: "set by threadgraph"},
+		{args: []string{"./...", "--", "-timeout=5m"}, err: "use --duration"},
+		{args: []string{"./...", "--", "-test.timeout", "5m"}, err: "use --duration"},
+		{args: []string{"--", "-v"}, err: "no package patterns"},
+	} {
+		cmd := &cobra.Command{}
+		if err := cmd.ParseFlags(tc.args); err != nil {
+			t.Fatal(err)
+		}
+		pkgs, testFlags, err := splitTestArgs(cmd, cmd.Flags().Args())
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%q: error = %v, want %q", tc.args, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", tc.args, err)
+			continue
+		}
+		if got := strings.Join(pkgs, " "); got != tc.pkgs {
+			t.Errorf("%q: packages = %q, want %q", tc.args, got, tc.pkgs)
+		}
+		if got := strings.Join(testFlags, " "); got != tc.testFlags {
+			t.Errorf("%q: test flags = %q, want %q", tc.args, got, tc.testFlags)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/tracer"
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagIterations     int
+	flagStressDuration string
+)
+
+var stressCmd = &cobra.Command{
+	Use:   "stress <packages...> [-- go test flags...]",
+	Short: "Run tests N times in one trace and report goroutines leaked per invocation",
+	Long: `Stress runs 'go test -trace <tmpfile> -count=<iterations> <flags...> <pkg>'
+so every test runs N times inside a single trace, then attributes each leaked
+goroutine to the test invocation it descends from.
+
+Slow leaks that are easy to miss in one short run show up as a steady leak
+rate: "TestX leaks 1 goroutine per invocation" means the number of leaked
+goroutines grows linearly with the number of times TestX runs.`,
+	Example: `  threadgraph stress ./pkg/server --iterations 20
+  threadgraph stress ./pkg/server --iterations 50 -- -run TestHandler
+  threadgraph stress ./... --iterations 10 --format json`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runStress,
+}
+
+func init() {
+	rootCmd.AddCommand(stressCmd)
+	stressCmd.Flags().IntVar(&flagIterations, "iterations", 10, "Number of times to run each test (go test -count)")
+	stressCmd.Flags().StringVar(&flagStressDuration, "duration", "60s", "Test timeout / trace duration for the whole stress run")
+	stressCmd.Flags().IntVar(&flagParallel, "parallel", 1, "Number of packages to trace concurrently in multi-package runs")
+}
+
+func runStress(cmd *cobra.Command, args []string) error {
+	args, testFlags, err := splitTestArgs(cmd, args)
+	if err != nil {
+		return err
+	}
+	if flagIterations < 1 {
+		return fmt.Errorf("--iterations must be at least 1")
+	}
+	for _, f := range testFlags {
+		if name := strings.TrimLeft(f, "-"); name == "count" || strings.HasPrefix(name, "count=") {
+			return fmt.Errorf("go test flag %s conflicts with --iterations", f)
+		}
+	}
+
+	duration, err := time.ParseDuration(flagStressDuration)
+	if err != nil {
+		return fmt.Errorf("--duration: %w", err)
+	}
+
+	minBlock, err := time.ParseDuration(flagMinBlock)
+	if err != nil {
+		return fmt.Errorf("--min-block: %w", err)
+	}
+
+	opts := detector.Options{
+		MinBlock:      minBlock,
+		DebugFiltered: flagDebugFiltered,
+		Streaming:     flagStreaming,
+		Timeline:      flagFormat == "html",
+		Iterations:    flagIterations,
+		Unsuppressed:  unsuppressed,
+	}
+	applyConfig(&opts)
+	traceOpts := tracer.Options{
+		Duration:  duration,
+		Parallel:  flagParallel,
+		TestFlags: append([]string{fmt.Sprintf("-count=%d", flagIterations)}, testFlags...),
+	}
+
+	fmt.Fprintf(os.Stderr, "Running: go test -trace <tmpfile> -timeout %s %s %s\n",
+		flagStressDuration, joinArgs(traceOpts.TestFlags), joinArgs(args))
+
+	runResult, err := tracer.Run(args, traceOpts)
+	if err != nil {
+		return fmt.Errorf("trace: %w", err)
+	}
+	defer runResult.Remove()
+
+	printTestOutput(runResult.Output)
+
+	result, err := analyzeRun(runResult, opts)
+	if err != nil {
+		return fmt.Errorf("analyze: %w", err)
+	}
+
+	applySuppressions(result)
+
+	explanation := explainFindings(result)
+
+	baselineErr := applyBaseline(result)
+
+	if err := writeReport(result, explanation); err != nil {
+		return err
+	}
+
+	return baselineErr
+}
@@ -7,21 +7,34 @@ package baseline
 import (
 	"encoding/json"
 	"fmt"
+	"go/build"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/Heman10x-NGU/threadgraph/internal/detector"
 )
 
-const currentVersion = 1
+// currentVersion is the version written by Save. Version 1 files, which
+// have no fingerprints, are migrated by Load.
+const currentVersion = 2
 
-// Entry is a single finding captured in a baseline, keyed by kind and location.
-// Goroutine IDs and blocked durations are intentionally omitted because they
-// change between runs.
+// Entry is a single finding captured in a baseline. Goroutine IDs and
+// blocked durations are intentionally omitted because they change between
+// runs.
 type Entry struct {
-	Kind     string `json:"kind"`
-	Function string `json:"function"`
-	Location string `json:"location"`
+	// Fingerprint is detector.Finding.Fingerprint; empty in entries
+	// migrated from a version 1 file.
+	Fingerprint string `json:"fingerprint,omitempty"`
+	Kind        string `json:"kind"`
+	Function    string `json:"function"`
+	Location    string `json:"location"`
+	// CreatedAt is the location of the 'go' statement that started the
+	// goroutine, if known.
+	CreatedAt string `json:"created_at,omitempty"`
 }
 
 // Baseline is the on-disk format for a saved set of findings.
@@ -29,10 +42,15 @@ type Baseline struct {
 	Version int     `json:"version"`
 	Created string  `json:"created"`
 	Entries []Entry `json:"entries"`
+	// Audit records every rewrite by Update or Prune, oldest first.
+	Audit []AuditNote `json:"audit,omitempty"`
+	// Migrated is the version of the file Load read, if it was older than
+	// the current one.
+	Migrated int `json:"-"`
 }
 
 // Save writes findings to a baseline file at path.
-// Duplicate (kind, location) pairs are deduplicated before saving.
+// Duplicate (fingerprint, location) pairs are deduplicated before saving.
 func Save(findings []detector.Finding, path string) error {
 	b := &Baseline{
 		Version: currentVersion,
@@ -40,25 +58,57 @@ func Save(findings []detector.Finding, path string) error {
 	}
 	seen := make(map[[2]string]bool)
 	for _, f := range findings {
-		key := [2]string{string(f.Kind), f.Location}
+		e := entryOf(f)
+		key := [2]string{e.Fingerprint, e.Location}
 		if seen[key] {
 			continue
 		}
 		seen[key] = true
-		b.Entries = append(b.Entries, Entry{
-			Kind:     string(f.Kind),
-			Function: f.Function,
-			Location: f.Location,
-		})
+		b.Entries = append(b.Entries, e)
 	}
+	return Write(b, path)
+}
+
+// Write stores b at path as the current version. The file is replaced
+// atomically, so an interrupted write leaves the old baseline intact.
+func Write(b *Baseline, path string) error {
+	b.Version = currentVersion
 	data, err := json.MarshalIndent(b, "", "  ")
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func entryOf(f detector.Finding) Entry {
+	return Entry{
+		Fingerprint: f.Fingerprint(),
+		Kind:        string(f.Kind),
+		Function:    f.Function,
+		Location:    f.Location,
+		CreatedAt:   f.CreationLocation,
+	}
 }
 
-// Load reads a baseline file from path.
+// Load reads a baseline file from path. A version 1 file is migrated to the
+// current version in memory; its entries have no fingerprints and match by
+// location only, until the baseline is saved again.
 func Load(path string) (*Baseline, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
@@ -68,24 +118,178 @@ func Load(path string) (*Baseline, error) {
 	if err := json.Unmarshal(data, &b); err != nil {
 		return nil, err
 	}
-	if b.Version != currentVersion {
+	switch b.Version {
+	case currentVersion:
+	case 1:
+		// Version 1 entries are a subset of version 2 ones.
+		b.Migrated, b.Version = b.Version, currentVersion
+	default:
 		return nil, fmt.Errorf("unsupported baseline version %d (expected %d)", b.Version, currentVersion)
 	}
 	return &b, nil
 }
 
 // FilterNew returns only the findings that are not present in b.
-// Matching is by (kind, location) — goroutine IDs and timings are ignored.
+// Goroutine IDs and timings are ignored; see Match for how findings and
+// entries are paired.
 func FilterNew(findings []detector.Finding, b *Baseline) []detector.Finding {
-	known := make(map[[2]string]bool, len(b.Entries))
-	for _, e := range b.Entries {
-		known[[2]string{e.Kind, e.Location}] = true
-	}
+	matched, _ := Match(findings, b)
 	var out []detector.Finding
-	for _, f := range findings {
-		if !known[[2]string{string(f.Kind), f.Location}] {
+	for i, f := range findings {
+		if matched[i] < 0 {
 			out = append(out, f)
 		}
 	}
 	return out
 }
+
+// Apply compares result with b, loaded from file: the findings b knows about
+// move from result.Findings to result.Baseline.Persisting, and the entries no
+// finding matched are listed in result.Baseline.Resolved.
+func Apply(result *detector.Result, b *Baseline, file string) {
+	matched, used := Match(result.Findings, b)
+	cmp := &detector.BaselineComparison{File: file}
+	var fresh []detector.Finding
+	for i, f := range result.Findings {
+		if matched[i] < 0 {
+			fresh = append(fresh, f)
+		} else {
+			cmp.Persisting = append(cmp.Persisting, f)
+		}
+	}
+	for j, e := range b.Entries {
+		if !used[j] {
+			cmp.Resolved = append(cmp.Resolved, detector.BaselineEntry{
+				Kind:        detector.Kind(e.Kind),
+				Function:    e.Function,
+				Location:    e.Location,
+				Fingerprint: e.Fingerprint,
+			})
+		}
+	}
+	result.Findings = fresh
+	result.Baseline = cmp
+}
+
+// Match pairs findings with baseline entries. matched[i] is the index of the
+// entry that findings[i] matches, or -1 for a new finding; used[j] reports
+// whether entry j matched any finding.
+//
+// A finding matches an entry with the same fingerprint and location, or, for
+// entries without a fingerprint, the same kind and location; identical
+// findings, which Save stored once, share the entry. Findings left over then
+// match, one to one, an unused entry with the same fingerprint, and after
+// that an unused entry of the same kind and function, in the same file at the
+// nearest line: code that moved, with or without its stack changing, or any
+// move of a version 1 entry. Only the fingerprint pass looks in other files.
+func Match(findings []detector.Finding, b *Baseline) (matched []int, used []bool) {
+	current := make([]Entry, len(findings))
+	for i, f := range findings {
+		current[i] = entryOf(f)
+	}
+	return matchEntries(current, b)
+}
+
+// matchEntries is Match for findings already reduced to entries.
+func matchEntries(findings []Entry, b *Baseline) (matched []int, used []bool) {
+	matched = make([]int, len(findings))
+	used = make([]bool, len(b.Entries))
+
+	exact := make(map[[3]string]int, len(b.Entries))
+	for j, e := range b.Entries {
+		key := [3]string{e.Fingerprint, e.Kind, e.Location}
+		if e.Fingerprint != "" {
+			key[1] = ""
+		}
+		if _, ok := exact[key]; !ok {
+			exact[key] = j
+		}
+	}
+	for i, f := range findings {
+		matched[i] = -1
+		j, ok := exact[[3]string{f.Fingerprint, "", f.Location}]
+		if !ok {
+			j, ok = exact[[3]string{"", f.Kind, f.Location}]
+		}
+		if ok {
+			matched[i] = j
+			used[j] = true
+		}
+	}
+
+	// One function can block at several lines under one fingerprint; pair
+	// moved findings with those entries by distance, not first come.
+	matchNearest(findings, b.Entries, matched, used, true, func(f, e Entry) bool {
+		return e.Fingerprint != "" && e.Fingerprint == f.Fingerprint
+	})
+
+	// Fuzzy pass: the same function in the same file, at another line.
+	// Locations inside GOROOT (sync.(*Mutex).Lock) say nothing about which
+	// user code blocked, so they never match fuzzily.
+	goroot := filepath.ToSlash(build.Default.GOROOT) + "/"
+	matchNearest(findings, b.Entries, matched, used, false, func(f, e Entry) bool {
+		if goroot != "/" && strings.HasPrefix(f.Location, goroot) {
+			return false
+		}
+		return e.Kind == f.Kind && e.Function == f.Function && e.Function != ""
+	})
+	return matched, used
+}
+
+// matchNearest pairs each unmatched finding with an unused entry that ok
+// accepts, preferring the same file and the nearest line; with anyFile, an
+// entry in another file is taken when the finding's file has none. The
+// closest pairs are made first, so the result does not depend on the order
+// of findings.
+func matchNearest(findings, entries []Entry, matched []int, used []bool, anyFile bool, ok func(f, e Entry) bool) {
+	type pair struct{ i, j, otherFile, dist int }
+	var pairs []pair
+	for i, f := range findings {
+		if matched[i] >= 0 {
+			continue
+		}
+		file, line := splitLocation(f.Location)
+		for j, e := range entries {
+			if used[j] || !ok(f, e) {
+				continue
+			}
+			p := pair{i: i, j: j}
+			eFile, eLine := splitLocation(e.Location)
+			if eFile != file {
+				if !anyFile {
+					continue
+				}
+				p.otherFile = 1
+			} else if p.dist = line - eLine; p.dist < 0 {
+				p.dist = -p.dist
+			}
+			pairs = append(pairs, p)
+		}
+	}
+	sort.SliceStable(pairs, func(a, b int) bool {
+		if pairs[a].otherFile != pairs[b].otherFile {
+			return pairs[a].otherFile < pairs[b].otherFile
+		}
+		return pairs[a].dist < pairs[b].dist
+	})
+	for _, p := range pairs {
+		if matched[p.i] < 0 && !used[p.j] {
+			matched[p.i] = p.j
+			used[p.j] = true
+		}
+	}
+}
+
+// splitLocation splits "file:line" into the file and the line number (0 if
+// there is none).
+func splitLocation(location string) (file string, line int) {
+	i := strings.LastIndex(location, ":")
+	if i < 0 {
+		return location, 0
+	}
+	line, err := strconv.Atoi(location[i+1:])
+	if err != nil {
+		return location, 0
+	}
+	return location[:i], line
+}
@@ -0,0 +1,175 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+// workerLeak is a leak in app.worker, which blocks either at <-a (line 7) or
+// at <-b (line 9); both findings have the same fingerprint.
+func workerLeak(line string) detector.Finding {
+	return detector.Finding{
+		Kind:      detector.KindGoroutineLeak,
+		BlockedOn: "chan receive",
+		Function:  "example.com/app.worker",
+		Location:  "/app/worker.go:" + line,
+		Stack:     "      example.com/app.worker (/app/worker.go:" + line + ")\n",
+	}
+}
+
+func TestMatchSharedFingerprint(t *testing.T) {
+	b := &Baseline{Entries: []Entry{entryOf(workerLeak("7")), entryOf(workerLeak("9"))}}
+	if b.Entries[0].Fingerprint != b.Entries[1].Fingerprint {
+		t.Fatal("test findings should share a fingerprint")
+	}
+
+	for _, tc := range []struct {
+		name  string
+		lines []string
+		want  []int
+	}{
+		{"unchanged", []string{"7", "9"}, []int{0, 1}},
+		{"unchanged reversed", []string{"9", "7"}, []int{1, 0}},
+		// A line was inserted above both receives.
+		{"both moved", []string{"8", "10"}, []int{0, 1}},
+		{"both moved reversed", []string{"10", "8"}, []int{1, 0}},
+		{"one moved", []string{"9", "8"}, []int{1, 0}},
+		// A third receive is new, not a second match for an entry.
+		{"extra", []string{"7", "9", "11"}, []int{0, 1, -1}},
+		// Identical findings were saved once and share the entry.
+		{"duplicate", []string{"7", "7"}, []int{0, 0}},
+	} {
+		var findings []detector.Finding
+		for _, l := range tc.lines {
+			findings = append(findings, workerLeak(l))
+		}
+		matched, used := Match(findings, b)
+		if !reflect.DeepEqual(matched, tc.want) {
+			t.Errorf("%s: matched = %v, want %v", tc.name, matched, tc.want)
+		}
+		for j, u := range used {
+			want := false
+			for _, m := range tc.want {
+				want = want || m == j
+			}
+			if u != want {
+				t.Errorf("%s: used[%d] = %v, want %v", tc.name, j, u, want)
+			}
+		}
+	}
+}
+
+func TestMatchVersion1Entries(t *testing.T) {
+	b := &Baseline{Entries: []Entry{
+		{Kind: "goroutine_leak", Function: "example.com/app.worker", Location: "/app/worker.go:7"},
+		{Kind: "goroutine_leak", Function: "example.com/app.worker", Location: "/app/worker.go:20"},
+		{Kind: "lock_contention", Function: "example.com/app.(*Cache).Get", Location: "/app/cache.go:30"},
+	}}
+	findings := []Entry{
+		entryOf(workerLeak("7")),
+		// Moved from line 20; line 7 is taken.
+		entryOf(workerLeak("18")),
+		// Same function, other file: new.
+		{Fingerprint: "f1", Kind: "lock_contention", Function: "example.com/app.(*Cache).Get", Location: "/app/cache2.go:30"},
+	}
+	matched, used := matchEntries(findings, b)
+	if want := []int{0, 1, -1}; !reflect.DeepEqual(matched, want) {
+		t.Errorf("matched = %v, want %v", matched, want)
+	}
+	if want := []bool{true, true, false}; !reflect.DeepEqual(used, want) {
+		t.Errorf("used = %v, want %v", used, want)
+	}
+}
+
+func TestApplyReportsOnlyResolvedEntries(t *testing.T) {
+	b := &Baseline{Entries: []Entry{
+		entryOf(workerLeak("7")),
+		entryOf(workerLeak("9")),
+		{Fingerprint: "gone", Kind: "deadlock", Function: "example.com/app.old", Location: "/app/old.go:3"},
+	}}
+	result := &detector.Result{Findings: []detector.Finding{workerLeak("8"), workerLeak("10"), workerLeak("30")}}
+	Apply(result, b, "baseline.json")
+
+	if len(result.Findings) != 1 || result.Findings[0].Location != "/app/worker.go:30" {
+		t.Errorf("new findings = %+v, want only the one at line 30", result.Findings)
+	}
+	cmp := result.Baseline
+	if cmp.File != "baseline.json" || len(cmp.Persisting) != 2 {
+		t.Errorf("comparison %+v, want 2 persisting", cmp)
+	}
+	if len(cmp.Resolved) != 1 || cmp.Resolved[0].Function != "example.com/app.old" {
+		t.Errorf("resolved = %+v, want only app.old", cmp.Resolved)
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	findings := []detector.Finding{workerLeak("7"), workerLeak("9"), workerLeak("7")}
+	if err := Save(findings, path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Version != currentVersion || b.Migrated != 0 || b.Created == "" {
+		t.Errorf("version %d, migrated %d, created %q", b.Version, b.Migrated, b.Created)
+	}
+	want := []Entry{entryOf(workerLeak("7")), entryOf(workerLeak("9"))}
+	if !reflect.DeepEqual(b.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", b.Entries, want)
+	}
+	if fresh := FilterNew(append(findings, workerLeak("12")), b); len(fresh) != 1 || fresh[0].Location != "/app/worker.go:12" {
+		t.Errorf("FilterNew = %+v, want only line 12", fresh)
+	}
+}
+
+func TestLoadVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	v1 := `{
+  "version": 1,
+  "created": "2025-01-02T03:04:05Z",
+  "entries": [
+    {"kind": "goroutine_leak", "function": "example.com/app.worker", "location": "/app/worker.go:7"}
+  ]
+}`
+	if err := os.WriteFile(path, []byte(v1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Version != currentVersion || b.Migrated != 1 {
+		t.Errorf("version %d, migrated %d; want %d, 1", b.Version, b.Migrated, currentVersion)
+	}
+	if len(b.Entries) != 1 || b.Entries[0].Fingerprint != "" || b.Entries[0].Location != "/app/worker.go:7" {
+		t.Fatalf("entries = %+v", b.Entries)
+	}
+	if fresh := FilterNew([]detector.Finding{workerLeak("7")}, b); len(fresh) != 0 {
+		t.Errorf("version 1 entry does not match by location: %+v", fresh)
+	}
+
+	// Writing it back stores the current version.
+	if err := Write(b, path); err != nil {
+		t.Fatal(err)
+	}
+	if b, err = Load(path); err != nil || b.Migrated != 0 || b.Created != "2025-01-02T03:04:05Z" {
+		t.Errorf("after Write: %+v, %v", b, err)
+	}
+}
+
+func TestLoadUnsupportedVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := os.WriteFile(path, []byte(`{"version": 3, "entries": []}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "unsupported baseline version 3") {
+		t.Errorf("Load error = %v", err)
+	}
+}
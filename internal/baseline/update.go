@@ -0,0 +1,142 @@
+package baseline
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// AuditNote records one rewrite of a baseline.
+type AuditNote struct {
+	Time   string `json:"time"`
+	Action string `json:"action"` // "update" or "prune"
+	Report string `json:"report,omitempty"`
+	Kept   int    `json:"kept"`
+	// Refreshed counts kept entries whose location or fingerprint changed,
+	// and findings that no longer share an entry with another.
+	Refreshed int     `json:"refreshed,omitempty"`
+	Added     []Entry `json:"added,omitempty"`
+	Removed   []Entry `json:"removed,omitempty"`
+}
+
+// ReadReport returns the findings of a --format json report as baseline
+// entries: its new findings and, for a report written with --baseline, the
+// findings the baseline already knew about. Suppressed findings are not
+// included.
+func ReadReport(path string) ([]Entry, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var report struct {
+		Findings []reportFinding `json:"findings"`
+		Baseline *struct {
+			Persisting []reportFinding `json:"persisting"`
+		} `json:"baseline"`
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if report.Findings == nil {
+		return nil, fmt.Errorf("%s: not a threadgraph JSON report (no \"findings\")", path)
+	}
+	findings := report.Findings
+	if report.Baseline != nil {
+		findings = append(findings, report.Baseline.Persisting...)
+	}
+
+	var entries []Entry
+	for _, f := range findings {
+		if f.Fingerprint == "" {
+			return nil, fmt.Errorf("%s: findings have no fingerprints; regenerate the report with this version of threadgraph", path)
+		}
+		entries = append(entries, Entry(f))
+	}
+	return entries, nil
+}
+
+// reportFinding is the part of a JSON report finding a baseline entry keeps.
+type reportFinding struct {
+	Fingerprint string `json:"fingerprint"`
+	Kind        string `json:"kind"`
+	Function    string `json:"function"`
+	Location    string `json:"location"`
+	CreatedAt   string `json:"created_at"`
+}
+
+// Update makes b record exactly the current findings: entries that still
+// match a finding are kept, refreshed to the finding's current location and
+// fingerprint; resolved entries are dropped; new findings are added. The
+// change is appended to b.Audit and returned.
+func Update(b *Baseline, current []Entry, report string) AuditNote {
+	return rewrite(b, current, "update", report, true)
+}
+
+// Prune drops the resolved entries of b and refreshes the ones still
+// present, like Update, but never adds new findings.
+func Prune(b *Baseline, current []Entry, report string) AuditNote {
+	return rewrite(b, current, "prune", report, false)
+}
+
+// Changed reports whether the rewrite changed the baseline.
+func (n AuditNote) Changed() bool {
+	return len(n.Added) > 0 || len(n.Removed) > 0 || n.Refreshed > 0
+}
+
+func rewrite(b *Baseline, current []Entry, action, report string, addNew bool) AuditNote {
+	matched, used := matchEntries(current, b)
+	note := AuditNote{
+		Time:   time.Now().UTC().Format(time.RFC3339),
+		Action: action,
+		Report: report,
+	}
+
+	// Each kept entry takes the place of the first finding matching it.
+	// Other findings sharing it, such as ones with different stacks at the
+	// location of a version 1 entry, are kept as entries of their own.
+	refreshed := make(map[int]Entry)
+	seen := make(map[[2]string]bool)
+	var split, added []Entry
+	for i, e := range current {
+		key := [2]string{e.Fingerprint, e.Location}
+		j := matched[i]
+		if _, ok := refreshed[j]; j >= 0 && !ok {
+			refreshed[j] = e
+			seen[key] = true
+			continue
+		}
+		if seen[key] {
+			continue
+		}
+		switch {
+		case j >= 0:
+			seen[key] = true
+			split = append(split, e)
+		case addNew:
+			seen[key] = true
+			added = append(added, e)
+		}
+	}
+
+	var entries []Entry
+	for j, e := range b.Entries {
+		if !used[j] {
+			note.Removed = append(note.Removed, e)
+			continue
+		}
+		if refreshed[j] != e {
+			note.Refreshed++
+		}
+		entries = append(entries, refreshed[j])
+	}
+	entries = append(entries, split...)
+	note.Refreshed += len(split)
+	note.Kept = len(entries)
+	note.Added = added
+	b.Entries = append(entries, added...)
+	if note.Changed() {
+		b.Audit = append(b.Audit, note)
+	}
+	return note
+}
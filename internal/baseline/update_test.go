@@ -0,0 +1,180 @@
+package baseline
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// updateFixture returns a baseline with two entries of app.worker, which
+// share a fingerprint, and one for a function that was since fixed, and the
+// current findings: both worker leaks moved down a line, plus a new one.
+func updateFixture() (*Baseline, []Entry) {
+	b := &Baseline{Created: "2025-01-02T03:04:05Z", Entries: []Entry{
+		entryOf(workerLeak("7")),
+		entryOf(workerLeak("9")),
+		{Fingerprint: "gone", Kind: "deadlock", Function: "example.com/app.old", Location: "/app/old.go:3"},
+	}}
+	current := []Entry{
+		entryOf(workerLeak("10")),
+		entryOf(workerLeak("8")),
+		{Fingerprint: "new", Kind: "deadlock", Function: "example.com/app.fresh", Location: "/app/fresh.go:5"},
+		{Fingerprint: "new", Kind: "deadlock", Function: "example.com/app.fresh", Location: "/app/fresh.go:5"},
+	}
+	return b, current
+}
+
+func TestUpdate(t *testing.T) {
+	b, current := updateFixture()
+	note := Update(b, current, "report.json")
+
+	if note.Action != "update" || note.Report != "report.json" || note.Time == "" {
+		t.Errorf("note = %+v", note)
+	}
+	if note.Kept != 2 || note.Refreshed != 2 {
+		t.Errorf("kept %d (%d refreshed), want 2 (2)", note.Kept, note.Refreshed)
+	}
+	if len(note.Removed) != 1 || note.Removed[0].Function != "example.com/app.old" {
+		t.Errorf("removed = %+v, want only app.old", note.Removed)
+	}
+	if len(note.Added) != 1 || note.Added[0].Function != "example.com/app.fresh" {
+		t.Errorf("added = %+v, want app.fresh once", note.Added)
+	}
+	want := []Entry{current[1], current[0], current[2]}
+	if !reflect.DeepEqual(b.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", b.Entries, want)
+	}
+	if len(b.Audit) != 1 || !reflect.DeepEqual(b.Audit[0], note) {
+		t.Errorf("audit = %+v, want the note", b.Audit)
+	}
+
+	// The baseline now records exactly the findings.
+	again := Update(b, current, "report.json")
+	if again.Changed() || again.Kept != 3 || len(b.Audit) != 1 {
+		t.Errorf("second update changed the baseline: %+v", again)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	b, current := updateFixture()
+	note := Prune(b, current, "report.json")
+
+	if note.Action != "prune" || note.Kept != 2 || note.Refreshed != 2 || len(note.Added) != 0 {
+		t.Errorf("note = %+v, want 2 kept and refreshed, none added", note)
+	}
+	if len(note.Removed) != 1 || note.Removed[0].Function != "example.com/app.old" {
+		t.Errorf("removed = %+v, want only app.old", note.Removed)
+	}
+	if want := []Entry{current[1], current[0]}; !reflect.DeepEqual(b.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", b.Entries, want)
+	}
+	if len(b.Audit) != 1 {
+		t.Errorf("audit has %d notes, want 1", len(b.Audit))
+	}
+}
+
+func TestPruneUnchanged(t *testing.T) {
+	b := &Baseline{Entries: []Entry{entryOf(workerLeak("7"))}}
+	note := Prune(b, []Entry{entryOf(workerLeak("7"))}, "report.json")
+	if note.Changed() || note.Kept != 1 || len(b.Audit) != 0 {
+		t.Errorf("note %+v, audit %+v; want no change and no audit note", note, b.Audit)
+	}
+}
+
+func TestUpdateVersion1(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	v1 := `{
+  "version": 1,
+  "created": "2025-01-02T03:04:05Z",
+  "entries": [
+    {"kind": "goroutine_leak", "function": "example.com/app.worker", "location": "/app/worker.go:7"}
+  ]
+}`
+	if err := os.WriteFile(path, []byte(v1), 0644); err != nil {
+		t.Fatal(err)
+	}
+	b, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Two findings with different stacks at the old entry's location: both
+	// are known, and each gets its own fingerprinted entry.
+	other := entryOf(workerLeak("7"))
+	other.Fingerprint = "other"
+	current := []Entry{entryOf(workerLeak("7")), other}
+	note := Prune(b, current, "report.json")
+	if note.Kept != 2 || note.Refreshed != 2 || len(note.Removed) != 0 {
+		t.Errorf("note = %+v, want both findings kept", note)
+	}
+	if !reflect.DeepEqual(b.Entries, current) {
+		t.Errorf("entries = %+v, want %+v", b.Entries, current)
+	}
+
+	// Writing stores version 2 with the fingerprints and the audit log.
+	if err := Write(b, path); err != nil {
+		t.Fatal(err)
+	}
+	b, err = Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Migrated != 0 || b.Created != "2025-01-02T03:04:05Z" || !reflect.DeepEqual(b.Entries, current) {
+		t.Errorf("reloaded %+v", b)
+	}
+	if len(b.Audit) != 1 || b.Audit[0].Action != "prune" || b.Audit[0].Kept != 2 || b.Audit[0].Report != "report.json" {
+		t.Errorf("reloaded audit = %+v", b.Audit)
+	}
+	if tmp, _ := filepath.Glob(path + ".*.tmp"); len(tmp) != 0 {
+		t.Errorf("Write left %v", tmp)
+	}
+}
+
+func TestReadReport(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, data string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	path := write("report.json", `{
+  "findings": [
+    {"fingerprint": "a1", "kind": "goroutine_leak", "function": "app.worker", "location": "/app/worker.go:7", "created_at": "/app/server.go:30", "goroutine_id": 18}
+  ],
+  "suppressed": [
+    {"fingerprint": "s1", "kind": "deadlock", "function": "app.ignored", "location": "/app/ignored.go:1"}
+  ],
+  "baseline": {
+    "persisting": [
+      {"fingerprint": "b2", "kind": "deadlock", "function": "app.lock", "location": "/app/lock.go:12"}
+    ]
+  }
+}`)
+	entries, err := ReadReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Entry{
+		{Fingerprint: "a1", Kind: "goroutine_leak", Function: "app.worker", Location: "/app/worker.go:7", CreatedAt: "/app/server.go:30"},
+		{Fingerprint: "b2", Kind: "deadlock", Function: "app.lock", Location: "/app/lock.go:12"},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Errorf("entries = %+v, want %+v", entries, want)
+	}
+
+	for _, tc := range []struct{ data, err string }{
+		{`{"entries": []}`, "not a threadgraph JSON report"},
+		{`{"findings": [{"kind": "deadlock", "location": "/app/a.go:1"}]}`, "no fingerprints"},
+		{`{"findings": [`, "unexpected end"},
+	} {
+		_, err := ReadReport(write("bad.json", tc.data))
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("ReadReport(%s) error = %v, want %q", tc.data, err, tc.err)
+		}
+	}
+}
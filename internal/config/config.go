@@ -0,0 +1,226 @@
+// Package config loads .threadgraph.yaml, the per-repository settings file:
+// detector thresholds, which detectors run, suppression rules for findings
+// to ignore, and default values for command-line flags.
+//
+//	thresholds:
+//	  deadlock: 250ms
+//	  abba_stale_window: 10s
+//	  orphan_max_trace: 200ms
+//	  leak_high_ratio: 0.9
+//	  leak_medium_ratio: 0.5
+//	  sync_history: 8
+//	detectors:
+//	  orphans: false
+//	ignore:
+//	  - kind: goroutine_leak
+//	    function: '^github.com/acme/api/internal/pool\.\(\*Pool\)\.worker$'
+//	    reason: pool workers live as long as the process
+//	  - location: 'vendor/**'
+//	    reason: third-party code
+//	defaults:
+//	  min-block: 2s
+//	  no-llm: true
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/suppress"
+	"gopkg.in/yaml.v3"
+)
+
+// FileName is the name of the config file looked for by Find.
+const FileName = ".threadgraph.yaml"
+
+// Config is a parsed and validated config file.
+type Config struct {
+	// Path is the file the config was loaded from.
+	Path string `yaml:"-"`
+
+	Thresholds Thresholds `yaml:"thresholds"`
+	// Detectors enables (true) or disables (false) detectors by name; see
+	// detector.DetectorNames. Unlisted detectors stay enabled.
+	Detectors map[string]bool `yaml:"detectors"`
+	// Ignore holds suppression rules; see suppress.Rule.
+	Ignore []suppress.Rule `yaml:"ignore"`
+	// Defaults holds default values for command-line flags, keyed by flag
+	// name without dashes. Flags given on the command line take precedence.
+	Defaults map[string]any `yaml:"defaults"`
+
+	thresholds   detector.Thresholds
+	suppressions *suppress.Set
+}
+
+// Thresholds is the thresholds section. Durations use Go syntax ("500ms").
+type Thresholds struct {
+	Deadlock        string  `yaml:"deadlock"`
+	ABBAStaleWindow string  `yaml:"abba_stale_window"`
+	OrphanMaxTrace  string  `yaml:"orphan_max_trace"`
+	LeakHighRatio   float64 `yaml:"leak_high_ratio"`
+	LeakMediumRatio float64 `yaml:"leak_medium_ratio"`
+	SyncHistory     int     `yaml:"sync_history"`
+}
+
+// Find looks for FileName in dir and its parents and returns the path of the
+// first one found, or "" if there is none.
+func Find(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for {
+		path := filepath.Join(dir, FileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !errors.Is(err, os.ErrNotExist) {
+			return "", err
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// Load reads and validates the config file at path.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	c.Path = path
+	return c, nil
+}
+
+// Parse parses and validates config file contents. Unknown keys are errors.
+func Parse(data []byte) (*Config, error) {
+	c := &Config{}
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(c); err != nil && err != io.EOF {
+		return nil, err
+	}
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Config) validate() error {
+	t := &c.thresholds
+	for _, d := range []struct {
+		key   string
+		value string
+		dst   *time.Duration
+	}{
+		{"thresholds.deadlock", c.Thresholds.Deadlock, &t.Deadlock},
+		{"thresholds.abba_stale_window", c.Thresholds.ABBAStaleWindow, &t.ABBAStaleWindow},
+		{"thresholds.orphan_max_trace", c.Thresholds.OrphanMaxTrace, &t.OrphanMaxTrace},
+	} {
+		if d.value == "" {
+			continue
+		}
+		v, err := time.ParseDuration(d.value)
+		if err != nil {
+			return fmt.Errorf("%s: %q is not a duration (e.g. 500ms, 5s)", d.key, d.value)
+		}
+		if v <= 0 {
+			return fmt.Errorf("%s: must be positive, got %s", d.key, d.value)
+		}
+		*d.dst = v
+	}
+
+	for _, r := range []struct {
+		key   string
+		value float64
+	}{
+		{"thresholds.leak_high_ratio", c.Thresholds.LeakHighRatio},
+		{"thresholds.leak_medium_ratio", c.Thresholds.LeakMediumRatio},
+	} {
+		if r.value < 0 || r.value > 1 {
+			return fmt.Errorf("%s: must be between 0 and 1, got %v", r.key, r.value)
+		}
+	}
+	t.HighRatio = c.Thresholds.LeakHighRatio
+	t.MediumRatio = c.Thresholds.LeakMediumRatio
+	if resolved := t.WithDefaults(); resolved.MediumRatio >= resolved.HighRatio {
+		return fmt.Errorf("thresholds: leak_medium_ratio (%v) must be below leak_high_ratio (%v)",
+			resolved.MediumRatio, resolved.HighRatio)
+	}
+
+	if n := c.Thresholds.SyncHistory; n < 0 || n > 64 {
+		return fmt.Errorf("thresholds.sync_history: must be between 0 and 64 (0 for the default), got %d", n)
+	}
+	t.SyncHistory = c.Thresholds.SyncHistory
+
+	for name := range c.Detectors {
+		if !slices.Contains(detector.DetectorNames, name) {
+			return fmt.Errorf("detectors: unknown detector %q (known: %s)",
+				name, strings.Join(detector.DetectorNames, ", "))
+		}
+	}
+
+	var err error
+	if c.suppressions, err = suppress.Compile("ignore", c.Ignore); err != nil {
+		return err
+	}
+
+	for name, v := range c.Defaults {
+		switch v.(type) {
+		case string, bool, int, float64, []any:
+		default:
+			return fmt.Errorf("defaults.%s: unsupported value %v", name, v)
+		}
+	}
+	return nil
+}
+
+// Apply sets the thresholds and disabled detectors of opts from c.
+func (c *Config) Apply(opts *detector.Options) {
+	opts.Thresholds = c.thresholds
+	for name, enabled := range c.Detectors {
+		if enabled {
+			continue
+		}
+		if opts.Disabled == nil {
+			opts.Disabled = make(map[string]bool)
+		}
+		opts.Disabled[name] = true
+	}
+}
+
+// Suppressions returns the compiled ignore rules.
+func (c *Config) Suppressions() *suppress.Set {
+	return c.suppressions
+}
+
+// DefaultValues returns the flag values for name from the defaults section:
+// one value for scalars, one per element for lists (repeatable flags).
+func (c *Config) DefaultValues(name string) []string {
+	switch v := c.Defaults[name].(type) {
+	case nil:
+		return nil
+	case []any:
+		vals := make([]string, len(v))
+		for i, e := range v {
+			vals[i] = fmt.Sprint(e)
+		}
+		return vals
+	default:
+		return []string{fmt.Sprint(v)}
+	}
+}
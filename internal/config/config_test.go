@@ -0,0 +1,34 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSyncHistory(t *testing.T) {
+	for _, tc := range []struct {
+		yaml string
+		want int    // resolved SyncHistory
+		err  string // error substring, if invalid
+	}{
+		{yaml: "thresholds:\n  sync_history: 0\n", want: 5},
+		{yaml: "thresholds:\n  sync_history: 64\n", want: 64},
+		{yaml: "thresholds:\n  sync_history: 65\n", err: "must be between 0 and 64 (0 for the default), got 65"},
+		{yaml: "thresholds:\n  sync_history: -1\n", err: "got -1"},
+	} {
+		c, err := Parse([]byte(tc.yaml))
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("Parse(%q) error = %v, want %q", tc.yaml, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tc.yaml, err)
+			continue
+		}
+		if got := c.thresholds.WithDefaults().SyncHistory; got != tc.want {
+			t.Errorf("Parse(%q): SyncHistory = %d, want %d", tc.yaml, got, tc.want)
+		}
+	}
+}
@@ -9,23 +9,15 @@ import (
 	"golang.org/x/exp/trace"
 )
 
-// abbaStaleWindow is how old a sync history entry can be before we ignore it
-// for AB-BA detection. Prevents false positives from unrelated lock sequences.
-const abbaStaleWindow = 5 * time.Second
-
-// deadlockThreshold is how long a goroutine must be blocked on a mutex
-// before it is considered a potential deadlock participant.
-const deadlockThreshold = 500 * time.Millisecond
-
 // detectDeadlocks identifies partial deadlocks: groups of goroutines all
-// blocked on mutex lock for longer than the threshold.
+// blocked on mutex lock for longer than Thresholds.Deadlock.
 //
 // The Go runtime panics on full deadlock, so we target partial deadlocks
 // (a subset of goroutines stuck). We group by call site similarity:
 // if 2+ goroutines are stuck at the same mutex-lock call site for a long
 // time, that is flagged as a potential deadlock.
 func detectDeadlocks(goroutines map[trace.GoID]*goroutineState, lastTime trace.Time, opts Options) []Finding {
-	threshold := deadlockThreshold
+	threshold := opts.Thresholds.Deadlock
 	if opts.MinBlock > 0 && opts.MinBlock < threshold {
 		threshold = opts.MinBlock
 	}
@@ -112,14 +104,135 @@ func detectDeadlocks(goroutines map[trace.GoID]*goroutineState, lastTime trace.T
 //
 // Complexity: O(V+E) — Tarjan's algorithm is linear in graph size.
 // The previous AB-BA check was O(E²) in the number of edges.
-func detectLockCycles(goroutines map[trace.GoID]*goroutineState, lastTime trace.Time) []Finding {
-	type graphEdge struct {
-		to  string
-		gid trace.GoID
-		g   *goroutineState
+func detectLockCycles(goroutines map[trace.GoID]*goroutineState, lastTime trace.Time, opts Options) []Finding {
+	adj := lockWaitEdges(goroutines, lastTime, opts)
+	if len(adj) == 0 {
+		return nil
+	}
+	sccs := lockWaitCycles(adj)
+
+	// --- Map each SCC to a Finding ---
+	var findings []Finding
+	seenSCC := make(map[string]bool)
+
+	for _, scc := range sccs {
+		sccSet := make(map[string]bool, len(scc))
+		for _, node := range scc {
+			sccSet[node] = true
+		}
+
+		// Representative = goroutine blocked the longest within the cycle.
+		var bestGID trace.GoID
+		var bestG *goroutineState
+		var bestBlocked time.Duration
+
+		for _, from := range scc {
+			for _, edge := range adj[from] {
+				if !sccSet[edge.to] {
+					continue
+				}
+				blocked := time.Duration(lastTime-edge.g.blockStart) * time.Nanosecond
+				if blocked > bestBlocked {
+					bestBlocked = blocked
+					bestGID = edge.gid
+					bestG = edge.g
+				}
+			}
+		}
+		if bestG == nil {
+			continue
+		}
+
+		// Canonical dedup key: sort SCC node list and join.
+		sorted := make([]string, len(scc))
+		copy(sorted, scc)
+		sort.Strings(sorted)
+		key := strings.Join(sorted, "|")
+		if seenSCC[key] {
+			continue
+		}
+		seenSCC[key] = true
+
+		cycleDesc := "sync (AB-BA lock inversion)"
+		if len(scc) > 2 {
+			cycleDesc = fmt.Sprintf("sync (%d-way lock cycle)", len(scc))
+		}
+
+		findings = append(findings, Finding{
+			Kind:        KindDeadlock,
+			Confidence:  ConfidenceMedium,
+			GoroutineID: bestGID,
+			BlockedOn:   cycleDesc,
+			BlockedFor:  bestBlocked,
+			Stack:       bestG.stack,
+			Function:    bestG.function,
+			Location:    bestG.location,
+		})
+	}
+
+	return findings
+}
+
+// LockWaitGraph is the runtime lock-wait graph detectLockCycles searches
+// for cycles, recorded when Options.LockWait is set. Nodes are lock call
+// sites (file:line); an edge From→To means a goroutine recently acquired a
+// lock at From and is now blocked on one at To.
+type LockWaitGraph struct {
+	Edges []LockWaitEdge // sorted by From, To and Goroutine
+	// Cycles are the strongly connected components of two or more sites,
+	// each sorted: the lock-ordering cycles reported as deadlocks.
+	Cycles [][]string
+}
+
+// LockWaitEdge is an edge of a LockWaitGraph.
+type LockWaitEdge struct {
+	From      string
+	To        string
+	Goroutine trace.GoID
+	// Function is the blocked goroutine's top user-code function, the one
+	// waiting for the lock at To.
+	Function string
+}
+
+// lockWaitGraph returns the lock-wait graph of goroutines in exported form.
+func lockWaitGraph(goroutines map[trace.GoID]*goroutineState, lastTime trace.Time, opts Options) *LockWaitGraph {
+	adj := lockWaitEdges(goroutines, lastTime, opts)
+	lw := &LockWaitGraph{}
+	for from, edges := range adj {
+		for _, e := range edges {
+			lw.Edges = append(lw.Edges, LockWaitEdge{From: from, To: e.to, Goroutine: e.gid, Function: e.g.function})
+		}
+	}
+	sort.Slice(lw.Edges, func(i, j int) bool {
+		a, b := lw.Edges[i], lw.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.To != b.To {
+			return a.To < b.To
+		}
+		return a.Goroutine < b.Goroutine
+	})
+	for _, scc := range lockWaitCycles(adj) {
+		sort.Strings(scc)
+		lw.Cycles = append(lw.Cycles, scc)
 	}
+	sort.Slice(lw.Cycles, func(i, j int) bool { return lw.Cycles[i][0] < lw.Cycles[j][0] })
+	return lw
+}
+
+// lockWaitEdge is an edge of the lock-wait graph: goroutine gid (state g) is
+// blocked on the lock at to.
+type lockWaitEdge struct {
+	to  string
+	gid trace.GoID
+	g   *goroutineState
+}
 
-	adj := make(map[string][]graphEdge)
+// lockWaitEdges builds the lock-wait graph detectLockCycles searches, as an
+// adjacency list keyed by lock call site.
+func lockWaitEdges(goroutines map[trace.GoID]*goroutineState, lastTime trace.Time, opts Options) map[string][]lockWaitEdge {
+	adj := make(map[string][]lockWaitEdge)
 
 	for gid, g := range goroutines {
 		if !g.isBlocked || g.reason != "sync" {
@@ -136,19 +249,20 @@ func detectLockCycles(goroutines map[trace.GoID]*goroutineState, lastTime trace.
 				continue
 			}
 			age := time.Duration(lastTime-entry.endTime) * time.Nanosecond
-			if age > abbaStaleWindow {
+			if age > opts.Thresholds.ABBAStaleWindow {
 				continue
 			}
-			adj[entry.location] = append(adj[entry.location], graphEdge{
+			adj[entry.location] = append(adj[entry.location], lockWaitEdge{
 				to: g.location, gid: gid, g: g,
 			})
 		}
 	}
+	return adj
+}
 
-	if len(adj) == 0 {
-		return nil
-	}
-
+// lockWaitCycles returns the strongly connected components of the lock-wait
+// graph adj with at least two nodes, found with Tarjan's algorithm.
+func lockWaitCycles(adj map[string][]lockWaitEdge) [][]string {
 	// Collect all nodes (both endpoints of every edge).
 	nodeSet := make(map[string]bool)
 	for from, edges := range adj {
@@ -215,67 +329,7 @@ func detectLockCycles(goroutines map[trace.GoID]*goroutineState, lastTime trace.
 			strongConnect(node)
 		}
 	}
-
-	// --- Map each SCC to a Finding ---
-	var findings []Finding
-	seenSCC := make(map[string]bool)
-
-	for _, scc := range sccs {
-		sccSet := make(map[string]bool, len(scc))
-		for _, node := range scc {
-			sccSet[node] = true
-		}
-
-		// Representative = goroutine blocked the longest within the cycle.
-		var bestGID trace.GoID
-		var bestG *goroutineState
-		var bestBlocked time.Duration
-
-		for _, from := range scc {
-			for _, edge := range adj[from] {
-				if !sccSet[edge.to] {
-					continue
-				}
-				blocked := time.Duration(lastTime-edge.g.blockStart) * time.Nanosecond
-				if blocked > bestBlocked {
-					bestBlocked = blocked
-					bestGID = edge.gid
-					bestG = edge.g
-				}
-			}
-		}
-		if bestG == nil {
-			continue
-		}
-
-		// Canonical dedup key: sort SCC node list and join.
-		sorted := make([]string, len(scc))
-		copy(sorted, scc)
-		sort.Strings(sorted)
-		key := strings.Join(sorted, "|")
-		if seenSCC[key] {
-			continue
-		}
-		seenSCC[key] = true
-
-		cycleDesc := "sync (AB-BA lock inversion)"
-		if len(scc) > 2 {
-			cycleDesc = fmt.Sprintf("sync (%d-way lock cycle)", len(scc))
-		}
-
-		findings = append(findings, Finding{
-			Kind:        KindDeadlock,
-			Confidence:  ConfidenceMedium,
-			GoroutineID: bestGID,
-			BlockedOn:   cycleDesc,
-			BlockedFor:  bestBlocked,
-			Stack:       bestG.stack,
-			Function:    bestG.function,
-			Location:    bestG.location,
-		})
-	}
-
-	return findings
+	return sccs
 }
 
 // detectChanLockCycle detects the pattern: goroutine G1 holds a mutex (last
@@ -283,7 +337,7 @@ func detectLockCycles(goroutines map[trace.GoID]*goroutineState, lastTime trace.
 // while goroutine G2 is blocked on "sync" at L_lock waiting for the same
 // mutex G1 holds. This creates a cycle: G1 waits for channel (which G2 would
 // service), G2 waits for G1's lock.
-func detectChanLockCycle(goroutines map[trace.GoID]*goroutineState, lastTime trace.Time) []Finding {
+func detectChanLockCycle(goroutines map[trace.GoID]*goroutineState, lastTime trace.Time, opts Options) []Finding {
 	// Build map: sync call site → goroutines blocked there waiting for the lock
 	lockWaiters := make(map[string]bool)
 	for _, g := range goroutines {
@@ -319,7 +373,7 @@ func detectChanLockCycle(goroutines map[trace.GoID]*goroutineState, lastTime tra
 			continue
 		}
 		age := time.Duration(lastTime-g.prevSyncEndTime) * time.Nanosecond
-		if age > abbaStaleWindow {
+		if age > opts.Thresholds.ABBAStaleWindow {
 			continue
 		}
 		if !lockWaiters[g.prevSyncLocation] {
@@ -5,6 +5,8 @@ import (
 	"io"
 	"log"
 	"os"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
 
@@ -23,6 +25,9 @@ const (
 	KindDataRace      Kind = "data_race"  // race detector: concurrent unsynchronized memory access
 )
 
+// Kinds lists every Kind.
+var Kinds = []Kind{KindGoroutineLeak, KindDeadlock, KindLongBlock, KindLockLeak, KindLockOrder, KindDataRace}
+
 // Confidence indicates how certain we are about a finding.
 type Confidence string
 
@@ -36,6 +41,62 @@ const (
 type Options struct {
 	MinBlock      time.Duration
 	DebugFiltered bool // print goroutines filtered out of findings to stderr
+	// Iterations is the -count of a stress run. When > 0, Analyze reports
+	// per-invocation leak counts for each leaking test in Result.LeakRates.
+	Iterations int
+	// Unsuppressed, if set, returns the findings it is given minus those a
+	// suppression rule matches. Leak rates then count only the leaks it
+	// keeps; the findings themselves are left for the caller to suppress.
+	Unsuppressed func([]Finding) []Finding
+	// Streaming bounds memory on very large traces: the state of exited
+	// goroutines that no detector reports on is reduced to a small tombstone
+	// as soon as they exit. Findings are the same as without Streaming.
+	Streaming bool
+	// From and To restrict analysis to part of the trace, as offsets from its
+	// first event (To == 0 means the end of the trace). Region restricts it
+	// to the first instance of the named runtime/trace user region.
+	// Goroutines are judged blocked or leaked as of the window's end.
+	From   time.Duration
+	To     time.Duration
+	Region string
+	// Service analyzes a trace or dump of a long-running service instead of
+	// a test binary: the test-ownership filter is replaced by ServiceRoots,
+	// and goroutines already waiting when the trace starts count as blocked
+	// only if StartDump shows how long they have waited.
+	Service bool
+	// ServiceRoots selects, in service mode, the goroutines whose trees are
+	// eligible for findings: those with a function matching any pattern in
+	// their blocking or creation stack, plus their descendants. Empty means
+	// every goroutine.
+	ServiceRoots []*regexp.Regexp
+	// StartDump is a goroutine dump taken as the trace started. In service
+	// mode the trace does not record why or since when already-waiting
+	// goroutines are blocked; their reason and wait are taken from the dump
+	// instead. The dump prints waits of a minute or more, so shorter ones,
+	// like idle workers between jobs, are not reported.
+	StartDump string
+	// MinGrowth and Steadiness are the GrowthTracker thresholds: a
+	// fingerprint is reported once its goroutine count has grown by at least
+	// MinGrowth and did not fall in at least the Steadiness fraction of
+	// sampling intervals. Zero selects the defaults (5 and 0.8).
+	MinGrowth  int
+	Steadiness float64
+	// IgnorePreexisting keeps goroutines that already existed when the trace
+	// started out of findings; they still root the goroutine tree. For
+	// traces started part-way through a process, where earlier goroutines
+	// are not the traced code's doing.
+	IgnorePreexisting bool
+	// Thresholds tunes the detectors; zero fields take their defaults.
+	Thresholds Thresholds
+	// Disabled turns off the detectors whose names (DetectorNames) map to
+	// true.
+	Disabled map[string]bool
+	// Timeline records the state changes of every goroutine in
+	// Result.Timeline, for the HTML report.
+	Timeline bool
+	// LockWait records the lock-wait graph of the lock_cycles detector in
+	// Result.LockWait.
+	LockWait bool
 }
 
 // Finding represents a single detected concurrency issue.
@@ -48,10 +109,37 @@ type Finding struct {
 	Stack       string
 	Function    string // top user-code function
 	Location    string // file:line of top user-code frame
-	// Count is the number of goroutines with the same (kind, location)
-	// signature. After deduplication, a Count > 1 means multiple goroutines
-	// are exhibiting the same bug from the same call site.
+	// Count is the number of distinct goroutines with the same (kind,
+	// location) signature. After deduplication, a Count > 1 means multiple
+	// goroutines are exhibiting the same bug from the same call site.
 	Count int
+	// Package is the import path of the package whose trace produced this
+	// finding. Only set for results assembled by Merge.
+	Package string
+	// Test is the top-level test (e.g. "TestFoo") whose goroutine tree the
+	// goroutine belongs to, or a "TestFoo/sub" name taken from a trace
+	// region or task. Empty if the owning test could not be determined.
+	// For a finding covering goroutines of several tests (Count > 1), it is
+	// the test of the representative goroutine, and Tests lists them all,
+	// sorted; Tests is nil otherwise. See TestNames.
+	Test  string
+	Tests []string
+	// Evidence lists supporting observations from the trace, such as the
+	// chain of goroutines that last woke the blocked goroutine.
+	Evidence []string
+	// CreationLocation is the file:line of the 'go' statement that started
+	// the goroutine, when known, and CreationFunction the function
+	// containing it.
+	CreationFunction string
+	CreationLocation string
+	// SpawnPath is the chain of 'go' statements that led to the goroutine,
+	// found by walking its parent goroutines: outermost first, ending with
+	// the one at CreationLocation. See Patterns.
+	SpawnPath []SpawnSite
+	// Hits is the number of runs of a repeated analysis (see MergeRuns)
+	// the finding appeared in, out of Runs; both are zero otherwise.
+	Hits int
+	Runs int
 }
 
 // Result holds all findings from one analysis pass.
@@ -60,10 +148,108 @@ type Result struct {
 	DurationMs         int64
 	GoroutinesAnalyzed int
 	Findings           []Finding
+	// Package is the package this result was captured for, if known.
+	Package string
+	// Tests lists the top-level tests the trace or dump shows goroutines of,
+	// sorted. Findings are attributed to them through Finding.Test. A test
+	// that never blocks or starts a goroutine leaves no stack naming it and
+	// is missing.
+	Tests []string
+	// Packages summarizes each package that contributed to a merged result.
+	// Empty for single-trace results.
+	Packages []PackageSummary
+	// LeakRates is set for stress runs (Options.Iterations > 0).
+	LeakRates []LeakRate
+	// PeakHeapBytes is the largest live heap observed while analyzing the
+	// trace (for merged results, the largest over all packages).
+	PeakHeapBytes uint64
+	// Window is the analyzed part of the trace when Options.From, To or
+	// Region were set; nil when the whole trace was analyzed.
+	Window *Window
+	// Suppressed holds the findings removed by suppression rules, and
+	// UnusedSuppressions describes the rules that matched no finding.
+	Suppressed         []Suppressed
+	UnusedSuppressions []string
+	// Baseline compares the findings with a baseline file (--baseline); nil
+	// when none was given. Findings then holds only the new findings.
+	Baseline *BaselineComparison
+	// Repeat describes a repeated analysis (see MergeRuns); nil otherwise.
+	Repeat *Repeat
+	// Timeline is the lifecycle of each goroutine when Options.Timeline was
+	// set; nil otherwise, for goroutine dumps, and for merged results, which
+	// keep it per package in Packages.
+	Timeline *Timeline
+	// LockWait is the lock-wait graph when Options.LockWait was set; nil
+	// otherwise and for merged results.
+	LockWait *LockWaitGraph
+}
+
+// Suppressed is a finding removed from a Result by a suppression rule.
+type Suppressed struct {
+	Finding Finding
+	Rule    string // description of the matching rule
+	Reason  string // the rule's reason
+}
+
+// BaselineComparison is the outcome of comparing a Result with a baseline.
+type BaselineComparison struct {
+	File string
+	// Persisting holds the findings the baseline already knew about.
+	Persisting []Finding
+	// Resolved holds the baseline entries no finding matched any more.
+	Resolved []BaselineEntry
+}
+
+// BaselineEntry is a finding recorded in a baseline file.
+type BaselineEntry struct {
+	Kind        Kind
+	Function    string
+	Location    string
+	Fingerprint string
 }
 
-// syncHistorySize is the number of recent sync-unblock sites to remember per goroutine.
-const syncHistorySize = 5
+// PackageSummary holds the per-package statistics of a merged Result.
+type PackageSummary struct {
+	Package            string
+	TraceFile          string
+	DurationMs         int64
+	GoroutinesAnalyzed int
+	Tests              []string
+	Timeline           *Timeline
+}
+
+// Merge combines the results of several per-package analyses into one Result.
+// Each finding is tagged with the Package of the result it came from, and the
+// per-package goroutine counts and durations are kept in Packages. The merged
+// DurationMs and GoroutinesAnalyzed are the sums over all packages.
+func Merge(results []*Result) *Result {
+	if len(results) == 1 {
+		return results[0]
+	}
+	merged := &Result{}
+	for _, r := range results {
+		merged.DurationMs += r.DurationMs
+		merged.GoroutinesAnalyzed += r.GoroutinesAnalyzed
+		merged.PeakHeapBytes = max(merged.PeakHeapBytes, r.PeakHeapBytes)
+		merged.Packages = append(merged.Packages, PackageSummary{
+			Package:            r.Package,
+			TraceFile:          r.TraceFile,
+			DurationMs:         r.DurationMs,
+			GoroutinesAnalyzed: r.GoroutinesAnalyzed,
+			Tests:              r.Tests,
+			Timeline:           r.Timeline,
+		})
+		for _, f := range r.Findings {
+			f.Package = r.Package
+			merged.Findings = append(merged.Findings, f)
+		}
+		for _, lr := range r.LeakRates {
+			lr.Package = r.Package
+			merged.LeakRates = append(merged.LeakRates, lr)
+		}
+	}
+	return merged
+}
 
 // syncEntry records one sync-primitive unblock (= lock acquisition site).
 type syncEntry struct {
@@ -79,12 +265,21 @@ type goroutineState struct {
 	location   string
 	blockStart trace.Time
 	isBlocked  bool
+	// waitingAtStart is set while a service trace's goroutine is still in
+	// the wait it was already in when the trace started, and wait is the
+	// duration a goroutine dump printed for it (0 if under a minute).
+	waitingAtStart bool
+	wait           time.Duration
 
 	// provenance: filled when the goroutine is first created
 	creationStack    string // stack at go func() call site
 	creationSeen     bool   // true = we saw GoNotExist→GoRunnable for this goroutine
 	creationFunction string // top user-code function at creation site
 	creationLocation string // file:line at creation site
+	// spawn is the creator's stack, whose top user-code frame is the 'go'
+	// statement itself; nil if the creation was not seen. It is shared
+	// with the stack table, so tombstones keep it cheaply.
+	spawn *stackInfo
 
 	// transient long block: most recent completed block that exceeded threshold
 	prevLongBlockReason   string
@@ -93,9 +288,10 @@ type goroutineState struct {
 	prevLongBlockLocation string
 	prevLongBlockDuration time.Duration
 
-	// Lock sequence history: circular buffer of last syncHistorySize sync unblocks.
+	// Lock sequence history: circular buffer of the last
+	// Thresholds.SyncHistory sync unblocks, allocated on first use.
 	// Used for both AB-BA detection (single prevSyncLocation) and multi-step cycle detection.
-	syncHistory    [syncHistorySize]syncEntry
+	syncHistory    []syncEntry
 	syncHistoryIdx int // total entries written (not capped at buffer size)
 
 	// prevSyncLocation is the most-recent sync unblock site (kept for
@@ -114,19 +310,32 @@ type goroutineState struct {
 	// True if this goroutine is descended from the testing framework.
 	// Only test-owned goroutines are reported as findings.
 	isTestOwned bool
+
+	// testName is the test this goroutine runs or was spawned by. Set from
+	// the frame called by testing.tRunner in any stack seen for the
+	// goroutine, or from a trace region/task named like a test; inherited
+	// from the nearest named ancestor by markTestOwned().
+	testName string
+	// outerTestNames holds testName from before each open test-named
+	// region, innermost last; the top is restored when that region ends.
+	outerTestNames []string
+
+	// lastWaker is the goroutine that most recently made this goroutine
+	// runnable from a wakeable block (channel op, sync primitive, select),
+	// and lastWakeLocation the site it was blocked at. See waitfor.go.
+	lastWaker        trace.GoID
+	lastWakeLocation string
 }
 
 // syncHistoryList returns recent sync unblock entries, most recent first.
 func (g *goroutineState) syncHistoryList() []syncEntry {
-	n := g.syncHistoryIdx
-	if n > syncHistorySize {
-		n = syncHistorySize
-	}
+	size := len(g.syncHistory)
+	n := min(g.syncHistoryIdx, size)
 	result := make([]syncEntry, 0, n)
 	for i := 0; i < n; i++ {
-		pos := (g.syncHistoryIdx - 1 - i) % syncHistorySize
+		pos := (g.syncHistoryIdx - 1 - i) % size
 		if pos < 0 {
-			pos += syncHistorySize
+			pos += size
 		}
 		if g.syncHistory[pos].location != "" {
 			result = append(result, g.syncHistory[pos])
@@ -143,14 +352,40 @@ func Analyze(path string, opts Options) (*Result, error) {
 	}
 	defer f.Close()
 
-	r, err := trace.NewReader(f)
+	result, err := AnalyzeReader(f, opts)
+	if err != nil {
+		return nil, err
+	}
+	result.TraceFile = path
+	return result, nil
+}
+
+// AnalyzeReader is Analyze for a trace read from r, such as one captured
+// in-process with runtime/trace. Result.TraceFile is left empty.
+func AnalyzeReader(rd io.Reader, opts Options) (*Result, error) {
+	r, err := trace.NewReader(rd)
 	if err != nil {
 		return nil, err
 	}
+	opts.Thresholds = opts.Thresholds.WithDefaults()
 
 	goroutines := make(map[trace.GoID]*goroutineState)
+	tombstones := make(map[trace.GoID]lineage) // streaming mode only
+	stacks := newStackTable()
+	heap := newHeapSampler()
+	wakes := newWakeGraph()
+	timeline := newTimelineRecorder(opts)
+	isRoot := opts.rootFilter()
+	win := newWindow(opts)
+	var startDump map[trace.GoID]*goroutineState
+	if opts.Service && opts.StartDump != "" {
+		startDump, _ = parseDump(opts.StartDump, defaultDumpWindow)
+	}
 	var firstTime, lastTime trace.Time
 	first := true
+	entered := false
+	exitedBefore := 0 // goroutines that exited before the window
+	events := 0
 
 	for {
 		ev, err := r.ReadEvent()
@@ -162,11 +397,37 @@ func Analyze(path string, opts Options) (*Result, error) {
 			break
 		}
 
+		inWindow, ok := win.advance(&ev, first)
+		if !ok {
+			break
+		}
 		if first {
 			firstTime = ev.Time()
 			first = false
+			timeline.begin(firstTime)
 		}
 		lastTime = ev.Time()
+		if inWindow && !entered {
+			entered = true
+			exitedBefore = win.enter(goroutines, tombstones, isRoot)
+		}
+
+		if events++; events%heapSampleInterval == 0 {
+			heap.read()
+		}
+
+		// User regions and tasks named like tests (e.g. "TestFoo/sub") are
+		// more specific than the stack-derived top-level test name.
+		switch ev.Kind() {
+		case trace.EventRegionBegin:
+			nameGoroutine(goroutines, ev.Goroutine(), ev.Region().Type, true)
+		case trace.EventRegionEnd:
+			nameGoroutine(goroutines, ev.Goroutine(), ev.Region().Type, false)
+		case trace.EventTaskBegin:
+			nameGoroutine(goroutines, ev.Goroutine(), ev.Task().Type, true)
+		case trace.EventSync:
+			stacks.newGeneration()
+		}
 
 		if ev.Kind() != trace.EventStateTransition {
 			continue
@@ -190,8 +451,21 @@ func Analyze(path string, opts Options) (*Result, error) {
 		// st.Resource.Goroutine() (= gid) is the newly created child.
 		if from == trace.GoNotExist {
 			g.parentID = ev.Goroutine()
-			g.creationStack, g.creationFunction, g.creationLocation = extractStack(st.Stack)
+			si := stacks.lookup(st.Stack)
+			g.creationStack, g.creationFunction, g.creationLocation = si.stack, si.function, si.location
 			g.creationSeen = true
+			// ev.Stack() is the creator's stack, topped by the 'go'
+			// statement: if it runs under tRunner, it names the test that
+			// executed the statement.
+			// The child starts out in its creator's test (or open region).
+			spawn := stacks.lookup(ev.Stack())
+			g.spawn = spawn
+			if parent := goroutines[g.parentID]; parent != nil {
+				if parent.testName == "" {
+					parent.testName = spawn.testName
+				}
+				g.testName = parent.testName
+			}
 		}
 
 		// Goroutine died — record for orphan detection
@@ -199,12 +473,24 @@ func Analyze(path string, opts Options) (*Result, error) {
 			g.goroutineDead = true
 		}
 
-		// Goroutine just blocked
-		if from.Executing() && to == trace.GoWaiting {
+		// Goroutine just blocked. A trace of a running service starts with
+		// GoUndetermined → GoWaiting for goroutines that were already
+		// blocked; their block started no later than the trace. Every
+		// generation repeats these transitions, which must not restart a
+		// block already seen.
+		if (from.Executing() || opts.Service && from == trace.GoUndetermined && !g.isBlocked) && to == trace.GoWaiting {
 			g.isBlocked = true
+			g.waitingAtStart = from == trace.GoUndetermined
 			g.reason = st.Reason
+			if d := startDump[gid]; g.reason == "" && from == trace.GoUndetermined && d != nil && d.isBlocked {
+				g.reason = d.reason
+			}
 			g.blockStart = ev.Time()
-			g.stack, g.function, g.location = extractStack(st.Stack)
+			si := stacks.lookup(st.Stack)
+			g.stack, g.function, g.location = si.stack, si.function, si.location
+			if g.testName == "" {
+				g.testName = si.testName
+			}
 		}
 
 		// Goroutine unblocked — clear blocked state on any transition away from GoWaiting.
@@ -212,9 +498,14 @@ func Analyze(path string, opts Options) (*Result, error) {
 		//   GoWaiting → Executing  (goroutine resumed directly)
 		//   GoWaiting → GoRunnable (goroutine woken by close(ch), signal, etc.)
 		if from == trace.GoWaiting && (to.Executing() || to == trace.GoRunnable) {
+			// ev.Goroutine() is the goroutine that performed the unblocking
+			// action (close, send, Unlock, Done, ...).
+			if inWindow && g.isBlocked && to == trace.GoRunnable {
+				wakes.record(gid, g, ev.Goroutine())
+			}
 			// Before clearing, capture long-duration sync blocks.
-			if g.isBlocked && g.reason == "sync" {
-				dur := time.Duration(ev.Time()-g.blockStart) * time.Nanosecond
+			if inWindow && g.isBlocked && g.reason == "sync" {
+				dur := time.Duration(ev.Time()-win.clamp(g.blockStart)) * time.Nanosecond
 				if dur > g.prevLongBlockDuration {
 					g.prevLongBlockReason = g.reason
 					g.prevLongBlockStack = g.stack
@@ -223,7 +514,10 @@ func Analyze(path string, opts Options) (*Result, error) {
 					g.prevLongBlockDuration = dur
 				}
 				// Push to sync history (circular buffer).
-				pos := g.syncHistoryIdx % syncHistorySize
+				if g.syncHistory == nil {
+					g.syncHistory = make([]syncEntry, opts.Thresholds.SyncHistory)
+				}
+				pos := g.syncHistoryIdx % len(g.syncHistory)
 				g.syncHistory[pos] = syncEntry{
 					location: g.location,
 					endTime:  ev.Time(),
@@ -234,11 +528,59 @@ func Analyze(path string, opts Options) (*Result, error) {
 				g.prevSyncEndTime = ev.Time()
 			}
 			g.isBlocked = false
+			g.waitingAtStart = false
 			g.reason = ""
 			g.stack = ""
 			g.function = ""
 			g.location = ""
 		}
+
+		timeline.record(gid, g, &ev, stacks)
+
+		if opts.Streaming && g.goroutineDead && !retainAfterExit(g, opts) {
+			tombstones[gid] = tombstoneOf(g, isRoot)
+			delete(goroutines, gid)
+		}
+	}
+	heap.read()
+	traceEnd := lastTime
+
+	// A goroutine waiting since before a service trace started may be an
+	// idle worker between jobs; the trace does not say how long it has
+	// waited. It counts as blocked only for the wait the start dump prints
+	// for it, which the runtime does from a minute on.
+	if opts.Service {
+		for gid, g := range goroutines {
+			if !g.isBlocked || !g.waitingAtStart {
+				continue
+			}
+			d := startDump[gid]
+			if d == nil || !d.isBlocked || d.wait == 0 || g.location != "" && d.location != g.location {
+				g.isBlocked = false
+				continue
+			}
+			if g.location == "" {
+				g.stack, g.function, g.location = d.stack, d.function, d.location
+			}
+			g.blockStart = firstTime - trace.Time(d.wait)
+		}
+	}
+
+	var window *Window
+	if win.restricted() {
+		start, end, err := win.bounds(lastTime)
+		if err != nil {
+			return nil, err
+		}
+		firstTime, lastTime = start, end
+		window = win.result(start, end)
+		// Goroutines blocked since before the window count as blocked
+		// from its start.
+		for _, g := range goroutines {
+			if g.isBlocked {
+				g.blockStart = win.clamp(g.blockStart)
+			}
+		}
 	}
 
 	traceDuration := time.Duration(lastTime-firstTime) * time.Nanosecond
@@ -246,19 +588,52 @@ func Analyze(path string, opts Options) (*Result, error) {
 	// Walk the goroutine parent-child tree to mark all goroutines descended
 	// from the testing framework as test-owned. Only test-owned goroutines
 	// are eligible for findings.
-	markTestOwned(goroutines)
+	markTestOwned(goroutines, tombstones, isRoot)
+	if opts.IgnorePreexisting {
+		for _, g := range goroutines {
+			if !g.creationSeen {
+				g.isTestOwned = false
+			}
+		}
+	}
 
 	if opts.DebugFiltered {
 		printDebugFiltered(goroutines, lastTime, traceDuration)
 	}
 
-	findings := detectLeaks(goroutines, lastTime, traceDuration, opts)
-	findings = append(findings, detectDeadlocks(goroutines, lastTime, opts)...)
-	findings = append(findings, detectTransientBlocks(goroutines, opts)...)
-	findings = append(findings, detectLockCycles(goroutines, lastTime)...)
-	findings = append(findings, detectChanLockCycle(goroutines, lastTime)...)
-	findings = append(findings, detectOrphans(goroutines, traceDuration)...)
-	findings = append(findings, detectWaitGroupDeadlock(goroutines, lastTime)...)
+	var findings []Finding
+	run := func(name string, detect func() []Finding) {
+		if !opts.Disabled[name] {
+			findings = append(findings, detect()...)
+		}
+	}
+	run(DetectorLeaks, func() []Finding { return detectLeaks(goroutines, lastTime, traceDuration, opts) })
+	run(DetectorDeadlocks, func() []Finding { return detectDeadlocks(goroutines, lastTime, opts) })
+	run(DetectorTransientBlocks, func() []Finding { return detectTransientBlocks(goroutines, opts) })
+	run(DetectorLockCycles, func() []Finding { return detectLockCycles(goroutines, lastTime, opts) })
+	run(DetectorChanLockCycle, func() []Finding { return detectChanLockCycle(goroutines, lastTime, opts) })
+	run(DetectorOrphans, func() []Finding { return detectOrphans(goroutines, traceDuration, opts) })
+	run(DetectorWaitGroupDeadlock, func() []Finding { return detectWaitGroupDeadlock(goroutines, lastTime) })
+	run(DetectorWaitForCycles, func() []Finding { return detectWaitForCycles(goroutines, wakes, lastTime) })
+
+	attributeTests(findings, goroutines)
+	attributeCreation(findings, goroutines)
+	attributeSpawnPaths(findings, goroutines, tombstones)
+	annotateWaitFor(findings, goroutines, tombstones, wakes)
+	lifecycles := timeline.finish(traceEnd, goroutines, tombstones, findings)
+	var lockWait *LockWaitGraph
+	if opts.LockWait {
+		lockWait = lockWaitGraph(goroutines, lastTime, opts)
+	}
+
+	var leakRates []LeakRate
+	if opts.Iterations > 0 {
+		leaks := findings
+		if opts.Unsuppressed != nil {
+			leaks = opts.Unsuppressed(findings)
+		}
+		leakRates = computeLeakRates(goroutines, tombstones, leaks, opts.Iterations)
+	}
 
 	// Deduplicate: collapse N goroutines with the same (kind, location) into
 	// one finding with Count = N. Reduces noise on leaks that affect many
@@ -266,16 +641,93 @@ func Analyze(path string, opts Options) (*Result, error) {
 	findings = deduplicateFindings(findings)
 
 	return &Result{
-		TraceFile:          path,
 		DurationMs:         traceDuration.Milliseconds(),
-		GoroutinesAnalyzed: len(goroutines),
+		GoroutinesAnalyzed: len(goroutines) + len(tombstones) - exitedBefore,
 		Findings:           findings,
+		Tests:              testNames(goroutines, tombstones),
+		LeakRates:          leakRates,
+		PeakHeapBytes:      heap.peak,
+		Window:             window,
+		Timeline:           lifecycles,
+		LockWait:           lockWait,
 	}, nil
 }
 
+// nameGoroutine applies a test-named region or task to gid: on begin the
+// name becomes gid's test name (so goroutines spawned inside inherit it), on
+// end the previous name is restored.
+func nameGoroutine(goroutines map[trace.GoID]*goroutineState, gid trace.GoID, name string, begin bool) {
+	if !isTestName(name) {
+		return
+	}
+	if goroutines[gid] == nil {
+		goroutines[gid] = &goroutineState{}
+	}
+	g := goroutines[gid]
+	if begin {
+		g.outerTestNames = append(g.outerTestNames, g.testName)
+		g.testName = name
+	} else if n := len(g.outerTestNames); n > 0 && g.testName == name {
+		g.testName = g.outerTestNames[n-1]
+		g.outerTestNames = g.outerTestNames[:n-1]
+	}
+}
+
+// attributeTests copies the owning test name of each finding's goroutine
+// onto the finding. Static and race findings (GoroutineID 0) are skipped.
+func attributeTests(findings []Finding, goroutines map[trace.GoID]*goroutineState) {
+	for i := range findings {
+		if findings[i].GoroutineID == 0 {
+			continue
+		}
+		if g := goroutines[findings[i].GoroutineID]; g != nil {
+			findings[i].Test = g.testName
+		}
+	}
+}
+
+// testNames returns the sorted top-level names of the tests that goroutines
+// and tombstones were attributed to by markTestOwned.
+func testNames(goroutines map[trace.GoID]*goroutineState, tombstones map[trace.GoID]lineage) []string {
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if top, _, _ := strings.Cut(name, "/"); top != "" {
+			seen[top] = true
+		}
+	}
+	for _, g := range goroutines {
+		add(g.testName)
+	}
+	for _, t := range tombstones {
+		add(t.testName)
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// attributeCreation sets CreationFunction and CreationLocation on findings
+// that belong to a goroutine.
+func attributeCreation(findings []Finding, goroutines map[trace.GoID]*goroutineState) {
+	for i := range findings {
+		if findings[i].GoroutineID == 0 {
+			continue
+		}
+		if g := goroutines[findings[i].GoroutineID]; g != nil {
+			site := g.spawn.spawnSite()
+			findings[i].CreationFunction, findings[i].CreationLocation = site.Function, site.Location
+		}
+	}
+}
+
 // deduplicateFindings collapses findings with the same (kind, location) into a
-// single representative, setting Count to the total number of affected
-// goroutines. The goroutine blocked the longest is kept as the representative.
+// single representative, setting Count to the number of distinct goroutines
+// affected and Tests to the tests they belong to. A goroutine reported by
+// several detectors (a lock cycle that is also a wait-for cycle) counts
+// once. The goroutine blocked the longest is kept as the representative.
 // Insertion order is preserved so output is deterministic.
 func deduplicateFindings(findings []Finding) []Finding {
 	type key struct {
@@ -283,8 +735,10 @@ func deduplicateFindings(findings []Finding) []Finding {
 		location string
 	}
 	type group struct {
-		rep   Finding
-		count int
+		rep        Finding
+		count      int
+		goroutines map[trace.GoID]bool
+		tests      map[string]bool
 	}
 
 	groups := make(map[key]*group)
@@ -293,15 +747,24 @@ func deduplicateFindings(findings []Finding) []Finding {
 	for _, f := range findings {
 		k := key{f.Kind, f.Location}
 		if g, ok := groups[k]; ok {
-			g.count++
+			if f.GoroutineID == 0 || !g.goroutines[f.GoroutineID] {
+				g.count++
+				g.goroutines[f.GoroutineID] = true
+			}
 			if f.BlockedFor > g.rep.BlockedFor {
 				saved := g.count
 				g.rep = f
 				g.count = saved
 			}
+			if f.Test != "" {
+				g.tests[f.Test] = true
+			}
 		} else {
 			cp := f
-			groups[k] = &group{rep: cp, count: 1}
+			groups[k] = &group{rep: cp, count: 1, goroutines: map[trace.GoID]bool{f.GoroutineID: true}, tests: make(map[string]bool)}
+			if f.Test != "" {
+				groups[k].tests[f.Test] = true
+			}
 			order = append(order, k)
 		}
 	}
@@ -310,19 +773,42 @@ func deduplicateFindings(findings []Finding) []Finding {
 	for _, k := range order {
 		g := groups[k]
 		g.rep.Count = g.count
+		if len(g.tests) > 1 {
+			g.rep.Tests = make([]string, 0, len(g.tests))
+			for t := range g.tests {
+				g.rep.Tests = append(g.rep.Tests, t)
+			}
+			sort.Strings(g.rep.Tests)
+		}
 		out = append(out, g.rep)
 	}
 	return out
 }
 
+// TestNames returns the tests the finding's goroutines belong to: Tests if
+// goroutines of several tests were merged into it, otherwise Test, if set.
+func (f Finding) TestNames() []string {
+	if len(f.Tests) > 0 {
+		return f.Tests
+	}
+	if f.Test != "" {
+		return []string{f.Test}
+	}
+	return nil
+}
+
 // markTestOwned performs a BFS from "test root" goroutines (those created by
 // the testing framework or that pre-existed the trace as the main goroutine)
-// and marks every reachable descendant as isTestOwned = true.
+// and marks every reachable descendant as isTestOwned = true. isRoot selects
+// the roots; see Options.rootFilter.
 //
 // Only test-owned goroutines are reported as findings; this eliminates false
 // positives from pre-test global background workers and goroutines spawned by
 // unrelated infrastructure that happens to be running during the test.
-func markTestOwned(goroutines map[trace.GoID]*goroutineState) {
+//
+// Tombstones of exited goroutines (streaming mode) take part in the walk so
+// that ownership and test names still flow through them.
+func markTestOwned(goroutines map[trace.GoID]*goroutineState, tombstones map[trace.GoID]lineage, isRoot func(*goroutineState) bool) {
 	// Build parent→children adjacency list from captured parentIDs.
 	children := make(map[trace.GoID][]trace.GoID)
 	for gid, g := range goroutines {
@@ -330,12 +816,23 @@ func markTestOwned(goroutines map[trace.GoID]*goroutineState) {
 			children[g.parentID] = append(children[g.parentID], gid)
 		}
 	}
+	for gid, t := range tombstones {
+		if t.parentID != 0 {
+			children[t.parentID] = append(children[t.parentID], gid)
+		}
+	}
 
 	owned := make(map[trace.GoID]bool)
 	var queue []trace.GoID
 
 	for gid, g := range goroutines {
-		if isTestRoot(g) {
+		if isRoot(g) {
+			owned[gid] = true
+			queue = append(queue, gid)
+		}
+	}
+	for gid, t := range tombstones {
+		if t.root {
 			owned[gid] = true
 			queue = append(queue, gid)
 		}
@@ -355,6 +852,39 @@ func markTestOwned(goroutines map[trace.GoID]*goroutineState) {
 	for gid, g := range goroutines {
 		g.isTestOwned = owned[gid]
 	}
+
+	// Goroutines that never showed a tRunner frame themselves inherit the
+	// test name of their nearest named ancestor.
+	for gid, g := range goroutines {
+		if g.testName == "" {
+			g.testName = inheritedTestName(goroutines, tombstones, gid, g.parentID)
+		}
+	}
+	for gid, t := range tombstones {
+		if t.testName == "" {
+			t.testName = inheritedTestName(goroutines, tombstones, gid, t.parentID)
+			tombstones[gid] = t
+		}
+	}
+}
+
+// inheritedTestName returns the test name of gid's nearest named ancestor,
+// starting the walk at its parent.
+func inheritedTestName(goroutines map[trace.GoID]*goroutineState, tombstones map[trace.GoID]lineage, gid, parent trace.GoID) string {
+	for id := parent; id != 0 && id != gid; {
+		l, ok := lineageOf(goroutines, tombstones, id)
+		if !ok {
+			break
+		}
+		if l.testName != "" {
+			return l.testName
+		}
+		if l.parentID == id {
+			break
+		}
+		id = l.parentID
+	}
+	return ""
 }
 
 // isTestRoot returns true if g should be treated as a root of the test goroutine
@@ -374,6 +904,40 @@ func isTestRoot(g *goroutineState) bool {
 		!g.creationSeen
 }
 
+// rootFilter returns the predicate selecting the roots of the goroutine tree
+// whose members are eligible for findings: isTestRoot for test binaries,
+// isServiceRoot in service mode.
+func (o Options) rootFilter() func(*goroutineState) bool {
+	if !o.Service {
+		return isTestRoot
+	}
+	return func(g *goroutineState) bool {
+		return isServiceRoot(g, o.ServiceRoots)
+	}
+}
+
+// isServiceRoot returns true if any function in g's blocking or creation
+// stack matches one of roots, or if roots is empty.
+func isServiceRoot(g *goroutineState, roots []*regexp.Regexp) bool {
+	if len(roots) == 0 {
+		return true
+	}
+	for _, stack := range []string{g.stack, g.creationStack} {
+		for _, line := range splitLines(stack) {
+			fn := strings.Fields(line)
+			if len(fn) == 0 {
+				continue
+			}
+			for _, re := range roots {
+				if re.MatchString(fn[0]) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 // printDebugFiltered prints all blocked goroutines with their filter status.
 // Useful for diagnosing why specific goroutines are not being reported.
 func printDebugFiltered(goroutines map[trace.GoID]*goroutineState, lastTime trace.Time, traceDuration time.Duration) {
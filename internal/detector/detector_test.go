@@ -0,0 +1,36 @@
+package detector
+
+import (
+	"testing"
+
+	"golang.org/x/exp/trace"
+)
+
+func TestNameGoroutineNestedRegions(t *testing.T) {
+	goroutines := map[trace.GoID]*goroutineState{1: {testName: "TestOuter"}}
+	g := goroutines[1]
+	steps := []struct {
+		name  string
+		begin bool
+		want  string
+	}{
+		{"TestOuter/a", true, "TestOuter/a"},
+		{"setup", true, "TestOuter/a"}, // not a test name: ignored
+		{"TestOuter/a/b", true, "TestOuter/a/b"},
+		{"TestOuter/a/b", true, "TestOuter/a/b"}, // same name nested
+		{"TestOuter/a/b", false, "TestOuter/a/b"},
+		{"TestOuter/a/b", false, "TestOuter/a"},
+		{"setup", false, "TestOuter/a"},
+		{"TestOuter/a", false, "TestOuter"},
+		{"TestOuter/a", false, "TestOuter"}, // unmatched end
+	}
+	for i, s := range steps {
+		nameGoroutine(goroutines, 1, s.name, s.begin)
+		if g.testName != s.want {
+			t.Fatalf("step %d (%q, begin=%v): testName = %q, want %q", i, s.name, s.begin, g.testName, s.want)
+		}
+	}
+	if len(g.outerTestNames) != 0 {
+		t.Errorf("outer names left over: %q", g.outerTestNames)
+	}
+}
@@ -0,0 +1,322 @@
+package detector
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/trace"
+)
+
+// defaultDumpWindow is the assumed observation window of a goroutine dump
+// that does not say how long the program ran (e.g. a SIGQUIT dump). The
+// runtime only annotates waits of a minute or more, so shorter waits are
+// indistinguishable anyway.
+const defaultDumpWindow = time.Minute
+
+var (
+	// goroutine 18 [chan receive, 10 minutes]:
+	// goroutine 18 gp=0xc000102a80 m=nil [semacquire]:
+	dumpHeaderRE = regexp.MustCompile(`^goroutine (\d+)(?: [^\[]*)? \[([^\]]*)\]:$`)
+	// created by pkg.Func in goroutine 7
+	dumpCreatedRE = regexp.MustCompile(`^created by (\S+)(?: in goroutine (\d+))?$`)
+	// panic: test timed out after 10m0s
+	dumpTimeoutRE = regexp.MustCompile(`panic: test timed out after (\S+)`)
+	// , 10 minutes
+	dumpWaitRE = regexp.MustCompile(`^(\d+) minutes?$`)
+)
+
+// IsGoroutineDump reports whether output contains a goroutine dump, such as
+// the one printed when `go test -timeout` fires.
+func IsGoroutineDump(output string) bool {
+	for _, line := range strings.Split(output, "\n") {
+		if dumpHeaderRE.MatchString(strings.TrimSpace(line)) {
+			return true
+		}
+	}
+	return false
+}
+
+// AnalyzeGoroutineDump reads a goroutine dump from path and returns its
+// findings as a Result, like Analyze does for a trace file.
+func AnalyzeGoroutineDump(path string, opts Options) (*Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	result, err := GoroutineDumpResult(string(data), opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	result.TraceFile = path
+	return result, nil
+}
+
+// GoroutineDumpResult is AnalyzeGoroutineDump for dump text that is already
+// in memory, such as the captured output of a timed-out go test run. The
+// dump is the text printed after "panic: test timed out", on SIGQUIT, or by
+// runtime.Stack(buf, true), with one section per goroutine:
+//
+//	goroutine 11 [chan send, 2 minutes]:
+//	pkg.worker(...)
+//		/path/file.go:14 +0x1b
+//	created by pkg.TestFoo in goroutine 10
+//		/path/file.go:12 +0x74
+//
+// A dump is a single snapshot, so only detectors that judge goroutines by
+// their state at the end apply: leaks, mutex contention groups and
+// WaitGroup deadlocks. Wait durations are only printed from one minute
+// upwards; shorter waits are assumed to have lasted the whole dump window
+// (the test timeout, when the dump comes from one).
+func GoroutineDumpResult(output string, opts Options) (*Result, error) {
+	goroutines, window := parseDump(output, defaultDumpWindow)
+	if len(goroutines) == 0 {
+		return nil, fmt.Errorf("no goroutine dump found")
+	}
+	findings := analyzeDump(goroutines, window, opts)
+	return &Result{
+		DurationMs:         window.Milliseconds(),
+		GoroutinesAnalyzed: len(goroutines),
+		Findings:           findings,
+		Tests:              testNames(goroutines, nil),
+	}, nil
+}
+
+// SnapshotsResult analyzes a series of goroutine dumps of the same process
+// taken over elapsed (e.g. from /debug/pprof/goroutine?debug=2). Findings come
+// from the last dump; goroutines that were not blocked at the same location
+// in every earlier dump are treated as transient and not reported. Neither
+// are goroutines whose wait is too short to be printed (under a minute): they
+// may have been woken in between, like idle workers picking up jobs.
+func SnapshotsResult(dumps []string, elapsed time.Duration, opts Options) (*Result, error) {
+	if len(dumps) == 0 {
+		return nil, fmt.Errorf("no goroutine dumps")
+	}
+	if elapsed <= 0 {
+		elapsed = defaultDumpWindow
+	}
+	last, window := parseDump(dumps[len(dumps)-1], elapsed)
+	if len(last) == 0 {
+		return nil, fmt.Errorf("no goroutine dump found")
+	}
+	for _, d := range dumps[:len(dumps)-1] {
+		earlier, _ := parseDump(d, elapsed)
+		for gid, g := range last {
+			if e := earlier[gid]; g.isBlocked && (e == nil || !e.isBlocked || e.location != g.location) {
+				g.isBlocked = false
+			}
+		}
+	}
+	for _, g := range last {
+		if g.wait == 0 {
+			g.isBlocked = false
+		}
+	}
+	return &Result{
+		DurationMs:         window.Milliseconds(),
+		GoroutinesAnalyzed: len(last),
+		Findings:           analyzeDump(last, window, opts),
+	}, nil
+}
+
+// analyzeDump runs the snapshot detectors over parsed dump state. Times are
+// synthetic: the dump is taken at window, and every goroutine's blockStart
+// lies that long before it.
+func analyzeDump(goroutines map[trace.GoID]*goroutineState, window time.Duration, opts Options) []Finding {
+	lastTime := trace.Time(window)
+	opts.Thresholds = opts.Thresholds.WithDefaults()
+	markTestOwned(goroutines, nil, opts.rootFilter())
+
+	var findings []Finding
+	if !opts.Disabled[DetectorLeaks] {
+		findings = append(findings, detectLeaks(goroutines, lastTime, window, opts)...)
+	}
+	if !opts.Disabled[DetectorDeadlocks] {
+		findings = append(findings, detectDeadlocks(goroutines, lastTime, opts)...)
+	}
+	if !opts.Disabled[DetectorWaitGroupDeadlock] {
+		findings = append(findings, detectWaitGroupDeadlock(goroutines, lastTime)...)
+	}
+
+	attributeTests(findings, goroutines)
+	attributeCreation(findings, goroutines)
+	attributeSpawnPaths(findings, goroutines, nil)
+	return deduplicateFindings(findings)
+}
+
+// dumpGoroutine is one goroutine section of a dump before conversion.
+type dumpGoroutine struct {
+	id       trace.GoID
+	state    string
+	wait     time.Duration // 0 if not printed
+	funcs    []string
+	stack    strings.Builder
+	function string
+	location string
+
+	parentID         trace.GoID
+	creationStack    string
+	creationFunction string
+	creationLocation string
+}
+
+// parseDump converts every goroutine section in output to goroutineState and
+// returns the dump's observation window: the test timeout if the dump comes
+// from one, else the longest printed wait, else defaultWindow.
+func parseDump(output string, defaultWindow time.Duration) (map[trace.GoID]*goroutineState, time.Duration) {
+	window := time.Duration(0)
+	if m := dumpTimeoutRE.FindStringSubmatch(output); m != nil {
+		window, _ = time.ParseDuration(m[1])
+	}
+
+	var parsed []*dumpGoroutine
+	var cur *dumpGoroutine
+	var pendingFunc string // function line awaiting its file:line line
+	inCreatedBy := false
+
+	for _, raw := range strings.Split(output, "\n") {
+		line := strings.TrimSpace(raw)
+
+		if m := dumpHeaderRE.FindStringSubmatch(line); m != nil {
+			id, _ := strconv.ParseUint(m[1], 10, 64)
+			cur = &dumpGoroutine{id: trace.GoID(id)}
+			cur.state, cur.wait = parseDumpState(m[2])
+			parsed = append(parsed, cur)
+			pendingFunc, inCreatedBy = "", false
+			continue
+		}
+		if cur == nil {
+			continue
+		}
+		if line == "" {
+			cur = nil
+			continue
+		}
+
+		if m := dumpCreatedRE.FindStringSubmatch(line); m != nil {
+			pendingFunc, inCreatedBy = m[1], true
+			if m[2] != "" {
+				pid, _ := strconv.ParseUint(m[2], 10, 64)
+				cur.parentID = trace.GoID(pid)
+			}
+			continue
+		}
+
+		// "\t/path/file.go:42 +0x1b" completes the preceding function line.
+		if strings.HasPrefix(raw, "\t") && pendingFunc != "" {
+			file, lineNo := splitDumpLocation(line)
+			frame := fmt.Sprintf("      %s (%s:%s)\n", pendingFunc, file, lineNo)
+			loc := file + ":" + lineNo
+			if inCreatedBy {
+				cur.creationStack = frame
+				if !isRuntimeFrame(pendingFunc, file) {
+					cur.creationFunction, cur.creationLocation = pendingFunc, loc
+				}
+			} else {
+				cur.stack.WriteString(frame)
+				cur.funcs = append(cur.funcs, pendingFunc)
+				// The trace omits the internal/sync frames below sync's
+				// exported API; skip them too so locations match.
+				if cur.location == "" && !isRuntimeFrame(pendingFunc, file) &&
+					!strings.HasPrefix(pendingFunc, "internal/sync.") {
+					cur.function, cur.location = pendingFunc, loc
+				}
+			}
+			pendingFunc = ""
+			continue
+		}
+
+		// "pkg.(*T).Method(0xc000010000, ...)" or "pkg.Func(...)".
+		if i := strings.LastIndex(line, "("); i > 0 && strings.HasSuffix(line, ")") {
+			pendingFunc = line[:i]
+		}
+	}
+
+	for _, d := range parsed {
+		if d.wait > window {
+			window = d.wait
+		}
+	}
+	if window == 0 {
+		window = defaultWindow
+	}
+
+	goroutines := make(map[trace.GoID]*goroutineState, len(parsed))
+	for _, d := range parsed {
+		g := &goroutineState{
+			stack:            d.stack.String(),
+			function:         d.function,
+			location:         d.location,
+			wait:             d.wait,
+			parentID:         d.parentID,
+			creationSeen:     d.creationStack != "",
+			creationStack:    d.creationStack,
+			creationFunction: d.creationFunction,
+			creationLocation: d.creationLocation,
+			// "created by" names the 'go' statement itself.
+			spawn:    &stackInfo{function: d.creationFunction, location: d.creationLocation},
+			testName: testNameFromFuncs(d.funcs),
+		}
+		if reason, blocked := dumpReason(d.state); blocked {
+			g.isBlocked = true
+			g.reason = reason
+			wait := d.wait
+			if wait == 0 {
+				wait = window
+			}
+			g.blockStart = trace.Time(window - wait)
+		}
+		goroutines[d.id] = g
+	}
+	return goroutines, window
+}
+
+// parseDumpState splits a header state such as "chan receive, 10 minutes,
+// locked to thread" into the state and the printed wait duration.
+func parseDumpState(s string) (state string, wait time.Duration) {
+	parts := strings.Split(s, ",")
+	state = strings.TrimSpace(parts[0])
+	for _, p := range parts[1:] {
+		if m := dumpWaitRE.FindStringSubmatch(strings.TrimSpace(p)); m != nil {
+			n, _ := strconv.Atoi(m[1])
+			wait = time.Duration(n) * time.Minute
+		}
+	}
+	return state, wait
+}
+
+// dumpReason maps a dump wait state to the block reason the execution trace
+// uses for the same wait, and reports whether the goroutine is blocked.
+func dumpReason(state string) (reason string, blocked bool) {
+	switch {
+	case state == "running", state == "runnable", state == "syscall",
+		strings.HasPrefix(state, "running"), strings.HasPrefix(state, "syscall"):
+		return "", false
+	case strings.HasPrefix(state, "chan receive"):
+		return "chan receive", true
+	case strings.HasPrefix(state, "chan send"):
+		return "chan send", true
+	case strings.HasPrefix(state, "select"):
+		return "select", true
+	case state == "sync.Cond.Wait":
+		return "sync.(*Cond).Wait", true
+	case strings.HasPrefix(state, "sync."), strings.HasPrefix(state, "semacquire"):
+		return "sync", true
+	default:
+		return state, true
+	}
+}
+
+// splitDumpLocation splits "/path/file.go:42 +0x1b" into file and line.
+func splitDumpLocation(s string) (file, line string) {
+	if i := strings.Index(s, " +0x"); i >= 0 {
+		s = s[:i]
+	}
+	if i := strings.LastIndex(s, ":"); i >= 0 {
+		return s[:i], s[i+1:]
+	}
+	return s, "0"
+}
@@ -0,0 +1,173 @@
+package detector
+
+import (
+	"testing"
+	"time"
+
+	"golang.org/x/exp/trace"
+)
+
+// timeoutDump is the output of a go test run killed by -timeout 2m, as
+// printed by Go 1.25 (trimmed to four goroutines).
+const timeoutDump = `=== RUN   TestLeak
+panic: test timed out after 2m0s
+	running tests:
+		TestLeak (2m0s)
+
+goroutine 21 gp=0xc000102fc0 m=5 mp=0xc000100008 [running]:
+panic({0x6cdc60?, 0xc000028480?})
+	/usr/local/go/src/runtime/panic.go:806 +0x168
+testing.(*M).startAlarm.func1()
+	/usr/local/go/src/testing/testing.go:2484 +0x394
+created by time.goFunc
+	/usr/local/go/src/time/sleep.go:215 +0x2d
+
+goroutine 1 gp=0xc000002380 m=nil [chan receive, 2 minutes]:
+runtime.gopark(0xc0000a5970?, 0x4c4d9c?, 0x80?, 0x98?, 0x7fd0c8?)
+	/usr/local/go/src/runtime/proc.go:435 +0xce fp=0xc0000a5950 sp=0xc0000a5930 pc=0x46f66e
+runtime.chanrecv(0xc0000b0070, 0xc0000a5a4f, 0x1)
+	/usr/local/go/src/runtime/chan.go:664 +0x445 fp=0xc0000a59c8 sp=0xc0000a5950 pc=0x40a9c5
+testing.(*T).Run(0xc000003dc0, {0x6ea1b5?, 0x0?}, 0x700000?)
+	/usr/local/go/src/testing/testing.go:1859 +0x431
+testing.runTests(0xc000012108, {0x8b7c00, 0x1, 0x1}, {0x0?, 0x0?, 0x0?})
+	/usr/local/go/src/testing/testing.go:2279 +0x4b4
+main.main()
+	_testmain.go:45 +0x9b
+
+goroutine 7 gp=0xc000003180 m=nil [chan receive, 2 minutes]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:435 +0xce
+runtime.chanrecv1(0xc0000b0150?, 0x0?)
+	/usr/local/go/src/runtime/chan.go:506 +0x12
+example.com/app.TestLeak(0xc000003dc0)
+	/app/leak_test.go:20 +0x8a
+testing.tRunner(0xc000003dc0, 0x70e2d8)
+	/usr/local/go/src/testing/testing.go:1792 +0xf4
+created by testing.(*T).Run in goroutine 1
+	/usr/local/go/src/testing/testing.go:1851 +0x413
+
+goroutine 8 gp=0xc000003340 m=nil [chan send, 1 minutes]:
+runtime.gopark(0x0?, 0x0?, 0x0?, 0x0?, 0x0?)
+	/usr/local/go/src/runtime/proc.go:435 +0xce
+runtime.chansend1(0xc0000b0150?, 0x0?)
+	/usr/local/go/src/runtime/chan.go:161 +0x18
+example.com/app.worker(...)
+	/app/leak.go:14
+created by example.com/app.TestLeak in goroutine 7
+	/app/leak_test.go:18 +0x74
+FAIL	example.com/app	120.011s
+`
+
+func TestParseDumpTestTimeout(t *testing.T) {
+	goroutines, window := parseDump(timeoutDump, defaultDumpWindow)
+	if window != 2*time.Minute {
+		t.Errorf("window = %v, want the 2m0s timeout", window)
+	}
+	if len(goroutines) != 4 {
+		t.Fatalf("parsed %d goroutines, want 4", len(goroutines))
+	}
+
+	if g := goroutines[21]; g.isBlocked {
+		t.Errorf("running goroutine 21 parsed as blocked on %q", g.reason)
+	}
+
+	test := goroutines[7]
+	if !test.isBlocked || test.reason != "chan receive" || test.blockStart != 0 {
+		t.Errorf("goroutine 7: blocked %v on %q from %d, want chan receive from 0", test.isBlocked, test.reason, test.blockStart)
+	}
+	if test.function != "example.com/app.TestLeak" || test.location != "/app/leak_test.go:20" {
+		t.Errorf("goroutine 7 at %s (%s), want the first non-runtime frame", test.function, test.location)
+	}
+	if test.testName != "TestLeak" || test.parentID != 1 {
+		t.Errorf("goroutine 7: test %q, parent %d; want TestLeak, 1", test.testName, test.parentID)
+	}
+
+	worker := goroutines[8]
+	if !worker.isBlocked || worker.reason != "chan send" || worker.wait != time.Minute {
+		t.Errorf("goroutine 8: blocked %v on %q for %v, want chan send for 1m", worker.isBlocked, worker.reason, worker.wait)
+	}
+	if worker.blockStart != trace.Time(time.Minute) {
+		t.Errorf("goroutine 8 blockStart = %d, want a minute before the end", worker.blockStart)
+	}
+	if worker.function != "example.com/app.worker" || worker.location != "/app/leak.go:14" {
+		t.Errorf("goroutine 8 at %s (%s)", worker.function, worker.location)
+	}
+	if worker.parentID != 7 || worker.creationFunction != "example.com/app.TestLeak" || worker.creationLocation != "/app/leak_test.go:18" {
+		t.Errorf("goroutine 8 created by %s at %s in goroutine %d, want TestLeak at leak_test.go:18 in 7",
+			worker.creationFunction, worker.creationLocation, worker.parentID)
+	}
+}
+
+func TestGoroutineDumpResultTestTimeout(t *testing.T) {
+	result, err := GoroutineDumpResult(timeoutDump, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.DurationMs != 120000 || result.GoroutinesAnalyzed != 4 {
+		t.Errorf("DurationMs %d, GoroutinesAnalyzed %d; want 120000, 4", result.DurationMs, result.GoroutinesAnalyzed)
+	}
+	var leak bool
+	for _, f := range result.Findings {
+		if f.Kind == KindGoroutineLeak && f.Location == "/app/leak.go:14" {
+			leak = true
+			if f.Test != "TestLeak" {
+				t.Errorf("leak attributed to test %q, want TestLeak", f.Test)
+			}
+		}
+	}
+	if !leak {
+		t.Errorf("no leak reported for worker; findings: %+v", result.Findings)
+	}
+
+	if _, err := GoroutineDumpResult("--- FAIL: TestX\nFAIL\n", Options{}); err == nil {
+		t.Error("no error for output without a goroutine dump")
+	}
+}
+
+func TestParseDumpState(t *testing.T) {
+	for _, tc := range []struct {
+		in    string
+		state string
+		wait  time.Duration
+	}{
+		{"running", "running", 0},
+		{"chan receive", "chan receive", 0},
+		{"chan receive, 10 minutes", "chan receive", 10 * time.Minute},
+		{"chan send, 1 minutes", "chan send", time.Minute},
+		{"semacquire, 3 minutes, locked to thread", "semacquire", 3 * time.Minute},
+		{"syscall, locked to thread", "syscall", 0},
+		{"select (no cases)", "select (no cases)", 0},
+	} {
+		state, wait := parseDumpState(tc.in)
+		if state != tc.state || wait != tc.wait {
+			t.Errorf("parseDumpState(%q) = %q, %v; want %q, %v", tc.in, state, wait, tc.state, tc.wait)
+		}
+	}
+}
+
+func TestDumpReason(t *testing.T) {
+	for _, tc := range []struct {
+		state   string
+		reason  string
+		blocked bool
+	}{
+		{"running", "", false},
+		{"runnable", "", false},
+		{"syscall", "", false},
+		{"chan receive", "chan receive", true},
+		{"chan receive (nil chan)", "chan receive", true},
+		{"chan send", "chan send", true},
+		{"select", "select", true},
+		{"select (no cases)", "select", true},
+		{"sync.Cond.Wait", "sync.(*Cond).Wait", true},
+		{"sync.Mutex.Lock", "sync", true},
+		{"sync.WaitGroup.Wait", "sync", true},
+		{"semacquire", "sync", true},
+		{"IO wait", "IO wait", true},
+	} {
+		reason, blocked := dumpReason(tc.state)
+		if reason != tc.reason || blocked != tc.blocked {
+			t.Errorf("dumpReason(%q) = %q, %v; want %q, %v", tc.state, reason, blocked, tc.reason, tc.blocked)
+		}
+	}
+}
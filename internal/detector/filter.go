@@ -4,6 +4,8 @@ import (
 	"bytes"
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/exp/trace"
 )
@@ -74,3 +76,66 @@ func isRuntimeFrame(funcName, line string) bool {
 		strings.HasPrefix(funcName, "testing.") ||
 		strings.Contains(line, "_testmain.go")
 }
+
+// testNameFromStack returns the name of the test function called by
+// testing.tRunner (or the benchmark called by testing.(*B).runN) in s, or ""
+// if s does not run under the testing framework. Closures inside a test
+// (subtests, TestFoo.func1) are attributed to their top-level test.
+func testNameFromStack(s trace.Stack) string {
+	var funcs []string
+	for f := range s.Frames() {
+		funcs = append(funcs, f.Func)
+	}
+	return testNameFromFuncs(funcs)
+}
+
+// testNameFromFuncs is testNameFromStack for a list of function names,
+// innermost first.
+func testNameFromFuncs(funcs []string) string {
+	var callee string
+	for _, fn := range funcs {
+		if fn == "testing.tRunner" || fn == "testing.(*B).runN" {
+			if callee == "" || strings.HasPrefix(callee, "testing.") {
+				return ""
+			}
+			return shortTestName(callee)
+		}
+		callee = fn
+	}
+	return ""
+}
+
+// shortTestName reduces a qualified function name such as
+// "github.com/org/pkg.TestFoo.func1" to its top-level test name "TestFoo".
+func shortTestName(fn string) string {
+	if slash := strings.LastIndex(fn, "/"); slash >= 0 {
+		fn = fn[slash+1:]
+	}
+	if dot := strings.Index(fn, "."); dot >= 0 {
+		fn = fn[dot+1:]
+	}
+	if dot := strings.Index(fn, "."); dot >= 0 {
+		fn = fn[:dot]
+	}
+	return fn
+}
+
+// isTestName reports whether name is a Go test, benchmark, fuzz target or
+// example name, optionally followed by "/subtest". As for go test, the
+// prefix must be the whole name or be followed by a character that is not
+// a lower-case letter: "TestFoo" and "Test_foo" are tests, "Testing" is not.
+func isTestName(name string) bool {
+	top, _, _ := strings.Cut(name, "/")
+	for _, prefix := range []string{"Test", "Benchmark", "Fuzz", "Example"} {
+		rest, ok := strings.CutPrefix(top, prefix)
+		if !ok {
+			continue
+		}
+		if rest == "" {
+			return true
+		}
+		r, _ := utf8.DecodeRuneInString(rest)
+		return !unicode.IsLower(r)
+	}
+	return false
+}
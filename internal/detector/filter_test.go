@@ -0,0 +1,29 @@
+package detector
+
+import "testing"
+
+func TestIsTestName(t *testing.T) {
+	for name, want := range map[string]bool{
+		"TestFoo":           true,
+		"Test_foo":          true,
+		"Test":              true,
+		"TestFoo/sub_case":  true,
+		"BenchmarkParse":    true,
+		"FuzzDecode":        true,
+		"Example":           true,
+		"ExampleClient_Get": true,
+		"Testing":           false,
+		"Tests/foo":         false,
+		"Examples":          false,
+		"Benchmarks":        false,
+		"TestifySuite":      false,
+		"handler":           false,
+		"":                  false,
+		"Testé":             false,
+		"ExampleÉcole":      true,
+	} {
+		if got := isTestName(name); got != want {
+			t.Errorf("isTestName(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
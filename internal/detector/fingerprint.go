@@ -0,0 +1,48 @@
+package detector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// Fingerprint identifies f independently of line numbers, so that it stays
+// the same when unrelated edits shift code up or down a file. It hashes the
+// kind, the top user-code function, the function names of the stack's
+// user-code frames, and the function containing the 'go' statement that
+// started the goroutine. Goroutine IDs, durations, file paths and line
+// numbers are left out.
+//
+// Findings without a stack, such as static analysis findings, would then
+// only differ by kind and function, so their message and location are
+// hashed instead: two unreleased locks in one function stay distinct, at
+// the cost of a new fingerprint when their line moves.
+func (f Finding) Fingerprint() string {
+	h := sha256.New()
+	h.Write([]byte(string(f.Kind) + "\n" + f.Function + "\n"))
+	for _, fn := range stackFunctions(f.Stack) {
+		h.Write([]byte(fn + "\n"))
+	}
+	if f.Stack == "" {
+		h.Write([]byte(f.BlockedOn + "\n" + f.Location + "\n"))
+	}
+	h.Write([]byte("created by " + f.CreationFunction))
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// stackFunctions returns the function names of the user-code frames of a
+// formatted stack ("      pkg.Func (/path/file.go:42)" per line). Runtime,
+// testing and internal/sync frames are skipped: they differ between Go
+// versions and between traces and goroutine dumps of the same code.
+func stackFunctions(stack string) []string {
+	var funcs []string
+	for _, line := range strings.Split(stack, "\n") {
+		line = strings.TrimSpace(line)
+		fn, _, _ := strings.Cut(line, " ")
+		if fn == "" || isRuntimeFrame(fn, line) || strings.HasPrefix(fn, "internal/sync.") {
+			continue
+		}
+		funcs = append(funcs, fn)
+	}
+	return funcs
+}
@@ -0,0 +1,28 @@
+package detector
+
+import "testing"
+
+func TestFingerprintIgnoresLines(t *testing.T) {
+	stack := func(line string) string {
+		return "      example.com/app.worker (/app/worker.go:" + line + ")\n"
+	}
+	a := Finding{Kind: KindGoroutineLeak, Function: "example.com/app.worker", Location: "/app/worker.go:12", Stack: stack("12"), GoroutineID: 7}
+	b := Finding{Kind: KindGoroutineLeak, Function: "example.com/app.worker", Location: "/app/worker.go:15", Stack: stack("15"), GoroutineID: 9}
+	if a.Fingerprint() != b.Fingerprint() {
+		t.Error("fingerprint changed with the line number")
+	}
+}
+
+func TestFingerprintStaticFindings(t *testing.T) {
+	const msg = "mutex Lock() acquired but not released on all exit paths"
+	a := Finding{Kind: KindLockLeak, Function: "example.com/app.(*Cache).Get", Location: "/app/cache.go:20", BlockedOn: msg}
+	b := Finding{Kind: KindLockLeak, Function: "example.com/app.(*Cache).Get", Location: "/app/cache.go:31", BlockedOn: msg}
+	if a.Fingerprint() == b.Fingerprint() {
+		t.Error("two lock leaks in one function share a fingerprint")
+	}
+	c := Finding{Kind: KindLockOrder, Function: a.Function, Location: a.Location, BlockedOn: "lock ordering cycle (AB-BA): a → b → a"}
+	d := Finding{Kind: KindLockOrder, Function: a.Function, Location: a.Location, BlockedOn: "lock ordering cycle (AB-BA): a → c → a"}
+	if c.Fingerprint() == d.Fingerprint() {
+		t.Error("two lock-order cycles at one location share a fingerprint")
+	}
+}
@@ -0,0 +1,189 @@
+package detector
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/trace"
+)
+
+// Default growth thresholds, used when Options.MinGrowth or
+// Options.Steadiness is zero.
+const (
+	defaultMinGrowth  = 5
+	defaultSteadiness = 0.8
+)
+
+// GrowthTracker detects goroutine leaks in a long-running process from a
+// series of goroutine dumps, in the style of LeakProf: blocked goroutines are
+// fingerprinted by creation site and blocking stack, and a fingerprint whose
+// count grows steadily from sample to sample is reported as a leak. Unlike
+// the trace detectors it needs no single goroutine to be blocked for long,
+// only more and more of them piling up at the same place.
+type GrowthTracker struct {
+	opts  Options
+	times []time.Time
+	sites map[string]*growthSite
+	last  int // goroutines in the most recent sample
+}
+
+// growthSite is one fingerprint's count in every sample so far, and its
+// oldest goroutine in the most recent sample it appeared in.
+type growthSite struct {
+	counts []int
+	gid    trace.GoID
+	g      *goroutineState
+}
+
+// NewGrowthTracker returns a tracker that applies opts' service-root filter
+// and growth thresholds. It reports nothing if the leaks detector is
+// disabled.
+func NewGrowthTracker(opts Options) *GrowthTracker {
+	opts.Service = true
+	return &GrowthTracker{opts: opts, sites: make(map[string]*growthSite)}
+}
+
+// Samples returns the number of dumps added so far.
+func (t *GrowthTracker) Samples() int {
+	return len(t.times)
+}
+
+// Add records a goroutine dump taken at the given time and returns the
+// number of goroutines in it.
+func (t *GrowthTracker) Add(dump string, at time.Time) (int, error) {
+	goroutines, _ := parseDump(dump, defaultDumpWindow)
+	if len(goroutines) == 0 {
+		return 0, fmt.Errorf("no goroutine dump found")
+	}
+	markTestOwned(goroutines, nil, t.opts.rootFilter())
+
+	counts := make(map[string]int)
+	for gid, g := range goroutines {
+		if !g.isBlocked || !g.isTestOwned || isRuntimeGoroutine(g.stack) {
+			continue
+		}
+		fp := g.creationLocation + "\n" + g.stack
+		counts[fp]++
+		s := t.sites[fp]
+		if s == nil {
+			s = &growthSite{counts: make([]int, len(t.times))}
+			t.sites[fp] = s
+		}
+		if counts[fp] == 1 || gid < s.gid {
+			s.gid, s.g = gid, g
+		}
+	}
+	for fp, s := range t.sites {
+		s.counts = append(s.counts, counts[fp])
+	}
+
+	t.times = append(t.times, at)
+	t.last = len(goroutines)
+	return len(goroutines), nil
+}
+
+// Result reports the fingerprints whose count grew by at least
+// Options.MinGrowth between the first and the last sample and did not fall
+// in at least Options.Steadiness of the intervals between samples. Count is
+// the number of goroutines in the last sample; BlockedFor is the time since
+// the fingerprint was first seen.
+func (t *GrowthTracker) Result() *Result {
+	result := &Result{GoroutinesAnalyzed: t.last}
+	if len(t.times) < 2 || t.opts.Disabled[DetectorLeaks] {
+		return result
+	}
+	elapsed := t.times[len(t.times)-1].Sub(t.times[0])
+	result.DurationMs = elapsed.Milliseconds()
+
+	minGrowth := t.opts.MinGrowth
+	if minGrowth <= 0 {
+		minGrowth = defaultMinGrowth
+	}
+	steadiness := t.opts.Steadiness
+	if steadiness <= 0 {
+		steadiness = defaultSteadiness
+	}
+
+	type grown struct {
+		f      Finding
+		growth int
+	}
+	var out []grown
+	for _, s := range t.sites {
+		c := s.counts
+		growth := c[len(c)-1] - c[0]
+		if growth < minGrowth {
+			continue
+		}
+		held, rose := 0, 0
+		for i := 1; i < len(c); i++ {
+			if c[i] >= c[i-1] {
+				held++
+			}
+			if c[i] > c[i-1] {
+				rose++
+			}
+		}
+		intervals := len(c) - 1
+		if float64(held)/float64(intervals) < steadiness {
+			continue
+		}
+		conf := ConfidenceMedium
+		if rose == intervals {
+			conf = ConfidenceHigh
+		}
+
+		firstSeen := 0
+		for firstSeen < len(c) && c[firstSeen] == 0 {
+			firstSeen++
+		}
+
+		f := Finding{
+			Kind:        KindGoroutineLeak,
+			Confidence:  conf,
+			GoroutineID: s.gid,
+			BlockedOn:   s.g.reason,
+			BlockedFor:  t.times[len(t.times)-1].Sub(t.times[firstSeen]),
+			Stack:       s.g.stack,
+			Function:    s.g.function,
+			Location:    s.g.location,
+			Count:       c[len(c)-1],
+			Evidence: []string{fmt.Sprintf("goroutine count grew %s over %d samples (%s)",
+				formatCounts(c), len(c), elapsed.Round(time.Second))},
+			CreationFunction: s.g.creationFunction,
+			CreationLocation: s.g.creationLocation,
+		}
+		out = append(out, grown{f, growth})
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].growth != out[j].growth {
+			return out[i].growth > out[j].growth
+		}
+		return out[i].f.Location < out[j].f.Location
+	})
+	for _, g := range out {
+		result.Findings = append(result.Findings, g.f)
+	}
+	return result
+}
+
+// formatCounts renders a count series as "3 → 12 → 25", eliding the middle
+// of long series.
+func formatCounts(c []int) string {
+	const edge = 3
+	var parts []string
+	for i, n := range c {
+		if len(c) > 2*edge+1 && i == edge {
+			parts = append(parts, "…")
+		}
+		if len(c) > 2*edge+1 && i >= edge && i < len(c)-edge {
+			continue
+		}
+		parts = append(parts, strconv.Itoa(n))
+	}
+	return strings.Join(parts, " → ")
+}
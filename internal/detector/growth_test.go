@@ -0,0 +1,66 @@
+package detector
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+)
+
+// growthDump returns a goroutine dump with n handler goroutines blocked on a
+// channel receive, each started by the same go statement.
+func growthDump(n int) string {
+	var b strings.Builder
+	b.WriteString("goroutine 1 [running]:\nmain.main()\n\t/app/main.go:10 +0x1d\n\n")
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(&b, `goroutine %d [chan receive]:
+example.com/app.(*Server).wait(...)
+	/app/server.go:42 +0x2a
+created by example.com/app.(*Server).handle in goroutine 1
+	/app/server.go:30 +0x5c
+
+`, 100+i)
+	}
+	return b.String()
+}
+
+func TestGrowthTrackerResult(t *testing.T) {
+	tracker := NewGrowthTracker(Options{})
+	start := time.Unix(0, 0)
+	for i, n := range []int{2, 5, 9, 14} {
+		if _, err := tracker.Add(growthDump(n), start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	result := tracker.Result()
+	if len(result.Findings) != 1 {
+		t.Fatalf("got %d findings, want 1: %+v", len(result.Findings), result.Findings)
+	}
+	f := result.Findings[0]
+	if f.Kind != KindGoroutineLeak || f.Confidence != ConfidenceHigh || f.Count != 14 {
+		t.Errorf("got %s/%s Count %d, want goroutine_leak/high Count 14", f.Kind, f.Confidence, f.Count)
+	}
+	if f.Location != "/app/server.go:42" {
+		t.Errorf("Location = %q", f.Location)
+	}
+	if f.CreationFunction != "example.com/app.(*Server).handle" || f.CreationLocation != "/app/server.go:30" {
+		t.Errorf("creation = %q at %q, want the go statement", f.CreationFunction, f.CreationLocation)
+	}
+	if len(f.Evidence) != 1 || !strings.Contains(f.Evidence[0], "2 → 5 → 9 → 14") {
+		t.Errorf("Evidence = %q, want the count series", f.Evidence)
+	}
+}
+
+func TestGrowthTrackerLeaksDisabled(t *testing.T) {
+	tracker := NewGrowthTracker(Options{Disabled: map[string]bool{DetectorLeaks: true}})
+	start := time.Unix(0, 0)
+	for i, n := range []int{2, 9, 14} {
+		if _, err := tracker.Add(growthDump(n), start.Add(time.Duration(i)*time.Minute)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if f := tracker.Result().Findings; len(f) != 0 {
+		t.Errorf("got %d findings with the leaks detector disabled", len(f))
+	}
+}
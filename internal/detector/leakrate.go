@@ -0,0 +1,122 @@
+package detector
+
+import (
+	"sort"
+	"strings"
+
+	"golang.org/x/exp/trace"
+)
+
+// LeakRate summarizes how many goroutines each invocation of one test leaked
+// during a stress run (go test -count=N in a single trace).
+type LeakRate struct {
+	Test    string
+	Package string // set by Merge for multi-package runs
+	// Invocations is the number of times the test ran: the larger of the
+	// requested iteration count and the invocations seen in the trace.
+	Invocations int
+	// PerInvocation holds the goroutines leaked by each observed invocation,
+	// in run order. Invocations that spawned nothing (and so left no trace
+	// of their test name) are counted as zero and appended at the end.
+	PerInvocation []int
+	Leaked        int     // total goroutines leaked across all invocations
+	Rate          float64 // Leaked / Invocations
+	// Linear is true when every invocation leaked at least one goroutine,
+	// i.e. the number of leaked goroutines grows with the iteration count.
+	Linear bool
+}
+
+// computeLeakRates attributes every leaked goroutine to the test invocation
+// it descends from and returns one LeakRate per test that leaked anything.
+// findings must be the pre-deduplication list so each leaked goroutine is
+// counted once.
+//
+// An invocation is identified by its outermost tRunner goroutine: with
+// -count=N each run of TestX gets a fresh tRunner goroutine, and subtests
+// run in tRunner goroutines nested below it.
+func computeLeakRates(goroutines map[trace.GoID]*goroutineState, tombstones map[trace.GoID]lineage, findings []Finding, iterations int) []LeakRate {
+	leaked := make(map[trace.GoID]int) // invocation root → leaked goroutines
+	for _, f := range findings {
+		if f.Kind != KindGoroutineLeak || f.GoroutineID == 0 {
+			continue
+		}
+		if root := invocationRoot(goroutines, tombstones, f.GoroutineID); root != 0 {
+			leaked[root]++
+		}
+	}
+	if len(leaked) == 0 {
+		return nil
+	}
+
+	roots := make(map[string][]trace.GoID) // test name → invocation roots
+	addRoot := func(gid trace.GoID, l lineage) {
+		if l.testName == "" || !l.tRunner {
+			return
+		}
+		if invocationRoot(goroutines, tombstones, gid) != gid {
+			return // subtest
+		}
+		roots[l.testName] = append(roots[l.testName], gid)
+	}
+	for gid, g := range goroutines {
+		addRoot(gid, lineageOfState(g))
+	}
+	for gid, t := range tombstones {
+		addRoot(gid, t)
+	}
+
+	var rates []LeakRate
+	for test, ids := range roots {
+		// Goroutine IDs are allocated monotonically, so ID order is run order.
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		lr := LeakRate{Test: test, Invocations: len(ids)}
+		for _, id := range ids {
+			lr.PerInvocation = append(lr.PerInvocation, leaked[id])
+			lr.Leaked += leaked[id]
+		}
+		if lr.Leaked == 0 {
+			continue
+		}
+		for lr.Invocations < iterations {
+			lr.PerInvocation = append(lr.PerInvocation, 0)
+			lr.Invocations++
+		}
+		lr.Rate = float64(lr.Leaked) / float64(lr.Invocations)
+		lr.Linear = true
+		for _, n := range lr.PerInvocation {
+			if n == 0 {
+				lr.Linear = false
+				break
+			}
+		}
+		rates = append(rates, lr)
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].Test < rates[j].Test })
+	return rates
+}
+
+// invocationRoot returns the outermost tRunner goroutine among gid and its
+// ancestors, or 0 if gid does not run under a test.
+func invocationRoot(goroutines map[trace.GoID]*goroutineState, tombstones map[trace.GoID]lineage, gid trace.GoID) trace.GoID {
+	var root trace.GoID
+	for id := gid; id != 0; {
+		l, ok := lineageOf(goroutines, tombstones, id)
+		if !ok {
+			break
+		}
+		if l.tRunner {
+			root = id
+		}
+		if l.parentID == id {
+			break
+		}
+		id = l.parentID
+	}
+	return root
+}
+
+// isTRunnerGoroutine reports whether g is a per-test goroutine started by
+// testing.(*T).Run.
+func isTRunnerGoroutine(g *goroutineState) bool {
+	return strings.Contains(g.creationStack, "testing.tRunner")
+}
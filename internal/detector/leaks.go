@@ -52,9 +52,9 @@ func detectLeaks(goroutines map[trace.GoID]*goroutineState, lastTime trace.Time,
 			kind = KindGoroutineLeak
 			// Confidence scaled by lifetime ratio.
 			switch {
-			case blockRatio >= 0.85:
+			case blockRatio >= opts.Thresholds.HighRatio:
 				conf = ConfidenceHigh
-			case blockRatio >= 0.40:
+			case blockRatio >= opts.Thresholds.MediumRatio:
 				conf = ConfidenceMedium
 			default:
 				conf = ConfidenceLow
@@ -66,7 +66,7 @@ func detectLeaks(goroutines map[trace.GoID]*goroutineState, lastTime trace.Time,
 			}
 			kind = KindLongBlock
 			switch {
-			case blockRatio >= 0.85:
+			case blockRatio >= opts.Thresholds.HighRatio:
 				conf = ConfidenceMedium // long blocks are at most Medium
 			default:
 				conf = ConfidenceLow
@@ -89,14 +89,15 @@ func detectLeaks(goroutines map[trace.GoID]*goroutineState, lastTime trace.Time,
 }
 
 // detectOrphans finds goroutines that were created but never ran and never died
-// during a very short trace (< 200ms). This targets the "test exits immediately"
-// pattern where goroutines are spawned just before the test returns, leaving
-// them in limbo. Emits ConfidenceLow findings.
-func detectOrphans(goroutines map[trace.GoID]*goroutineState, traceDuration time.Duration) []Finding {
+// during a very short trace (< Thresholds.OrphanMaxTrace, 200ms by default).
+// This targets the "test exits immediately" pattern where goroutines are
+// spawned just before the test returns, leaving them in limbo. Emits
+// ConfidenceLow findings.
+func detectOrphans(goroutines map[trace.GoID]*goroutineState, traceDuration time.Duration, opts Options) []Finding {
 	// Only trigger on very short traces — the "test exits immediately" pattern.
 	// On longer traces, goroutines that are alive-but-not-blocked are normal
 	// background workers and would produce false positives.
-	if traceDuration >= 200*time.Millisecond {
+	if traceDuration >= opts.Thresholds.OrphanMaxTrace {
 		return nil
 	}
 
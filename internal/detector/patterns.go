@@ -0,0 +1,99 @@
+package detector
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/trace"
+)
+
+// SpawnSite is a 'go' statement: the function that executed it and its
+// file:line.
+type SpawnSite struct {
+	Function string
+	Location string
+}
+
+// spawnPathMaxDepth bounds the ancestors walked for a spawn path, which
+// also guards against parent cycles in corrupt traces.
+const spawnPathMaxDepth = 32
+
+// attributeSpawnPaths sets SpawnPath on findings about a goroutine. It runs
+// before deduplication, so every goroutine's own path is seen; a
+// deduplicated finding keeps its representative's. Ancestors whose 'go'
+// statement is not in user code (testing.tRunner, runtime goroutines) add
+// nothing, and a goroutine re-spawning itself from one statement appears
+// once.
+func attributeSpawnPaths(findings []Finding, goroutines map[trace.GoID]*goroutineState, tombstones map[trace.GoID]lineage) {
+	for i := range findings {
+		gid := findings[i].GoroutineID
+		var path []SpawnSite
+		for depth := 0; gid != 0 && depth < spawnPathMaxDepth; depth++ {
+			l, ok := lineageOf(goroutines, tombstones, gid)
+			if !ok {
+				break
+			}
+			if site := l.spawn.spawnSite(); site.Location != "" && (len(path) == 0 || path[len(path)-1] != site) {
+				path = append(path, site)
+			}
+			if l.parentID == gid {
+				break
+			}
+			gid = l.parentID
+		}
+		slices.Reverse(path)
+		findings[i].SpawnPath = path
+	}
+}
+
+// Pattern is a group of findings whose goroutines were started along the
+// same spawn path, such as 500 goroutines leaked by the same HTTP handler
+// at two different lines: one bug, however many findings and goroutines it
+// shows up as.
+type Pattern struct {
+	// SpawnPath is the findings' common Finding.SpawnPath; its first site
+	// is the pattern's root.
+	SpawnPath []SpawnSite
+	// Goroutines is the number of goroutines affected: the largest total
+	// Count of the pattern's findings of one kind, since one goroutine can
+	// be reported by several detectors (a leak that is also a deadlock).
+	Goroutines int
+	Findings   []Finding
+}
+
+// Patterns groups findings with a spawn path by path (and package, for
+// merged results), largest first. Findings that are not about a goroutine
+// created in the trace, such as static and race findings, belong to none.
+func Patterns(findings []Finding) []Pattern {
+	var patterns []Pattern
+	index := make(map[string]int)
+	for _, f := range findings {
+		if len(f.SpawnPath) == 0 {
+			continue
+		}
+		var key strings.Builder
+		key.WriteString(f.Package)
+		for _, s := range f.SpawnPath {
+			key.WriteString("\x00" + s.Function + "\x00" + s.Location)
+		}
+		i, ok := index[key.String()]
+		if !ok {
+			i = len(patterns)
+			index[key.String()] = i
+			patterns = append(patterns, Pattern{SpawnPath: f.SpawnPath})
+		}
+		patterns[i].Findings = append(patterns[i].Findings, f)
+	}
+	for i := range patterns {
+		byKind := make(map[Kind]int)
+		for _, f := range patterns[i].Findings {
+			byKind[f.Kind] += max(f.Count, 1)
+			patterns[i].Goroutines = max(patterns[i].Goroutines, byKind[f.Kind])
+		}
+	}
+	slices.SortStableFunc(patterns, func(a, b Pattern) int {
+		return cmp.Compare(b.Goroutines, a.Goroutines)
+	})
+	return patterns
+}
@@ -0,0 +1,79 @@
+package detector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPatterns(t *testing.T) {
+	serve := SpawnSite{Function: "example.com/app.(*Server).Serve", Location: "/app/server.go:20"}
+	handle := SpawnSite{Function: "example.com/app.(*Server).handle", Location: "/app/server.go:55"}
+	tick := SpawnSite{Function: "example.com/app.startTicker", Location: "/app/tick.go:8"}
+	handlerPath := []SpawnSite{serve, handle}
+
+	findings := []Finding{
+		// One handler leaks at two lines, and some of those goroutines
+		// are also deadlocked: one bug.
+		{Kind: KindGoroutineLeak, Location: "/app/handler.go:30", Count: 300, SpawnPath: handlerPath},
+		{Kind: KindGoroutineLeak, Location: "/app/handler.go:41", Count: 200, SpawnPath: handlerPath},
+		{Kind: KindDeadlock, Location: "/app/handler.go:41", Count: 2, SpawnPath: handlerPath},
+		// Started by Serve directly: a path that shares only a prefix.
+		{Kind: KindGoroutineLeak, Location: "/app/server.go:90", SpawnPath: []SpawnSite{serve}},
+		// Another path, and the same path in another package.
+		{Kind: KindLongBlock, Location: "/app/tick.go:12", Count: 4, SpawnPath: []SpawnSite{tick}},
+		{Kind: KindLongBlock, Location: "/app/tick.go:12", Count: 1, Package: "example.com/other", SpawnPath: []SpawnSite{tick}},
+		// Not about a goroutine: no pattern.
+		{Kind: KindLockOrder, Location: "/app/lock.go:3"},
+	}
+	patterns := Patterns(findings)
+
+	var got []string
+	for _, p := range patterns {
+		var locs []string
+		for _, f := range p.Findings {
+			locs = append(locs, f.Location[strings.LastIndex(f.Location, "/")+1:])
+		}
+		got = append(got, strings.Join(locs, ","))
+	}
+	// Largest first; ties keep the order of their first finding.
+	want := []string{"handler.go:30,handler.go:41,handler.go:41", "tick.go:12", "server.go:90", "tick.go:12"}
+	if strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Fatalf("patterns %q, want %q", got, want)
+	}
+
+	// Leaks add up across lines; the deadlocked goroutines are among them.
+	for i, n := range []int{500, 4, 1, 1} {
+		if patterns[i].Goroutines != n {
+			t.Errorf("pattern %d (%s): %d goroutines, want %d", i, want[i], patterns[i].Goroutines, n)
+		}
+	}
+	if p := patterns[3]; p.Findings[0].Package != "example.com/other" {
+		t.Errorf("last pattern is in %q, want example.com/other", p.Findings[0].Package)
+	}
+}
+
+func TestPatternsFromTrace(t *testing.T) {
+	result, err := Analyze("../../testdata/timeline-spans/trace.out", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	patterns := Patterns(result.Findings)
+	if len(patterns) != 2 {
+		t.Fatalf("got %d patterns, want the waiters' and the churner's: %+v", len(patterns), patterns)
+	}
+	// testing.tRunner's 'go' statement is not user code, so each path is
+	// the single statement in the test.
+	for i, want := range []struct {
+		site       string
+		goroutines int
+	}{
+		{"spans_test.go:33", 3},
+		{"spans_test.go:20", 1},
+	} {
+		p := patterns[i]
+		if len(p.SpawnPath) != 1 || !strings.HasSuffix(p.SpawnPath[0].Location, want.site) ||
+			!strings.HasSuffix(p.SpawnPath[0].Function, ".TestTimelineSpans") || p.Goroutines != want.goroutines {
+			t.Errorf("pattern %d: path %+v, %d goroutines; want TestTimelineSpans at %s, %d", i, p.SpawnPath, p.Goroutines, want.site, want.goroutines)
+		}
+	}
+}
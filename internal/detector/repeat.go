@@ -0,0 +1,71 @@
+package detector
+
+import "slices"
+
+// Repeat describes the runs merged by MergeRuns.
+type Repeat struct {
+	Runs    int
+	MinHits int
+	// Envs is the extra environment of each run, such as "GOMAXPROCS=2";
+	// "" for a run in the default environment.
+	Envs []string
+	// Flaky holds the findings seen in fewer than MinHits runs. They are
+	// not in Result.Findings.
+	Flaky []Finding
+}
+
+// MergeRuns merges the results of running the same tests several times, as
+// `threadgraph run --repeat` does to tell schedule-dependent findings from
+// reproducible ones. envs[i] describes the environment of results[i].
+//
+// Findings are matched across runs by package, Fingerprint and Location:
+// every run traces the same build, and one function blocking at two lines is
+// two findings. Each merged finding is the occurrence blocked the longest,
+// with Hits set to the number of runs it appeared in and Runs to
+// len(results). Findings with fewer than minHits hits go to Repeat.Flaky
+// instead of Findings. The summary fields (trace file, packages, leak rates,
+// timeline) are those of the first run.
+func MergeRuns(results []*Result, envs []string, minHits int) *Result {
+	merged := *results[0]
+	merged.Findings = nil
+	merged.Repeat = &Repeat{Runs: len(results), MinHits: minHits, Envs: envs}
+
+	type key struct{ pkg, fingerprint, location string }
+	index := make(map[key]int)
+	var all []Finding
+	for _, r := range results {
+		merged.PeakHeapBytes = max(merged.PeakHeapBytes, r.PeakHeapBytes)
+		seen := make(map[key]bool)
+		for _, f := range r.Findings {
+			k := key{f.Package, f.Fingerprint(), f.Location}
+			i, ok := index[k]
+			if !ok {
+				index[k] = len(all)
+				f.Hits = 0
+				all = append(all, f)
+				i = len(all) - 1
+			} else if f.BlockedFor > all[i].BlockedFor {
+				f.Hits = all[i].Hits
+				all[i] = f
+			}
+			if !seen[k] {
+				seen[k] = true
+				all[i].Hits++
+			}
+		}
+	}
+
+	for _, f := range all {
+		f.Runs = len(results)
+		if f.Hits >= minHits {
+			merged.Findings = append(merged.Findings, f)
+		} else {
+			merged.Repeat.Flaky = append(merged.Repeat.Flaky, f)
+		}
+	}
+	// Most reproducible first; stable, so detector order is kept within.
+	for _, list := range [][]Finding{merged.Findings, merged.Repeat.Flaky} {
+		slices.SortStableFunc(list, func(a, b Finding) int { return b.Hits - a.Hits })
+	}
+	return &merged
+}
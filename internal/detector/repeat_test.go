@@ -0,0 +1,61 @@
+package detector
+
+import (
+	"testing"
+	"time"
+)
+
+// workerLeak is a leak in worker, which blocks either at <-a (line 7) or at
+// <-b (line 9); both have the same fingerprint.
+func workerLeak(line string, blocked time.Duration) Finding {
+	return Finding{
+		Kind:       KindGoroutineLeak,
+		BlockedOn:  "chan receive",
+		BlockedFor: blocked,
+		Function:   "example.com/app.worker",
+		Location:   "/app/worker.go:" + line,
+		Stack:      "      example.com/app.worker (/app/worker.go:" + line + ")\n",
+		Package:    "example.com/app",
+	}
+}
+
+func TestMergeRuns(t *testing.T) {
+	results := []*Result{
+		{TraceFile: "run1.out", PeakHeapBytes: 10, Findings: []Finding{
+			workerLeak("7", time.Second),
+			workerLeak("9", time.Second),
+		}},
+		{TraceFile: "run2.out", PeakHeapBytes: 30, Findings: []Finding{
+			workerLeak("9", 3*time.Second),
+			// The same goroutine site twice in one run counts once.
+			workerLeak("9", 2*time.Second),
+		}},
+		{TraceFile: "run3.out", PeakHeapBytes: 20, Findings: []Finding{
+			workerLeak("9", 2*time.Second),
+		}},
+	}
+	if results[0].Findings[0].Fingerprint() != results[0].Findings[1].Fingerprint() {
+		t.Fatal("test findings should share a fingerprint")
+	}
+
+	merged := MergeRuns(results, []string{"", "GOMAXPROCS=1", "GOMAXPROCS=2"}, 2)
+	if merged.TraceFile != "run1.out" || merged.PeakHeapBytes != 30 {
+		t.Errorf("TraceFile %q, PeakHeapBytes %d; want the first run's file and the peak", merged.TraceFile, merged.PeakHeapBytes)
+	}
+	if r := merged.Repeat; r.Runs != 3 || r.MinHits != 2 || len(r.Envs) != 3 {
+		t.Errorf("Repeat = %+v", r)
+	}
+
+	if len(merged.Findings) != 1 {
+		t.Fatalf("got %d findings, want the one at line 9: %+v", len(merged.Findings), merged.Findings)
+	}
+	if f := merged.Findings[0]; f.Location != "/app/worker.go:9" || f.Hits != 3 || f.Runs != 3 || f.BlockedFor != 3*time.Second {
+		t.Errorf("finding at %s: %d/%d hits, blocked %v; want line 9, 3/3, 3s", f.Location, f.Hits, f.Runs, f.BlockedFor)
+	}
+	if len(merged.Repeat.Flaky) != 1 {
+		t.Fatalf("got %d flaky findings, want the one at line 7: %+v", len(merged.Repeat.Flaky), merged.Repeat.Flaky)
+	}
+	if f := merged.Repeat.Flaky[0]; f.Location != "/app/worker.go:7" || f.Hits != 1 || f.Runs != 3 {
+		t.Errorf("flaky finding at %s: %d/%d hits; want line 7, 1/3", f.Location, f.Hits, f.Runs)
+	}
+}
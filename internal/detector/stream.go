@@ -0,0 +1,140 @@
+package detector
+
+import (
+	"runtime/metrics"
+
+	"golang.org/x/exp/trace"
+)
+
+// heapSampleInterval is how many trace events are processed between two
+// samples of the analyzer's own heap size.
+const heapSampleInterval = 1 << 14
+
+// stackInfo is the formatted form of one distinct stack. Every goroutine
+// blocked at (or created from) the same stack shares one stackInfo, so the
+// formatted text is held once no matter how many goroutines use it.
+type stackInfo struct {
+	stack    string
+	function string
+	location string
+	testName string // testNameFromStack
+}
+
+// stackTable interns stacks. Within a trace generation, events refer to
+// stacks by ID, so the trace.Stack handle is used as a first-level key; the
+// handle cache is dropped at every generation boundary (EventSync) so that
+// old generations' stack tables can be freed. Formatted text is interned
+// across generations.
+type stackTable struct {
+	byHandle map[trace.Stack]*stackInfo
+	byText   map[string]*stackInfo
+}
+
+func newStackTable() *stackTable {
+	return &stackTable{
+		byHandle: make(map[trace.Stack]*stackInfo),
+		byText:   make(map[string]*stackInfo),
+	}
+}
+
+// lookup returns the interned stackInfo for s, formatting s only the first
+// time its ID is seen in the current generation.
+func (t *stackTable) lookup(s trace.Stack) *stackInfo {
+	if si, ok := t.byHandle[s]; ok {
+		return si
+	}
+	stack, function, location := extractStack(s)
+	si, ok := t.byText[stack]
+	if !ok {
+		si = &stackInfo{
+			stack:    stack,
+			function: function,
+			location: location,
+			testName: testNameFromStack(s),
+		}
+		t.byText[stack] = si
+	}
+	t.byHandle[s] = si
+	return si
+}
+
+// spawnSite returns the top user-code frame of a creator's stack si: the
+// 'go' statement. si may be nil.
+func (si *stackInfo) spawnSite() SpawnSite {
+	if si == nil {
+		return SpawnSite{}
+	}
+	return SpawnSite{Function: si.function, Location: si.location}
+}
+
+// newGeneration drops the per-generation handle cache.
+func (t *stackTable) newGeneration() {
+	clear(t.byHandle)
+}
+
+// lineage is the part of a goroutine's state needed to walk the goroutine
+// tree: test ownership, test-name inheritance, leak-rate invocation roots
+// and spawn paths. In streaming mode it is all that is kept of an exited
+// goroutine that no detector reports on (a tombstone).
+type lineage struct {
+	parentID trace.GoID
+	testName string
+	root     bool       // Options.rootFilter; only set on tombstones
+	tRunner  bool       // isTRunnerGoroutine
+	spawn    *stackInfo // goroutineState.spawn
+}
+
+func lineageOfState(g *goroutineState) lineage {
+	return lineage{
+		parentID: g.parentID,
+		testName: g.testName,
+		tRunner:  isTRunnerGoroutine(g),
+		spawn:    g.spawn,
+	}
+}
+
+// tombstoneOf reduces the state of an exited goroutine to its lineage.
+func tombstoneOf(g *goroutineState, isRoot func(*goroutineState) bool) lineage {
+	l := lineageOfState(g)
+	l.root = isRoot(g)
+	return l
+}
+
+// lineageOf returns gid's lineage from its live state or its tombstone.
+func lineageOf(goroutines map[trace.GoID]*goroutineState, tombstones map[trace.GoID]lineage, gid trace.GoID) (lineage, bool) {
+	if g := goroutines[gid]; g != nil {
+		return lineageOfState(g), true
+	}
+	l, ok := tombstones[gid]
+	return l, ok
+}
+
+// retainAfterExit reports whether a detector may still report on the exited
+// goroutine g, so that streaming mode must keep its full state. Only
+// detectTransientBlocks looks at exited goroutines.
+func retainAfterExit(g *goroutineState, opts Options) bool {
+	return g.prevLongBlockDuration > 0 &&
+		g.prevLongBlockDuration >= opts.MinBlock &&
+		!isRuntimeGoroutine(g.prevLongBlockStack)
+}
+
+// heapSampler tracks the peak size of live heap objects while a trace is
+// analyzed.
+type heapSampler struct {
+	sample []metrics.Sample
+	peak   uint64
+}
+
+func newHeapSampler() *heapSampler {
+	return &heapSampler{sample: []metrics.Sample{{Name: "/memory/classes/heap/objects:bytes"}}}
+}
+
+func (h *heapSampler) read() {
+	metrics.Read(h.sample)
+	if h.sample[0].Value.Kind() != metrics.KindUint64 {
+		return
+	}
+	if v := h.sample[0].Value.Uint64(); v > h.peak {
+		h.peak = v
+	}
+}
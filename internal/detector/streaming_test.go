@@ -0,0 +1,63 @@
+package detector
+
+import (
+	"cmp"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+	"time"
+)
+
+// TestStreamingMatchesFullAnalysis checks that discarding the state of exited
+// goroutines early does not change what is reported for any recorded trace.
+// Detectors walk goroutine maps, so findings are compared in a fixed order.
+func TestStreamingMatchesFullAnalysis(t *testing.T) {
+	traces, err := filepath.Glob("../../testdata/*/trace.out")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) == 0 {
+		t.Fatal("no traces in testdata")
+	}
+	total := 0
+	for _, path := range traces {
+		t.Run(filepath.Base(filepath.Dir(path)), func(t *testing.T) {
+			opts := Options{MinBlock: time.Second}
+			full, err := Analyze(path, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts.Streaming = true
+			streamed, err := Analyze(path, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			total += len(full.Findings)
+			sortFindings(full.Findings)
+			sortFindings(streamed.Findings)
+			if !reflect.DeepEqual(full.Findings, streamed.Findings) {
+				t.Errorf("findings differ\nfull:      %+v\nstreaming: %+v", full.Findings, streamed.Findings)
+			}
+			if !reflect.DeepEqual(full.Tests, streamed.Tests) {
+				t.Errorf("tests differ: full %v, streaming %v", full.Tests, streamed.Tests)
+			}
+			if full.GoroutinesAnalyzed != streamed.GoroutinesAnalyzed {
+				t.Errorf("goroutines analyzed: full %d, streaming %d", full.GoroutinesAnalyzed, streamed.GoroutinesAnalyzed)
+			}
+		})
+	}
+	if total == 0 {
+		t.Error("no findings in any trace; the fixtures no longer exercise the detectors")
+	}
+}
+
+func sortFindings(findings []Finding) {
+	slices.SortFunc(findings, func(a, b Finding) int {
+		return cmp.Or(
+			cmp.Compare(a.Kind, b.Kind),
+			cmp.Compare(a.Location, b.Location),
+			cmp.Compare(a.GoroutineID, b.GoroutineID),
+		)
+	})
+}
@@ -0,0 +1,86 @@
+package detector
+
+import "time"
+
+// Thresholds are the detectors' tunable cutoffs. A zero field selects the
+// default from DefaultThresholds.
+type Thresholds struct {
+	// Deadlock is how long goroutines must be blocked at the same mutex
+	// site to form a deadlock group. A smaller Options.MinBlock lowers it.
+	Deadlock time.Duration
+	// ABBAStaleWindow is how old a lock acquisition can be and still count
+	// towards a lock-order or channel-lock cycle.
+	ABBAStaleWindow time.Duration
+	// OrphanMaxTrace is the trace length below which goroutines that never
+	// ran are reported; on longer traces they are normal background workers.
+	OrphanMaxTrace time.Duration
+	// HighRatio and MediumRatio are the fractions of the trace a leaked
+	// goroutine must have been blocked for high and medium confidence.
+	HighRatio   float64
+	MediumRatio float64
+	// SyncHistory is how many recent lock acquisitions are remembered per
+	// goroutine for lock-order cycle detection.
+	SyncHistory int
+}
+
+// DefaultThresholds returns the thresholds used for zero Thresholds fields.
+func DefaultThresholds() Thresholds {
+	return Thresholds{
+		Deadlock:        500 * time.Millisecond,
+		ABBAStaleWindow: 5 * time.Second,
+		OrphanMaxTrace:  200 * time.Millisecond,
+		HighRatio:       0.85,
+		MediumRatio:     0.40,
+		SyncHistory:     5,
+	}
+}
+
+// WithDefaults returns t with every zero field set to its default.
+func (t Thresholds) WithDefaults() Thresholds {
+	d := DefaultThresholds()
+	if t.Deadlock == 0 {
+		t.Deadlock = d.Deadlock
+	}
+	if t.ABBAStaleWindow == 0 {
+		t.ABBAStaleWindow = d.ABBAStaleWindow
+	}
+	if t.OrphanMaxTrace == 0 {
+		t.OrphanMaxTrace = d.OrphanMaxTrace
+	}
+	if t.HighRatio == 0 {
+		t.HighRatio = d.HighRatio
+	}
+	if t.MediumRatio == 0 {
+		t.MediumRatio = d.MediumRatio
+	}
+	if t.SyncHistory == 0 {
+		t.SyncHistory = d.SyncHistory
+	}
+	return t
+}
+
+// Detector names, as used by Options.Disabled and the detectors section of
+// .threadgraph.yaml.
+const (
+	DetectorLeaks             = "leaks"
+	DetectorDeadlocks         = "deadlocks"
+	DetectorTransientBlocks   = "transient_blocks"
+	DetectorLockCycles        = "lock_cycles"
+	DetectorChanLockCycle     = "chan_lock_cycle"
+	DetectorOrphans           = "orphans"
+	DetectorWaitGroupDeadlock = "waitgroup_deadlock"
+	DetectorWaitForCycles     = "wait_for_cycles"
+)
+
+// DetectorNames lists every detector that can be disabled, in the order
+// Analyze runs them.
+var DetectorNames = []string{
+	DetectorLeaks,
+	DetectorDeadlocks,
+	DetectorTransientBlocks,
+	DetectorLockCycles,
+	DetectorChanLockCycle,
+	DetectorOrphans,
+	DetectorWaitGroupDeadlock,
+	DetectorWaitForCycles,
+}
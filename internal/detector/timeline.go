@@ -0,0 +1,210 @@
+package detector
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/trace"
+)
+
+// SpanState is the state of a goroutine during a Span.
+type SpanState string
+
+const (
+	SpanRunnable SpanState = "runnable"
+	SpanRunning  SpanState = "running"
+	SpanBlocked  SpanState = "blocked"
+	SpanSyscall  SpanState = "syscall"
+)
+
+// timelineMaxSpans bounds the spans kept per goroutine. A goroutine that
+// changes state more often keeps its most recent spans, which are the ones
+// that show how it ended up leaked or deadlocked.
+const timelineMaxSpans = 512
+
+// Timeline is the lifecycle of every goroutine in a trace, recorded when
+// Options.Timeline is set. Times are offsets from the first event of the
+// trace.
+type Timeline struct {
+	Duration   time.Duration
+	Goroutines []GoroutineTimeline // sorted by ID
+}
+
+// GoroutineTimeline is the lifecycle of one goroutine.
+type GoroutineTimeline struct {
+	ID       trace.GoID
+	ParentID trace.GoID
+	Test     string
+	// Function and Location are the goroutine's entry point: its top
+	// user-code frame, or for goroutines that run no user code, such as
+	// testing.tRunner, the function it started in (without a location).
+	// For goroutines created before the trace, Function is the outermost
+	// frame of the first stack seen for them.
+	Function string
+	Location string
+	// CreatedBy and CreatedAt are the function and file:line of the 'go'
+	// statement that started the goroutine, and CreationStack the stack
+	// that executed it.
+	CreatedBy     string
+	CreatedAt     string
+	CreationStack string
+	// Created is when the goroutine was created, or -1 if it existed before
+	// the trace started. Exited is when it exited, or -1 if it was still
+	// alive when the trace ended.
+	Created time.Duration
+	Exited  time.Duration
+	Spans   []Span
+	// Dropped is the number of earliest spans dropped to bound memory.
+	Dropped int
+	// Kinds lists the kinds of the findings about this goroutine. Unlike
+	// Finding.GoroutineID, it is taken before deduplication, so every
+	// goroutine of a finding with Count > 1 is marked.
+	Kinds []Kind
+}
+
+// Span is a period a goroutine spent in one state.
+type Span struct {
+	State SpanState
+	Start time.Duration
+	End   time.Duration
+	// Reason and Stack are why and where a blocked goroutine was blocked.
+	Reason string
+	Stack  string
+}
+
+// timelineRecorder builds a Timeline from the goroutine state transitions
+// of a trace. Its own state outlives the goroutine states that streaming
+// mode drops. A nil recorder records nothing.
+type timelineRecorder struct {
+	base  trace.Time
+	lanes map[trace.GoID]*GoroutineTimeline
+}
+
+func newTimelineRecorder(opts Options) *timelineRecorder {
+	if !opts.Timeline {
+		return nil
+	}
+	return &timelineRecorder{lanes: make(map[trace.GoID]*GoroutineTimeline)}
+}
+
+// begin sets the time of the first event of the trace.
+func (t *timelineRecorder) begin(first trace.Time) {
+	if t != nil {
+		t.base = first
+	}
+}
+
+// record applies the state transition ev of goroutine gid, whose state g
+// has already been updated for it.
+func (t *timelineRecorder) record(gid trace.GoID, g *goroutineState, ev *trace.Event, stacks *stackTable) {
+	if t == nil {
+		return
+	}
+	st := ev.StateTransition()
+	from, to := st.Goroutine()
+	now := time.Duration(ev.Time()-t.base) * time.Nanosecond
+
+	lane := t.lanes[gid]
+	if lane == nil {
+		lane = &GoroutineTimeline{ID: gid, Created: -1, Exited: -1}
+		t.lanes[gid] = lane
+	}
+	if from == trace.GoNotExist {
+		lane.Created = now
+		lane.ParentID = g.parentID
+		lane.Function, lane.Location = g.creationFunction, g.creationLocation
+		if lane.Function == "" {
+			start := strings.TrimSpace(stacks.lookup(st.Stack).stack)
+			lane.Function, _, _ = strings.Cut(start, " ")
+		}
+		site := g.spawn.spawnSite()
+		lane.CreatedBy, lane.CreatedAt = site.Function, site.Location
+		lane.CreationStack = stacks.lookup(ev.Stack()).stack
+	}
+	if from == to {
+		return // a status re-announced at a generation boundary
+	}
+	if n := len(lane.Spans); n > 0 {
+		lane.Spans[n-1].End = now
+	}
+
+	span := Span{Start: now, End: now}
+	if from == trace.GoUndetermined && len(lane.Spans) == 0 {
+		// The goroutine was in this state since before the trace started.
+		span.Start = 0
+	}
+	switch to {
+	case trace.GoRunnable:
+		span.State = SpanRunnable
+	case trace.GoRunning:
+		span.State = SpanRunning
+	case trace.GoWaiting:
+		span.State = SpanBlocked
+		span.Reason = st.Reason
+		span.Stack = stacks.lookup(st.Stack).stack
+		if g.isBlocked && g.reason != "" {
+			span.Reason = g.reason // from the start dump, in service mode
+		}
+	case trace.GoSyscall:
+		span.State = SpanSyscall
+	case trace.GoNotExist:
+		lane.Exited = now
+		return
+	default:
+		return
+	}
+	if n := len(lane.Spans); n > 0 {
+		prev := &lane.Spans[n-1]
+		if prev.State == span.State && prev.Reason == span.Reason && prev.Stack == span.Stack {
+			return // the open span continues
+		}
+	}
+	if len(lane.Spans) == timelineMaxSpans {
+		half := timelineMaxSpans / 2
+		lane.Spans = append(lane.Spans[:0], lane.Spans[half:]...)
+		lane.Dropped += half
+	}
+	lane.Spans = append(lane.Spans, span)
+}
+
+// finish closes the spans of goroutines still alive at last, the time of
+// the last event of the trace, sets their test names and marks the
+// goroutines of findings.
+func (t *timelineRecorder) finish(last trace.Time, goroutines map[trace.GoID]*goroutineState, tombstones map[trace.GoID]lineage, findings []Finding) *Timeline {
+	if t == nil {
+		return nil
+	}
+	end := time.Duration(last-t.base) * time.Nanosecond
+	tl := &Timeline{Duration: end}
+	for gid, lane := range t.lanes {
+		if n := len(lane.Spans); n > 0 && lane.Exited < 0 {
+			lane.Spans[n-1].End = end
+		}
+		if l, ok := lineageOf(goroutines, tombstones, gid); ok {
+			lane.Test = l.testName
+		}
+		for _, s := range lane.Spans {
+			if lane.Function == "" && s.Stack != "" {
+				lines := strings.Split(strings.TrimSpace(s.Stack), "\n")
+				lane.Function, _, _ = strings.Cut(strings.TrimSpace(lines[len(lines)-1]), " ")
+			}
+		}
+		tl.Goroutines = append(tl.Goroutines, *lane)
+	}
+	slices.SortFunc(tl.Goroutines, func(a, b GoroutineTimeline) int {
+		return cmp.Compare(a.ID, b.ID)
+	})
+
+	index := make(map[trace.GoID]int, len(tl.Goroutines))
+	for i, lane := range tl.Goroutines {
+		index[lane.ID] = i
+	}
+	for _, f := range findings {
+		if i, ok := index[f.GoroutineID]; ok && f.GoroutineID != 0 && !slices.Contains(tl.Goroutines[i].Kinds, f.Kind) {
+			tl.Goroutines[i].Kinds = append(tl.Goroutines[i].Kinds, f.Kind)
+		}
+	}
+	return tl
+}
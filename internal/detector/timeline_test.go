@@ -0,0 +1,80 @@
+package detector
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestTimeline(t *testing.T) {
+	const path = "../../testdata/timeline-spans/trace.out"
+	result, err := Analyze(path, Options{Timeline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tl := result.Timeline
+	if tl == nil || len(tl.Goroutines) == 0 {
+		t.Fatal("no timeline")
+	}
+
+	var churner *GoroutineTimeline
+	var waiters int
+	for i, lane := range tl.Goroutines {
+		if i > 0 && lane.ID <= tl.Goroutines[i-1].ID {
+			t.Errorf("goroutine %d listed after %d", lane.ID, tl.Goroutines[i-1].ID)
+		}
+		if len(lane.Spans) > timelineMaxSpans || lane.Dropped%(timelineMaxSpans/2) != 0 {
+			t.Errorf("goroutine %d: %d spans, %d dropped", lane.ID, len(lane.Spans), lane.Dropped)
+		}
+		for j, s := range lane.Spans {
+			if s.Start < 0 || s.Start > s.End || s.End > tl.Duration {
+				t.Errorf("goroutine %d span %d: %v to %v, outside the %v trace", lane.ID, j, s.Start, s.End, tl.Duration)
+			}
+			if j > 0 && s.Start != lane.Spans[j-1].End {
+				t.Errorf("goroutine %d span %d starts at %v, previous ended at %v", lane.ID, j, s.Start, lane.Spans[j-1].End)
+			}
+		}
+		switch {
+		case strings.HasSuffix(lane.Function, ".TestTimelineSpans.func1"):
+			churner = &tl.Goroutines[i]
+		case strings.HasSuffix(lane.Function, ".TestTimelineSpans.func2"):
+			waiters++
+			if !slices.Equal(lane.Kinds, []Kind{KindGoroutineLeak}) {
+				t.Errorf("waiter %d: Kinds = %v, want goroutine_leak", lane.ID, lane.Kinds)
+			}
+		}
+	}
+
+	// The waiters are one finding with Count 3, but each lane is marked.
+	if waiters != 3 {
+		t.Errorf("got %d waiter lanes, want 3", waiters)
+	}
+
+	if churner == nil {
+		t.Fatal("no lane for the churner")
+	}
+	if churner.Dropped == 0 || len(churner.Spans) == 0 {
+		t.Fatalf("churner: %d spans, %d dropped; want the earliest dropped", len(churner.Spans), churner.Dropped)
+	}
+	if !slices.Equal(churner.Kinds, []Kind{KindGoroutineLeak}) || churner.Test != "TestTimelineSpans" {
+		t.Errorf("churner: Kinds %v, Test %q", churner.Kinds, churner.Test)
+	}
+	if churner.Created < 0 || churner.Exited != -1 || !strings.HasSuffix(churner.CreatedAt, "spans_test.go:20") {
+		t.Errorf("churner: created %v at %q, exited %v", churner.Created, churner.CreatedAt, churner.Exited)
+	}
+	last := churner.Spans[len(churner.Spans)-1]
+	if last.State != SpanBlocked || !strings.Contains(last.Reason, "chan receive") || last.End != tl.Duration {
+		t.Errorf("churner's last span: %s (%s) until %v, want blocked on chan receive until the end, %v", last.State, last.Reason, last.End, tl.Duration)
+	}
+	if !strings.Contains(last.Stack, ".TestTimelineSpans.func1") {
+		t.Errorf("churner's last span has stack %q", last.Stack)
+	}
+
+	result, err = Analyze(path, Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Timeline != nil {
+		t.Error("timeline recorded without Options.Timeline")
+	}
+}
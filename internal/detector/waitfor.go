@@ -0,0 +1,255 @@
+package detector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/exp/trace"
+)
+
+// maxEvidenceHops bounds how far a "last woken by" chain is followed when
+// writing evidence for a finding.
+const maxEvidenceHops = 4
+
+// wakeGraph records which goroutine made which waiting goroutine runnable
+// over the whole trace. The execution trace attributes every
+// GoWaiting → GoRunnable transition to the goroutine executing at the time
+// (ev.Goroutine()), which for channel operations, mutex unlocks, wg.Done()
+// and cond signals is the goroutine that performed the unblocking action.
+//
+// Unlike the call-site heuristics in deadlock.go, the wait-for edges built
+// from it are a real "who unblocks whom" relation between goroutines: each
+// goroutine's own last waker (goroutineState.lastWaker).
+type wakeGraph struct {
+	// bySite maps a blocking call site (file:line) to the goroutine that
+	// most recently woke a goroutine blocked there. It is a call-site
+	// heuristic, used only for evidence about goroutines never woken
+	// themselves, and labeled as inferred there.
+	bySite map[string]trace.GoID
+}
+
+func newWakeGraph() *wakeGraph {
+	return &wakeGraph{bySite: make(map[string]trace.GoID)}
+}
+
+// record notes that waker made g runnable. It must be called before g's
+// blocking state is cleared.
+func (w *wakeGraph) record(gid trace.GoID, g *goroutineState, waker trace.GoID) {
+	if waker == trace.NoGoroutine || waker == gid || !isWakeableReason(g.reason) {
+		return
+	}
+	g.lastWaker = waker
+	g.lastWakeLocation = g.location
+	if g.location != "" {
+		w.bySite[g.location] = waker
+	}
+}
+
+// isWakeableReason reports whether a goroutine blocked for reason can only be
+// unblocked by another goroutine's action (as opposed to timers or I/O).
+func isWakeableReason(reason string) bool {
+	switch reason {
+	case "chan send", "chan receive", "select", "sync", "sync.(*Cond).Wait":
+		return true
+	}
+	return false
+}
+
+// wakerOf returns the goroutine expected to unblock the currently blocked
+// goroutine g: the last goroutine that woke g itself, or 0 if none did.
+func wakerOf(g *goroutineState) trace.GoID {
+	if !g.isBlocked || !isWakeableReason(g.reason) {
+		return 0
+	}
+	return g.lastWaker
+}
+
+// siteWakerOf returns the last goroutine that woke anyone blocked at the
+// call site g is blocked at, or 0. This only suggests who might unblock g.
+func (w *wakeGraph) siteWakerOf(gid trace.GoID, g *goroutineState) trace.GoID {
+	if !g.isBlocked || !isWakeableReason(g.reason) {
+		return 0
+	}
+	if id := w.bySite[g.location]; id != gid {
+		return id
+	}
+	return 0
+}
+
+// waitForEdges builds the wait-for graph at trace end: an edge G → W means
+// the blocked goroutine G waits for W, the goroutine that last unblocked G.
+// Only non-runtime goroutines participate.
+func (w *wakeGraph) waitForEdges(goroutines map[trace.GoID]*goroutineState) map[trace.GoID]trace.GoID {
+	edges := make(map[trace.GoID]trace.GoID)
+	for gid, g := range goroutines {
+		if isRuntimeGoroutine(g.stack) {
+			continue
+		}
+		waker := wakerOf(g)
+		if waker == 0 {
+			continue
+		}
+		if wg := goroutines[waker]; wg == nil || wg.goroutineDead {
+			continue
+		}
+		edges[gid] = waker
+	}
+	return edges
+}
+
+// detectWaitForCycles reports cycles in the goroutine wait-for graph: every
+// member is blocked, and each one's expected waker is the next member, so
+// none of them can ever be unblocked. Each goroutine has at most one outgoing
+// edge, so cycles are found by following edges with three-colour marking.
+func detectWaitForCycles(goroutines map[trace.GoID]*goroutineState, wakes *wakeGraph, lastTime trace.Time) []Finding {
+	edges := wakes.waitForEdges(goroutines)
+
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	color := make(map[trace.GoID]int, len(edges)) // zero value is unvisited
+	var cycles [][]trace.GoID
+
+	starts := make([]trace.GoID, 0, len(edges))
+	for gid := range edges {
+		starts = append(starts, gid)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i] < starts[j] })
+
+	for _, start := range starts {
+		var path []trace.GoID
+		cur := start
+		for {
+			if color[cur] == done {
+				break
+			}
+			if color[cur] == onPath {
+				// Cycle: the suffix of path starting at cur.
+				for i, id := range path {
+					if id == cur {
+						cycles = append(cycles, append([]trace.GoID(nil), path[i:]...))
+						break
+					}
+				}
+				break
+			}
+			next, ok := edges[cur]
+			if !ok {
+				break
+			}
+			color[cur] = onPath
+			path = append(path, cur)
+			cur = next
+		}
+		for _, id := range path {
+			color[id] = done
+		}
+	}
+
+	var findings []Finding
+	for _, cycle := range cycles {
+		var best trace.GoID
+		var bestBlocked time.Duration
+		for _, id := range cycle {
+			g := goroutines[id]
+			if !g.isTestOwned {
+				continue
+			}
+			blocked := time.Duration(lastTime-g.blockStart) * time.Nanosecond
+			if best == 0 || blocked > bestBlocked {
+				best, bestBlocked = id, blocked
+			}
+		}
+		if best == 0 {
+			continue
+		}
+
+		// Rotate so the representative comes first in the description.
+		for cycle[0] != best {
+			cycle = append(cycle[1:], cycle[0])
+		}
+		ids := make([]string, 0, len(cycle)+1)
+		for _, id := range cycle {
+			ids = append(ids, fmt.Sprintf("G%d", id))
+		}
+		ids = append(ids, ids[0])
+
+		g := goroutines[best]
+		findings = append(findings, Finding{
+			Kind:        KindDeadlock,
+			Confidence:  ConfidenceMedium,
+			GoroutineID: best,
+			BlockedOn:   fmt.Sprintf("%s (wait-for cycle: %s)", g.reason, strings.Join(ids, " → ")),
+			BlockedFor:  bestBlocked,
+			Stack:       g.stack,
+			Function:    g.function,
+			Location:    g.location,
+		})
+	}
+	return findings
+}
+
+// annotateWaitFor attaches "last woken by" evidence to every finding whose
+// goroutine is blocked, following the chain of expected wakers up to
+// maxEvidenceHops goroutines. A goroutine that was never woken is followed
+// to the last waker of its call site instead, and the evidence says so.
+func annotateWaitFor(findings []Finding, goroutines map[trace.GoID]*goroutineState, tombstones map[trace.GoID]lineage, wakes *wakeGraph) {
+	for i := range findings {
+		gid := findings[i].GoroutineID
+		if gid == 0 {
+			continue
+		}
+		seen := map[trace.GoID]bool{gid: true}
+		for hop := 0; hop < maxEvidenceHops; hop++ {
+			g := goroutines[gid]
+			if g == nil {
+				break
+			}
+			waker, inferred := wakerOf(g), false
+			if waker == 0 {
+				waker, inferred = wakes.siteWakerOf(gid, g), true
+			}
+			if waker == 0 {
+				break
+			}
+			w := goroutines[waker]
+			if _, exited := tombstones[waker]; exited {
+				w = &goroutineState{goroutineDead: true}
+			}
+			var line string
+			switch {
+			case inferred:
+				line = fmt.Sprintf("G%d was never woken; inferred from its call site %s: G%d, which last woke a goroutine blocked there, %s",
+					gid, g.location, waker, describeWaker(w))
+			case g.lastWakeLocation != g.location:
+				line = fmt.Sprintf("G%d (while blocked at %s) was last woken by G%d, which %s", gid, g.lastWakeLocation, waker, describeWaker(w))
+			default:
+				line = fmt.Sprintf("G%d was last woken by G%d, which %s", gid, waker, describeWaker(w))
+			}
+			findings[i].Evidence = append(findings[i].Evidence, line)
+			if seen[waker] {
+				break
+			}
+			seen[waker] = true
+			gid = waker
+		}
+	}
+}
+
+// describeWaker summarizes the state of a waker goroutine at trace end.
+func describeWaker(g *goroutineState) string {
+	switch {
+	case g == nil:
+		return "does not appear in the trace"
+	case g.goroutineDead:
+		return "has exited — nothing is left to unblock it"
+	case g.isBlocked:
+		return fmt.Sprintf("is itself blocked on %s at %s", g.reason, g.location)
+	default:
+		return "is still running and may yet unblock it"
+	}
+}
@@ -0,0 +1,87 @@
+package detector
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/exp/trace"
+)
+
+func TestWaitForCycleFixture(t *testing.T) {
+	result, err := Analyze("../../testdata/waitfor-cycle/trace.out", Options{MinBlock: time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cycles []Finding
+	for _, f := range result.Findings {
+		if f.Kind == KindDeadlock && strings.Contains(f.BlockedOn, "wait-for cycle") {
+			cycles = append(cycles, f)
+		}
+	}
+	if len(cycles) != 1 {
+		t.Fatalf("got %d wait-for cycle findings, want 1: %+v", len(cycles), result.Findings)
+	}
+	f := cycles[0]
+	if f.Count != 1 {
+		t.Errorf("Count = %d, want 1", f.Count)
+	}
+	if !strings.HasSuffix(f.Location, "cycle_test.go:37") {
+		t.Errorf("Location = %s, want the pinger's last receive at cycle_test.go:37", f.Location)
+	}
+	if f.Test != "TestWaitForCycle" {
+		t.Errorf("Test = %q, want TestWaitForCycle", f.Test)
+	}
+	if len(f.Evidence) == 0 {
+		t.Fatal("no wait-for evidence")
+	}
+	for _, e := range f.Evidence {
+		if !strings.Contains(e, "was last woken by") || strings.Contains(e, "inferred") {
+			t.Errorf("evidence %q does not come from the goroutine's own waker", e)
+		}
+	}
+}
+
+func TestWaitForEdgesUseOwnWaker(t *testing.T) {
+	const stack = "example.com/a.worker @ 0x1\n\t/src/a.go:1"
+	wakes := newWakeGraph()
+	goroutines := map[trace.GoID]*goroutineState{
+		// G1 was woken by G2 before; G3 was never woken, although G2 woke
+		// G1 at the call site G3 is blocked at.
+		1: {isBlocked: true, reason: "chan receive", stack: stack, location: "a.go:1", lastWaker: 2, lastWakeLocation: "a.go:1"},
+		2: {isBlocked: true, reason: "chan send", stack: stack, location: "a.go:2"},
+		3: {isBlocked: true, reason: "chan receive", stack: stack, location: "a.go:1"},
+	}
+	wakes.bySite["a.go:1"] = 2
+
+	edges := wakes.waitForEdges(goroutines)
+	if len(edges) != 1 || edges[1] != 2 {
+		t.Errorf("edges = %v, want only 1 → 2", edges)
+	}
+	if got := wakes.siteWakerOf(3, goroutines[3]); got != 2 {
+		t.Errorf("siteWakerOf(G3) = %d, want 2", got)
+	}
+}
+
+func TestDeduplicateCountsGoroutinesOnce(t *testing.T) {
+	findings := []Finding{
+		{Kind: KindDeadlock, GoroutineID: 7, Location: "a.go:1", BlockedOn: "sync"},
+		{Kind: KindDeadlock, GoroutineID: 7, Location: "a.go:1", BlockedOn: "sync (wait-for cycle: G7 → G8 → G7)", BlockedFor: time.Second},
+		{Kind: KindDeadlock, GoroutineID: 8, Location: "a.go:1", BlockedOn: "sync"},
+		{Kind: KindLockLeak, Location: "b.go:2"},
+		{Kind: KindLockLeak, Location: "b.go:2"},
+	}
+	got := deduplicateFindings(findings)
+	if len(got) != 2 {
+		t.Fatalf("got %d findings, want 2", len(got))
+	}
+	if got[0].Count != 2 {
+		t.Errorf("deadlock Count = %d, want 2 distinct goroutines", got[0].Count)
+	}
+	if !strings.Contains(got[0].BlockedOn, "wait-for cycle") {
+		t.Errorf("representative BlockedOn = %q, want the longest-blocked report", got[0].BlockedOn)
+	}
+	if got[1].Count != 2 {
+		t.Errorf("static Count = %d, want 2", got[1].Count)
+	}
+}
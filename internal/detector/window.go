@@ -0,0 +1,151 @@
+package detector
+
+import (
+	"fmt"
+	"time"
+
+	"golang.org/x/exp/trace"
+)
+
+// Window is the slice of a trace that was analyzed, as offsets from the
+// trace's first event. It is set on Result only when Options.From, To or
+// Region restricted the analysis.
+type Window struct {
+	From   time.Duration
+	To     time.Duration
+	Region string // user region name the window was taken from, if any
+}
+
+// window tracks the analysis window while events are read. Events outside
+// the window still update structural state (goroutine creation, parents,
+// test names, whether a goroutine is blocked), but only events inside it
+// count towards findings: block durations start no earlier than the window,
+// unblocks before it are not recorded, and "blocked at end" is evaluated at
+// the window's end.
+type window struct {
+	from, to time.Duration // offsets from traceStart; to == 0 means trace end
+	region   string
+
+	traceStart trace.Time
+	start, end trace.Time // absolute bounds; valid once open / closed
+	open       bool       // the window has started
+	closed     bool       // the window has ended; stop reading events
+
+	// regionG and regionDepth track the goroutine running the region and
+	// how deeply regions of the same name are nested on it.
+	regionG     trace.GoID
+	regionDepth int
+}
+
+func newWindow(opts Options) *window {
+	return &window{from: opts.From, to: opts.To, region: opts.Region}
+}
+
+// restricted reports whether the options restrict analysis to a window.
+func (w *window) restricted() bool {
+	return w.from > 0 || w.to > 0 || w.region != ""
+}
+
+// advance updates the window for ev, which must be passed in trace order.
+// It returns false once ev lies past the window's end; the event must then
+// not be processed. Otherwise it reports whether ev lies inside the window.
+func (w *window) advance(ev *trace.Event, first bool) (inWindow, ok bool) {
+	t := ev.Time()
+	if first {
+		w.traceStart = t
+	}
+	if w.closed {
+		return false, false
+	}
+
+	if w.region != "" {
+		return w.advanceRegion(ev)
+	}
+
+	if w.to > 0 && t > w.traceStart+trace.Time(w.to) {
+		w.closed = true
+		w.end = w.traceStart + trace.Time(w.to)
+		return false, false
+	}
+	if !w.open && t >= w.traceStart+trace.Time(w.from) {
+		w.open = true
+		w.start = w.traceStart + trace.Time(w.from)
+	}
+	return w.open, true
+}
+
+// advanceRegion opens the window at the first begin of the named region and
+// closes it at the matching end on the same goroutine.
+func (w *window) advanceRegion(ev *trace.Event) (inWindow, ok bool) {
+	switch ev.Kind() {
+	case trace.EventRegionBegin:
+		if ev.Region().Type != w.region {
+			break
+		}
+		if !w.open {
+			w.open = true
+			w.start = ev.Time()
+			w.regionG = ev.Goroutine()
+		}
+		if ev.Goroutine() == w.regionG {
+			w.regionDepth++
+		}
+	case trace.EventRegionEnd:
+		if !w.open || ev.Region().Type != w.region || ev.Goroutine() != w.regionG {
+			break
+		}
+		if w.regionDepth--; w.regionDepth == 0 {
+			// The end event itself is still part of the region.
+			w.closed = true
+			w.end = ev.Time()
+		}
+	}
+	return w.open, true
+}
+
+// enter is called with the first event inside the window. Goroutines that
+// exited before the window are reduced to tombstones: they still link the
+// goroutine tree together but are not analyzed. It returns their number.
+func (w *window) enter(goroutines map[trace.GoID]*goroutineState, tombstones map[trace.GoID]lineage, isRoot func(*goroutineState) bool) int {
+	for gid, g := range goroutines {
+		if g.goroutineDead {
+			tombstones[gid] = tombstoneOf(g, isRoot)
+			delete(goroutines, gid)
+		}
+	}
+	return len(tombstones)
+}
+
+// clamp returns t, or the window start if t lies before it.
+func (w *window) clamp(t trace.Time) trace.Time {
+	if w.open && t < w.start {
+		return w.start
+	}
+	return t
+}
+
+// bounds returns the window's start and end given the time of the last
+// event read, or an error if no part of the trace fell inside the window.
+func (w *window) bounds(lastTime trace.Time) (start, end trace.Time, err error) {
+	if !w.open {
+		if w.region != "" {
+			return 0, 0, fmt.Errorf("region %q not found in trace", w.region)
+		}
+		return 0, 0, fmt.Errorf("window start %v is past the end of the trace (%v)",
+			w.from, time.Duration(lastTime-w.traceStart).Round(time.Millisecond))
+	}
+	end = lastTime
+	if w.closed {
+		end = w.end
+	}
+	return w.start, end, nil
+}
+
+// result describes the window for Result.Window.
+func (w *window) result(start, end trace.Time) *Window {
+	return &Window{
+		From:   time.Duration(start - w.traceStart),
+		To:     time.Duration(end - w.traceStart),
+		Region: w.region,
+	}
+}
@@ -0,0 +1,92 @@
+package detector
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+const regionWindowTrace = "../../testdata/region-window/trace.out"
+
+// leakFunctions returns the function of every leak in result, with the
+// package path dropped, sorted.
+func leakFunctions(result *Result) []string {
+	var funcs []string
+	for _, f := range result.Findings {
+		if f.Kind == KindGoroutineLeak {
+			funcs = append(funcs, f.Function[strings.LastIndex(f.Function, ".TestRegionWindow")+1:])
+		}
+	}
+	sort.Strings(funcs)
+	return funcs
+}
+
+// checkClamped reports findings blocked for longer than the window, and
+// returns how long early (func1), blocked since before the window, waited.
+func checkClamped(t *testing.T, result *Result) time.Duration {
+	t.Helper()
+	span := result.Window.To - result.Window.From
+	var early time.Duration
+	for _, f := range result.Findings {
+		if f.BlockedFor > span {
+			t.Errorf("%s blocked for %v, longer than the %v window", f.Function, f.BlockedFor, span)
+		}
+		if strings.HasSuffix(f.Function, ".TestRegionWindow.func1") {
+			early = f.BlockedFor
+		}
+	}
+	return early
+}
+
+func TestWindowRegion(t *testing.T) {
+	result, err := Analyze(regionWindowTrace, Options{Region: "phase"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := result.Window
+	if w == nil || w.Region != "phase" {
+		t.Fatalf("Window = %+v, want the phase region", w)
+	}
+	// The inner region ends after 20ms; the outer one lasts over 100ms.
+	if span := w.To - w.From; span < 100*time.Millisecond || span > 200*time.Millisecond {
+		t.Errorf("window spans %v (%v to %v), want the outer region of ~120ms", span, w.From, w.To)
+	}
+	if got, want := strings.Join(leakFunctions(result), " "), "TestRegionWindow.func1 TestRegionWindow.func2.2"; got != want {
+		t.Errorf("leaks in %s, want %s", got, want)
+	}
+	if early := checkClamped(t, result); early != w.To-w.From {
+		t.Errorf("early blocked for %v, want the whole window %v", early, w.To-w.From)
+	}
+}
+
+func TestWindowFromTo(t *testing.T) {
+	result, err := Analyze(regionWindowTrace, Options{From: 60 * time.Millisecond, To: 150 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := result.Window; w == nil || w.From != 60*time.Millisecond || w.To != 150*time.Millisecond || w.Region != "" {
+		t.Fatalf("Window = %+v, want 60ms to 150ms", w)
+	}
+	if result.DurationMs != 90 {
+		t.Errorf("DurationMs = %d, want 90", result.DurationMs)
+	}
+	if early := checkClamped(t, result); early != 90*time.Millisecond {
+		t.Errorf("early blocked for %v, want the whole 90ms window", early)
+	}
+}
+
+func TestWindowErrors(t *testing.T) {
+	for _, tc := range []struct {
+		opts Options
+		err  string
+	}{
+		{Options{Region: "missing"}, `region "missing" not found in trace`},
+		{Options{From: 10 * time.Second}, "window start 10s is past the end of the trace"},
+	} {
+		_, err := Analyze(regionWindowTrace, tc.opts)
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Analyze(%+v) error = %v, want %q", tc.opts, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,291 @@
+package graph
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/exp/trace"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/static"
+)
+
+// Spawn returns the goroutine provenance tree of tl: an edge from each
+// goroutine to the goroutines it created. Goroutines with findings are
+// highlighted, along with the spawn edges leading to them; goroutines that
+// exited are dimmed.
+//
+// Sibling goroutines with identical subtrees — same entry point, state and
+// descendants — are drawn as one node with a count, so that a worker pool of
+// a thousand goroutines is one node. With flaggedOnly, only goroutines with
+// findings and their ancestors are drawn.
+func Spawn(tl *detector.Timeline, flaggedOnly bool) *Graph {
+	s := &spawnTree{
+		tl:       tl,
+		children: make(map[trace.GoID][]int),
+		sigs:     make(map[string]int),
+		sig:      make([]int, len(tl.Goroutines)),
+		flagged:  make([]bool, len(tl.Goroutines)),
+	}
+	index := make(map[trace.GoID]bool, len(tl.Goroutines))
+	for _, g := range tl.Goroutines {
+		index[g.ID] = true
+	}
+	var roots []int
+	for i, g := range tl.Goroutines {
+		if g.ParentID != 0 && g.ParentID != g.ID && index[g.ParentID] {
+			s.children[g.ParentID] = append(s.children[g.ParentID], i)
+		} else {
+			roots = append(roots, i)
+		}
+	}
+	for _, i := range roots {
+		s.signature(i)
+	}
+
+	g := &Graph{TopDown: true}
+	for _, members := range s.group(roots, flaggedOnly) {
+		s.emit(g, "", members, flaggedOnly)
+	}
+	return g
+}
+
+type spawnTree struct {
+	tl       *detector.Timeline
+	children map[trace.GoID][]int // indexes into tl.Goroutines, by parent
+	sigs     map[string]int       // interned subtree signatures
+	sig      []int                // subtree signature of each goroutine
+	flagged  []bool               // whether each subtree has a finding
+}
+
+// signature computes the subtree signatures and flags of goroutine i and its
+// descendants. Two goroutines with the same signature draw the same.
+func (s *spawnTree) signature(i int) int {
+	g := &s.tl.Goroutines[i]
+	s.flagged[i] = len(g.Kinds) > 0
+	var sub []string
+	for _, c := range s.children[g.ID] {
+		sub = append(sub, strconv.Itoa(s.signature(c)))
+		s.flagged[i] = s.flagged[i] || s.flagged[c]
+	}
+	slices.Sort(sub)
+	key := fmt.Sprintf("%s\x00%s\x00%v\x00%t\x00%s", s.name(g), g.Location, g.Kinds, g.Exited >= 0, strings.Join(sub, ","))
+	id, ok := s.sigs[key]
+	if !ok {
+		id = len(s.sigs)
+		s.sigs[key] = id
+	}
+	s.sig[i] = id
+	return id
+}
+
+// group partitions goroutines by signature, in order of first appearance.
+func (s *spawnTree) group(gs []int, flaggedOnly bool) [][]int {
+	var groups [][]int
+	at := make(map[int]int)
+	for _, i := range gs {
+		if flaggedOnly && !s.flagged[i] {
+			continue
+		}
+		j, ok := at[s.sig[i]]
+		if !ok {
+			j = len(groups)
+			at[s.sig[i]] = j
+			groups = append(groups, nil)
+		}
+		groups[j] = append(groups[j], i)
+	}
+	return groups
+}
+
+// emit adds the node of members, goroutines with the same signature, and
+// the subtrees of their children, linked from parent.
+func (s *spawnTree) emit(g *Graph, parent string, members []int, flaggedOnly bool) {
+	first := &s.tl.Goroutines[members[0]]
+	id := fmt.Sprintf("G%d", first.ID)
+
+	label := fmt.Sprintf("G%d %s", first.ID, s.name(first))
+	if len(members) > 1 {
+		label = fmt.Sprintf("%s ×%d", s.name(first), len(members))
+	}
+	if first.Location != "" {
+		label += "\n" + filepath.Base(first.Location)
+	}
+	style := Normal
+	if len(first.Kinds) > 0 {
+		kinds := make([]string, len(first.Kinds))
+		for i, k := range first.Kinds {
+			kinds[i] = string(k)
+		}
+		label += "\n" + strings.Join(kinds, ", ")
+		style = Highlight
+	} else if first.Exited >= 0 {
+		style = Dim
+	}
+	g.Nodes = append(g.Nodes, Node{ID: id, Label: label, Style: style})
+	if parent != "" {
+		e := Edge{From: parent, To: id}
+		if s.flagged[members[0]] {
+			e.Style = Highlight
+		}
+		g.Edges = append(g.Edges, e)
+	}
+
+	var children []int
+	for _, m := range members {
+		children = append(children, s.children[s.tl.Goroutines[m].ID]...)
+	}
+	slices.Sort(children)
+	for _, cs := range s.group(children, flaggedOnly) {
+		s.emit(g, id, cs, flaggedOnly)
+	}
+}
+
+// name is the display name of a goroutine: its test for a test's own
+// goroutine, otherwise its entry function without the import path.
+func (s *spawnTree) name(g *detector.GoroutineTimeline) string {
+	if g.Test != "" && (g.Function == "testing.tRunner" || g.Function == "testing.(*B).runN") {
+		return g.Test
+	}
+	if g.Function == "" {
+		return "?"
+	}
+	return shortFunc(g.Function)
+}
+
+// LockWait returns the runtime lock-wait graph lw: lock call sites, with an
+// edge from the site where a goroutine last acquired a lock to the site it
+// is blocked at, labeled with the goroutines. Lock cycles — the deadlocks
+// the lock_cycles detector reports — are highlighted and boxed.
+func LockWait(lw *detector.LockWaitGraph) *Graph {
+	g := &Graph{}
+	inCycle := cycleGroups(g, lw.Cycles)
+
+	functions := make(map[string]string)
+	sites := make(map[string]bool)
+	var order []string
+	addSite := func(site string) {
+		if !sites[site] {
+			sites[site] = true
+			order = append(order, site)
+		}
+	}
+	for _, e := range lw.Edges {
+		addSite(e.From)
+		addSite(e.To)
+		if e.Function != "" {
+			functions[e.To] = shortFunc(e.Function)
+		}
+	}
+	for _, c := range lw.Cycles {
+		for _, site := range c {
+			addSite(site)
+		}
+	}
+	slices.Sort(order)
+	for _, site := range order {
+		label := filepath.Base(site)
+		if fn := functions[site]; fn != "" {
+			label += "\n" + fn
+		}
+		n := Node{ID: site, Label: label}
+		if _, ok := inCycle[site]; ok {
+			n.Style = Highlight
+		}
+		g.Nodes = append(g.Nodes, n)
+	}
+
+	// One edge per pair of sites, listing the goroutines waiting along it.
+	for i := 0; i < len(lw.Edges); {
+		j := i
+		var gids []string
+		for ; j < len(lw.Edges) && lw.Edges[j].From == lw.Edges[i].From && lw.Edges[j].To == lw.Edges[i].To; j++ {
+			gids = append(gids, fmt.Sprintf("G%d", lw.Edges[j].Goroutine))
+		}
+		if len(gids) > 3 {
+			gids = append(gids[:3], fmt.Sprintf("+%d", len(gids)-3))
+		}
+		g.Edges = append(g.Edges, cycleEdge(inCycle, lw.Edges[i].From, lw.Edges[i].To, strings.Join(gids, ", ")))
+		i = j
+	}
+	return g
+}
+
+// LockOrder returns the static lock-ordering graph lg: type-level lock IDs,
+// with an edge from each lock to the locks acquired while holding it,
+// labeled with where. Lock-ordering cycles, reported as lock_order
+// findings, are highlighted and boxed.
+func LockOrder(lg *static.LockGraph) *Graph {
+	g := &Graph{}
+	inCycle := cycleGroups(g, lg.Cycles)
+
+	locks := make(map[string]bool)
+	var order []string
+	for _, e := range lg.Edges {
+		for _, l := range []string{e.From, e.To} {
+			if !locks[l] {
+				locks[l] = true
+				order = append(order, l)
+			}
+		}
+	}
+	slices.Sort(order)
+	for _, l := range order {
+		n := Node{ID: l, Label: l}
+		if _, ok := inCycle[l]; ok {
+			n.Style = Highlight
+		}
+		g.Nodes = append(g.Nodes, n)
+	}
+	for _, e := range lg.Edges {
+		var where []string
+		if e.Function != "" {
+			where = append(where, shortFunc(e.Function))
+		}
+		if e.Location != "" {
+			where = append(where, filepath.Base(e.Location))
+		}
+		g.Edges = append(g.Edges, cycleEdge(inCycle, e.From, e.To, strings.Join(where, "\n")))
+	}
+	return g
+}
+
+// cycleGroups adds a group to g for each cycle and returns the index of the
+// cycle of each node in one.
+func cycleGroups(g *Graph, cycles [][]string) map[string]int {
+	inCycle := make(map[string]int)
+	for i, c := range cycles {
+		desc := "AB-BA lock inversion"
+		if len(c) > 2 {
+			desc = fmt.Sprintf("%d-way lock cycle", len(c))
+		}
+		g.Groups = append(g.Groups, Group{Label: desc, Nodes: c})
+		for _, n := range c {
+			inCycle[n] = i
+		}
+	}
+	return inCycle
+}
+
+// cycleEdge returns an edge, highlighted if both ends are in the same cycle.
+func cycleEdge(inCycle map[string]int, from, to, label string) Edge {
+	e := Edge{From: from, To: to, Label: label}
+	ci, ok1 := inCycle[from]
+	cj, ok2 := inCycle[to]
+	if ok1 && ok2 && ci == cj {
+		e.Style = Highlight
+	}
+	return e
+}
+
+// shortFunc strips the import path from a qualified function name:
+// "github.com/org/pkg.(*T).Run" becomes "pkg.(*T).Run".
+func shortFunc(fn string) string {
+	if slash := strings.LastIndex(fn, "/"); slash >= 0 {
+		return fn[slash+1:]
+	}
+	return fn
+}
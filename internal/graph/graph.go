@@ -0,0 +1,204 @@
+// Package graph renders the structures behind ThreadGraph's findings — the
+// goroutine spawn tree, the runtime lock-wait graph and the static
+// lock-ordering graph — as Graphviz DOT or Mermaid flowcharts, for
+// `threadgraph graph`.
+package graph
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Style is how a node or edge is drawn.
+type Style int
+
+const (
+	Normal    Style = iota
+	Highlight       // part of a finding: a leaked goroutine, a lock cycle
+	Dim             // no longer relevant: an exited goroutine
+)
+
+// Graph is a directed graph ready to be rendered.
+type Graph struct {
+	// TopDown lays the graph out top to bottom (trees) instead of left to
+	// right.
+	TopDown bool
+	Nodes   []Node
+	Edges   []Edge
+	// Groups are drawn as boxes around their nodes, such as the nodes of a
+	// lock cycle.
+	Groups []Group
+}
+
+// Node is a node of a Graph. Label lines are separated by "\n".
+type Node struct {
+	ID    string
+	Label string
+	Style Style
+}
+
+// Edge is an edge of a Graph between node IDs.
+type Edge struct {
+	From  string
+	To    string
+	Label string
+	Style Style
+}
+
+// Group is a labeled set of nodes; a node belongs to at most one group.
+type Group struct {
+	Label string
+	Nodes []string
+}
+
+const (
+	highlightColor = "#d9363e"
+	highlightFill  = "#f8d7da"
+	dimColor       = "#9a9a9a"
+)
+
+// WriteDOT writes g in the Graphviz DOT language.
+func WriteDOT(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+	rankdir := "LR"
+	if g.TopDown {
+		rankdir = "TB"
+	}
+	fmt.Fprintln(bw, "digraph threadgraph {")
+	fmt.Fprintf(bw, "  rankdir=%s;\n", rankdir)
+	fmt.Fprintln(bw, `  node [shape=box, style="rounded", fontname="Helvetica", fontsize=10];`)
+	fmt.Fprintln(bw, `  edge [fontname="Helvetica", fontsize=9];`)
+
+	grouped := make(map[string]bool)
+	for i, grp := range g.Groups {
+		fmt.Fprintf(bw, "  subgraph cluster_%d {\n", i)
+		fmt.Fprintf(bw, "    label=%s;\n    color=%q;\n    fontcolor=%q;\n", dotQuote(grp.Label), highlightColor, highlightColor)
+		for _, id := range grp.Nodes {
+			grouped[id] = true
+			for _, n := range g.Nodes {
+				if n.ID == id {
+					writeDOTNode(bw, "    ", n)
+				}
+			}
+		}
+		fmt.Fprintln(bw, "  }")
+	}
+	for _, n := range g.Nodes {
+		if !grouped[n.ID] {
+			writeDOTNode(bw, "  ", n)
+		}
+	}
+	for _, e := range g.Edges {
+		attrs := []string{}
+		if e.Label != "" {
+			attrs = append(attrs, "label="+dotQuote(e.Label))
+		}
+		switch e.Style {
+		case Highlight:
+			attrs = append(attrs, fmt.Sprintf("color=%q", highlightColor), "penwidth=2")
+		case Dim:
+			attrs = append(attrs, fmt.Sprintf("color=%q", dimColor), "style=dashed")
+		}
+		fmt.Fprintf(bw, "  %s -> %s", dotQuote(e.From), dotQuote(e.To))
+		if len(attrs) > 0 {
+			fmt.Fprintf(bw, " [%s]", strings.Join(attrs, ", "))
+		}
+		fmt.Fprintln(bw, ";")
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func writeDOTNode(w io.Writer, indent string, n Node) {
+	attrs := []string{"label=" + dotQuote(n.Label)}
+	switch n.Style {
+	case Highlight:
+		attrs = append(attrs, `style="rounded,filled"`, fmt.Sprintf("fillcolor=%q", highlightFill), fmt.Sprintf("color=%q", highlightColor))
+	case Dim:
+		attrs = append(attrs, `style="rounded,dashed"`, fmt.Sprintf("color=%q", dimColor), fmt.Sprintf("fontcolor=%q", dimColor))
+	}
+	fmt.Fprintf(w, "%s%s [%s];\n", indent, dotQuote(n.ID), strings.Join(attrs, ", "))
+}
+
+// dotQuote quotes s as a DOT string; "\n" becomes a line break.
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + strings.ReplaceAll(s, "\n", `\n`) + `"`
+}
+
+// WriteMermaid writes g as a Mermaid flowchart, for Markdown that renders
+// Mermaid code blocks (GitHub, GitLab). Node IDs are replaced by n0, n1, ...
+// since Mermaid IDs cannot contain most punctuation.
+func WriteMermaid(w io.Writer, g *Graph) error {
+	bw := bufio.NewWriter(w)
+	dir := "LR"
+	if g.TopDown {
+		dir = "TD"
+	}
+	fmt.Fprintf(bw, "flowchart %s\n", dir)
+	fmt.Fprintf(bw, "  classDef highlight fill:%s,stroke:%s,color:#000\n", highlightFill, highlightColor)
+	fmt.Fprintf(bw, "  classDef dim stroke:%s,stroke-dasharray:4 3,color:%s\n", dimColor, dimColor)
+
+	ids := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		ids[n.ID] = fmt.Sprintf("n%d", i)
+	}
+	grouped := make(map[string]bool)
+	for i, grp := range g.Groups {
+		fmt.Fprintf(bw, "  subgraph g%d[%s]\n", i, mermaidQuote(grp.Label))
+		for _, id := range grp.Nodes {
+			grouped[id] = true
+			for _, n := range g.Nodes {
+				if n.ID == id {
+					fmt.Fprintf(bw, "    %s[%s]\n", ids[n.ID], mermaidQuote(n.Label))
+				}
+			}
+		}
+		fmt.Fprintln(bw, "  end")
+		fmt.Fprintf(bw, "  style g%d stroke:%s\n", i, highlightColor)
+	}
+	for _, n := range g.Nodes {
+		if !grouped[n.ID] {
+			fmt.Fprintf(bw, "  %s[%s]\n", ids[n.ID], mermaidQuote(n.Label))
+		}
+	}
+	for _, n := range g.Nodes {
+		switch n.Style {
+		case Highlight:
+			fmt.Fprintf(bw, "  class %s highlight\n", ids[n.ID])
+		case Dim:
+			fmt.Fprintf(bw, "  class %s dim\n", ids[n.ID])
+		}
+	}
+	for i, e := range g.Edges {
+		if e.Label != "" {
+			fmt.Fprintf(bw, "  %s -->|%s| %s\n", ids[e.From], mermaidQuote(e.Label), ids[e.To])
+		} else {
+			fmt.Fprintf(bw, "  %s --> %s\n", ids[e.From], ids[e.To])
+		}
+		switch e.Style {
+		case Highlight:
+			fmt.Fprintf(bw, "  linkStyle %d stroke:%s,stroke-width:2px\n", i, highlightColor)
+		case Dim:
+			fmt.Fprintf(bw, "  linkStyle %d stroke:%s,stroke-dasharray:4 3\n", i, dimColor)
+		}
+	}
+	return bw.Flush()
+}
+
+// mermaidQuote quotes s as a Mermaid label. Quotes and the characters
+// Mermaid treats as markup are written as entity codes; "\n" becomes a
+// line break.
+func mermaidQuote(s string) string {
+	s = strings.NewReplacer(
+		`"`, "#quot;",
+		"<", "#lt;",
+		">", "#gt;",
+		"|", "#124;",
+		"\n", "<br>",
+	).Replace(s)
+	return `"` + s + `"`
+}
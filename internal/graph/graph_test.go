@@ -0,0 +1,128 @@
+package graph
+
+import (
+	"bytes"
+	"flag"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/static"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares the output of write for g with testdata/name.
+func checkGolden(t *testing.T, name string, g *Graph, write func(io.Writer, *Graph) error) {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := write(&buf, g); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := buf.String(); got != string(want) {
+		t.Errorf("%s differs (rerun with -update to accept):\n%s", path, got)
+	}
+}
+
+// spawnTimeline is the timeline of a trace with a test that leaks one
+// goroutine and three identical ones.
+func spawnTimeline(t *testing.T) *detector.Timeline {
+	result, err := detector.Analyze("../../testdata/timeline-spans/trace.out", detector.Options{Timeline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result.Timeline
+}
+
+func TestSpawn(t *testing.T) {
+	tl := spawnTimeline(t)
+	g := Spawn(tl, false)
+	checkGolden(t, "spawn.dot", g, WriteDOT)
+	checkGolden(t, "spawn.mmd", g, WriteMermaid)
+
+	// The three waiters are one node, named after the first of them.
+	var waiters []string
+	for _, n := range g.Nodes {
+		if strings.Contains(n.Label, "TestTimelineSpans.func2") {
+			waiters = append(waiters, n.Label)
+		}
+	}
+	if len(waiters) != 1 || !strings.Contains(waiters[0], " ×3\n") {
+		t.Errorf("waiter nodes %q, want one with ×3", waiters)
+	}
+}
+
+func TestSpawnFlaggedOnly(t *testing.T) {
+	g := Spawn(spawnTimeline(t), true)
+	checkGolden(t, "spawn-flagged.dot", g, WriteDOT)
+
+	// Only the leaks and their ancestors are left.
+	for _, n := range g.Nodes {
+		if strings.Contains(n.Label, "runtime.") {
+			t.Errorf("unflagged node %q drawn", n.Label)
+		}
+	}
+	if len(g.Nodes) != 4 || len(g.Edges) != 3 {
+		t.Errorf("got %d nodes and %d edges, want main → test → two leak nodes", len(g.Nodes), len(g.Edges))
+	}
+}
+
+func TestLockOrder(t *testing.T) {
+	g := LockOrder(&static.LockGraph{
+		Edges: []static.LockEdge{
+			{From: "app.Account.mu", To: "app.Ledger.mu", Function: "example.com/app.(*Account).Post", Location: "/src/app/account.go:40"},
+			{From: "app.Cache.mu", To: "app.Store.mu", Function: "example.com/app.(*Cache).Fill", Location: "/src/app/cache.go:12"},
+			{From: "app.Ledger.mu", To: "app.Account.mu", Function: "example.com/app.(*Ledger).Audit", Location: "/src/app/ledger.go:77"},
+			{From: "app.Queue.mu", To: "app.Cache.mu", Function: "example.com/app.(*Queue).Drain", Location: "/src/app/queue.go:51"},
+			{From: "app.Store.mu", To: "app.Log.mu", Location: "/src/app/store.go:30"},
+			{From: "app.Store.mu", To: "app.Queue.mu", Function: "example.com/app.(*Store).Evict", Location: "/src/app/store.go:9"},
+		},
+		Cycles: [][]string{{"app.Account.mu", "app.Ledger.mu"}, {"app.Cache.mu", "app.Queue.mu", "app.Store.mu"}},
+	})
+	checkGolden(t, "lockorder.dot", g, WriteDOT)
+	checkGolden(t, "lockorder.mmd", g, WriteMermaid)
+}
+
+func TestLockWait(t *testing.T) {
+	g := LockWait(&detector.LockWaitGraph{
+		Edges: []detector.LockWaitEdge{
+			{From: "/src/app/a.go:10", To: "/src/app/b.go:20", Goroutine: 7, Function: "example.com/app.transfer"},
+			{From: "/src/app/b.go:20", To: "/src/app/a.go:10", Goroutine: 8, Function: "example.com/app.refund"},
+			{From: "/src/app/c.go:5", To: "/src/app/a.go:10", Goroutine: 21, Function: "example.com/app.refund"},
+			{From: "/src/app/c.go:5", To: "/src/app/a.go:10", Goroutine: 22, Function: "example.com/app.refund"},
+			{From: "/src/app/c.go:5", To: "/src/app/a.go:10", Goroutine: 23, Function: "example.com/app.refund"},
+			{From: "/src/app/c.go:5", To: "/src/app/a.go:10", Goroutine: 24, Function: "example.com/app.refund"},
+			{From: "/src/app/c.go:5", To: "/src/app/a.go:10", Goroutine: 25, Function: "example.com/app.refund"},
+		},
+		Cycles: [][]string{{"/src/app/a.go:10", "/src/app/b.go:20"}},
+	})
+	checkGolden(t, "lockwait.dot", g, WriteDOT)
+	checkGolden(t, "lockwait.mmd", g, WriteMermaid)
+}
+
+func TestQuoting(t *testing.T) {
+	g := &Graph{
+		Nodes: []Node{
+			{ID: `a"1`, Label: `say "hi"` + "\n" + `C:\tmp`},
+			{ID: "b|2", Label: "chan<- int | <-chan int"},
+		},
+		Edges:  []Edge{{From: `a"1`, To: "b|2", Label: "x > y", Style: Dim}},
+		Groups: []Group{{Label: `"quoted" <group>`, Nodes: []string{"b|2"}}},
+	}
+	checkGolden(t, "quoting.dot", g, WriteDOT)
+	checkGolden(t, "quoting.mmd", g, WriteMermaid)
+}
@@ -0,0 +1,95 @@
+// Package live collects goroutine dumps and execution traces from a running
+// service through its net/http/pprof endpoints.
+package live
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client fetches profiles from a service that registers net/http/pprof
+// handlers under /debug/pprof/.
+type Client struct {
+	BaseURL string
+	HTTP    *http.Client
+}
+
+// NewClient returns a Client for the service at baseURL
+// (e.g. "http://localhost:6060"). A missing scheme defaults to http.
+func NewClient(baseURL string) *Client {
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "http://" + baseURL
+	}
+	return &Client{
+		BaseURL: strings.TrimRight(baseURL, "/"),
+		HTTP:    &http.Client{},
+	}
+}
+
+// GoroutineDump returns the text of /debug/pprof/goroutine?debug=2: a full
+// dump of every goroutine in the format the runtime prints on a crash.
+func (c *Client) GoroutineDump(ctx context.Context) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	body, err := c.get(ctx, "/debug/pprof/goroutine?debug=2")
+	if err != nil {
+		return "", err
+	}
+	defer body.Close()
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("read goroutine dump: %w", err)
+	}
+	return string(data), nil
+}
+
+// Trace records an execution trace of the service for d via
+// /debug/pprof/trace?seconds=N and writes it to path.
+func (c *Client) Trace(ctx context.Context, d time.Duration, path string) error {
+	// The handler blocks for d before it starts writing.
+	ctx, cancel := context.WithTimeout(ctx, d+30*time.Second)
+	defer cancel()
+
+	seconds := strconv.FormatFloat(d.Seconds(), 'f', -1, 64)
+	body, err := c.get(ctx, "/debug/pprof/trace?seconds="+seconds)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("create trace file: %w", err)
+	}
+	if _, err := io.Copy(f, body); err != nil {
+		f.Close()
+		return fmt.Errorf("download trace: %w", err)
+	}
+	return f.Close()
+}
+
+// get issues a GET request for path and returns the body of a 200 response.
+func (c *Client) get(ctx context.Context, path string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("http: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		resp.Body.Close()
+		return nil, fmt.Errorf("GET %s returned %d: %s", path, resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp.Body, nil
+}
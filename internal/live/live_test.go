@@ -0,0 +1,94 @@
+package live
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewClient(t *testing.T) {
+	for _, tc := range []struct{ in, want string }{
+		{"localhost:6060", "http://localhost:6060"},
+		{"http://localhost:6060/", "http://localhost:6060"},
+		{"https://10.0.0.5:6060", "https://10.0.0.5:6060"},
+	} {
+		if got := NewClient(tc.in).BaseURL; got != tc.want {
+			t.Errorf("NewClient(%q).BaseURL = %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestGoroutineDump(t *testing.T) {
+	const dump = "goroutine 1 [running]:\nmain.main()\n\t/app/main.go:10 +0x1d\n"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/debug/pprof/goroutine" || r.URL.Query().Get("debug") != "2" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(dump))
+	}))
+	defer srv.Close()
+
+	got, err := NewClient(srv.URL).GoroutineDump(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != dump {
+		t.Errorf("dump = %q, want %q", got, dump)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var seconds string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/debug/pprof/trace" {
+			http.NotFound(w, r)
+			return
+		}
+		seconds = r.URL.Query().Get("seconds")
+		w.Write([]byte("go 1.25 trace\x00"))
+	}))
+	defer srv.Close()
+
+	path := filepath.Join(t.TempDir(), "trace.out")
+	if err := NewClient(srv.URL).Trace(context.Background(), 1500*time.Millisecond, path); err != nil {
+		t.Fatal(err)
+	}
+	if seconds != "1.5" {
+		t.Errorf("seconds = %q, want 1.5", seconds)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "go 1.25 trace\x00" {
+		t.Errorf("trace file = %q", data)
+	}
+}
+
+func TestErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "profiling disabled", http.StatusForbidden)
+	}))
+	defer srv.Close()
+	c := NewClient(srv.URL)
+
+	_, err := c.GoroutineDump(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "returned 403: profiling disabled") {
+		t.Errorf("GoroutineDump error = %v, want the status and message", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "trace.out")
+	err = c.Trace(context.Background(), time.Second, path)
+	if err == nil || !strings.Contains(err.Error(), "returned 403") {
+		t.Errorf("Trace error = %v, want the status", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Trace created %s for a failed request", path)
+	}
+}
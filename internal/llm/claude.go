@@ -85,6 +85,14 @@ func buildPrompt(findings []detector.Finding) string {
 		if f.Function != "" {
 			sb.WriteString(fmt.Sprintf("  Function: %s\n", f.Function))
 		}
+		if len(f.Tests) > 1 {
+			sb.WriteString(fmt.Sprintf("  Tests: %s\n", strings.Join(f.Tests, ", ")))
+		} else if f.Test != "" {
+			sb.WriteString(fmt.Sprintf("  Test: %s\n", f.Test))
+		}
+		for _, e := range f.Evidence {
+			sb.WriteString(fmt.Sprintf("  Evidence: %s\n", e))
+		}
 		if f.Stack != "" {
 			sb.WriteString(fmt.Sprintf("  Stack trace:\n%s", f.Stack))
 		}
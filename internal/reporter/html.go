@@ -0,0 +1,245 @@
+package reporter
+
+import (
+	"cmp"
+	_ "embed"
+	"html/template"
+	"io"
+	"slices"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+//go:embed html.tmpl
+var htmlTemplate string
+
+var htmlReport = template.Must(template.New("report").Parse(htmlTemplate))
+
+// htmlMaxGoroutines bounds the goroutines of a timeline embedded in the
+// page. Goroutines with findings are always embedded; of the others, those
+// with the lowest IDs.
+const htmlMaxGoroutines = 5000
+
+// htmlData is the report's data, embedded in the page as JSON. Strings that
+// repeat across spans (stacks, block reasons) are interned in Strings and
+// referred to by index; index 0 is "".
+type htmlData struct {
+	Title       string        `json:"title"`
+	DurationMs  int64         `json:"duration_ms"`
+	Goroutines  int           `json:"goroutines_analyzed"`
+	Findings    []htmlFinding `json:"findings"`
+	Patterns    []htmlPattern `json:"patterns,omitempty"`
+	Packages    []htmlPackage `json:"packages"`
+	Strings     []string      `json:"strings"`
+	Suppressed  int           `json:"suppressed"`
+	Resolved    int           `json:"resolved"`
+	Explanation string        `json:"explanation,omitempty"`
+	interned    map[string]int
+}
+
+type htmlFinding struct {
+	jsonFinding
+	// Status is "new", or "known" for findings a --baseline knew about, or
+	// "flaky" for findings seen in too few --repeat runs.
+	Status string `json:"status"`
+	// Timeline is the index in Packages of the timeline holding the
+	// finding's goroutine, or -1 if none does.
+	Timeline int `json:"timeline"`
+}
+
+// htmlPattern is a node of the pattern tree (see patternTree). Findings are
+// indexes into htmlData.Findings.
+type htmlPattern struct {
+	Site       string        `json:"site"`
+	Title      string        `json:"title"`
+	Goroutines int           `json:"goroutines"`
+	Findings   []int         `json:"findings,omitempty"`
+	Children   []htmlPattern `json:"children,omitempty"`
+}
+
+type htmlPackage struct {
+	Name       string          `json:"name"`
+	DurationNs int64           `json:"duration_ns"`
+	Goroutines []htmlGoroutine `json:"goroutines"`
+	// Omitted is the number of goroutines left out for htmlMaxGoroutines.
+	Omitted int `json:"omitted,omitempty"`
+}
+
+type htmlGoroutine struct {
+	ID            uint64   `json:"id"`
+	Parent        uint64   `json:"parent,omitempty"`
+	Test          string   `json:"test,omitempty"`
+	Function      string   `json:"function,omitempty"`
+	Location      string   `json:"location,omitempty"`
+	CreatedBy     string   `json:"created_by,omitempty"`
+	CreatedAt     string   `json:"created_at,omitempty"`
+	CreationStack int      `json:"creation_stack,omitempty"`
+	Created       int64    `json:"created"`
+	Exited        int64    `json:"exited"`
+	Dropped       int      `json:"dropped,omitempty"`
+	Kinds         []string `json:"kinds,omitempty"`
+	// Spans are [state, start ns, end ns, reason, stack], with state an
+	// index into htmlSpanStates and reason and stack into htmlData.Strings.
+	Spans [][5]int64 `json:"spans"`
+}
+
+var htmlSpanStates = []detector.SpanState{
+	detector.SpanRunnable, detector.SpanRunning, detector.SpanBlocked, detector.SpanSyscall,
+}
+
+// WriteHTML writes result as a self-contained HTML page: the findings, and
+// for results analyzed with Options.Timeline, a swimlane timeline of every
+// goroutine's states. Goroutines with findings are highlighted; selecting a
+// finding or a goroutine shows its lifecycle with its creation and blocking
+// stacks.
+func WriteHTML(w io.Writer, result *detector.Result, explanation string) error {
+	data := &htmlData{
+		Title:       result.TraceFile,
+		DurationMs:  result.DurationMs,
+		Goroutines:  result.GoroutinesAnalyzed,
+		Findings:    []htmlFinding{},
+		Packages:    []htmlPackage{},
+		Strings:     []string{""},
+		Explanation: explanation,
+		interned:    map[string]int{"": 0},
+	}
+	if result.Package != "" {
+		data.Title = result.Package
+	}
+
+	var timelines []*detector.Timeline
+	if len(result.Packages) > 0 {
+		data.Title = pluralize(len(result.Packages), "package")
+		for _, p := range result.Packages {
+			if p.Timeline != nil {
+				data.Packages = append(data.Packages, data.pkg(p.Package, p.Timeline))
+				timelines = append(timelines, p.Timeline)
+			}
+		}
+	} else if result.Timeline != nil {
+		data.Packages = append(data.Packages, data.pkg(data.Title, result.Timeline))
+		timelines = append(timelines, result.Timeline)
+	}
+
+	add := func(f detector.Finding, status string) {
+		hf := htmlFinding{jsonFinding: toJSONFinding(f), Status: status, Timeline: -1}
+		for i, tl := range timelines {
+			if len(result.Packages) > 0 && data.Packages[i].Name != f.Package {
+				continue
+			}
+			if hasGoroutine(tl, f) {
+				hf.Timeline = i
+			}
+		}
+		data.Findings = append(data.Findings, hf)
+	}
+	for _, f := range result.Findings {
+		add(f, "new")
+	}
+	if result.Baseline != nil {
+		for _, f := range result.Baseline.Persisting {
+			add(f, "known")
+		}
+		data.Resolved = len(result.Baseline.Resolved)
+	}
+	if result.Repeat != nil {
+		for _, f := range result.Repeat.Flaky {
+			add(f, "flaky")
+		}
+	}
+	data.Suppressed = len(result.Suppressed)
+
+	if patterns := detector.Patterns(result.Findings); showPatterns(patterns) {
+		index := make(map[findingKey]int, len(result.Findings))
+		for i, f := range result.Findings {
+			index[keyOf(f)] = i
+		}
+		var convert func(nodes []*patternNode) []htmlPattern
+		convert = func(nodes []*patternNode) []htmlPattern {
+			var out []htmlPattern
+			for _, n := range nodes {
+				hp := htmlPattern{
+					Site:       siteLabel(n.site),
+					Title:      n.site.Function + " (" + n.site.Location + ")",
+					Goroutines: n.goroutines,
+					Children:   convert(n.children),
+				}
+				for _, f := range n.findings {
+					hp.Findings = append(hp.Findings, index[keyOf(f)])
+				}
+				out = append(out, hp)
+			}
+			return out
+		}
+		data.Patterns = convert(patternTree(patterns))
+	}
+
+	return htmlReport.Execute(w, data)
+}
+
+// hasGoroutine reports whether tl has the goroutine of f. The kind must
+// match too: the findings of a --repeat run may come from other runs than
+// the one whose timeline was kept.
+func hasGoroutine(tl *detector.Timeline, f detector.Finding) bool {
+	i, ok := slices.BinarySearchFunc(tl.Goroutines, f, func(g detector.GoroutineTimeline, f detector.Finding) int {
+		return cmp.Compare(g.ID, f.GoroutineID)
+	})
+	return ok && f.GoroutineID != 0 && slices.Contains(tl.Goroutines[i].Kinds, f.Kind)
+}
+
+func (d *htmlData) pkg(name string, tl *detector.Timeline) htmlPackage {
+	p := htmlPackage{Name: name, DurationNs: tl.Duration.Nanoseconds()}
+	budget := htmlMaxGoroutines
+	for _, g := range tl.Goroutines {
+		if len(g.Kinds) > 0 {
+			budget--
+		}
+	}
+	for _, g := range tl.Goroutines {
+		if len(g.Kinds) == 0 {
+			if budget <= 0 {
+				p.Omitted++
+				continue
+			}
+			budget--
+		}
+		hg := htmlGoroutine{
+			ID:            uint64(g.ID),
+			Parent:        uint64(g.ParentID),
+			Test:          g.Test,
+			Function:      g.Function,
+			Location:      g.Location,
+			CreatedBy:     g.CreatedBy,
+			CreatedAt:     g.CreatedAt,
+			CreationStack: d.intern(g.CreationStack),
+			Created:       g.Created.Nanoseconds(),
+			Exited:        g.Exited.Nanoseconds(),
+			Dropped:       g.Dropped,
+			Spans:         make([][5]int64, 0, len(g.Spans)),
+		}
+		for _, k := range g.Kinds {
+			hg.Kinds = append(hg.Kinds, string(k))
+		}
+		for _, s := range g.Spans {
+			hg.Spans = append(hg.Spans, [5]int64{
+				int64(slices.Index(htmlSpanStates, s.State)),
+				s.Start.Nanoseconds(),
+				s.End.Nanoseconds(),
+				int64(d.intern(s.Reason)),
+				int64(d.intern(s.Stack)),
+			})
+		}
+		p.Goroutines = append(p.Goroutines, hg)
+	}
+	return p
+}
+
+func (d *htmlData) intern(s string) int {
+	i, ok := d.interned[s]
+	if !ok {
+		i = len(d.Strings)
+		d.interned[s] = i
+		d.Strings = append(d.Strings, s)
+	}
+	return i
+}
@@ -10,23 +10,113 @@ import (
 )
 
 type jsonFinding struct {
-	Kind          string `json:"kind"`
-	Confidence    string `json:"confidence"`
-	GoroutineID   uint64 `json:"goroutine_id"`
-	Count         int    `json:"count"`
-	BlockedOn     string `json:"blocked_on"`
-	BlockedForMs  int64  `json:"blocked_for_ms"`
-	Function      string `json:"function,omitempty"`
-	Location      string `json:"location,omitempty"`
-	Stack         string `json:"stack,omitempty"`
-	Explanation   string `json:"explanation,omitempty"`
+	Kind         string          `json:"kind"`
+	Confidence   string          `json:"confidence"`
+	GoroutineID  uint64          `json:"goroutine_id"`
+	Count        int             `json:"count"`
+	BlockedOn    string          `json:"blocked_on"`
+	BlockedForMs int64           `json:"blocked_for_ms"`
+	Function     string          `json:"function,omitempty"`
+	Location     string          `json:"location,omitempty"`
+	Package      string          `json:"package,omitempty"`
+	Test         string          `json:"test,omitempty"`
+	Tests        []string        `json:"tests,omitempty"`
+	Evidence     []string        `json:"evidence,omitempty"`
+	CreatedBy    string          `json:"created_by,omitempty"`
+	CreatedAt    string          `json:"created_at,omitempty"`
+	SpawnPath    []jsonSpawnSite `json:"spawn_path,omitempty"`
+	Stack        string          `json:"stack,omitempty"`
+	Fingerprint  string          `json:"fingerprint"`
+	Hits         int             `json:"hits,omitempty"`
+	Runs         int             `json:"runs,omitempty"`
+	HitRatio     float64         `json:"hit_ratio,omitempty"`
+	Explanation  string          `json:"explanation,omitempty"`
+}
+
+type jsonSpawnSite struct {
+	Function string `json:"function"`
+	Location string `json:"location"`
+}
+
+// jsonPattern is a detector.Pattern; Findings are indexes into the report's
+// findings.
+type jsonPattern struct {
+	SpawnPath  []jsonSpawnSite `json:"spawn_path"`
+	Goroutines int             `json:"goroutines"`
+	Findings   []int           `json:"findings"`
+}
+
+type jsonPackage struct {
+	Package            string `json:"package"`
+	TraceFile          string `json:"trace_file"`
+	DurationMs         int64  `json:"duration_ms"`
+	GoroutinesAnalyzed int    `json:"goroutines_analyzed"`
+}
+
+type jsonLeakRate struct {
+	Test          string  `json:"test"`
+	Package       string  `json:"package,omitempty"`
+	Invocations   int     `json:"invocations"`
+	Leaked        int     `json:"leaked"`
+	PerInvocation []int   `json:"leaked_per_invocation"`
+	LeakRate      float64 `json:"leak_rate"`
+	Linear        bool    `json:"linear"`
+}
+
+type jsonWindow struct {
+	FromMs int64  `json:"from_ms"`
+	ToMs   int64  `json:"to_ms"`
+	Region string `json:"region,omitempty"`
+}
+
+type jsonSuppressed struct {
+	Kind     string `json:"kind"`
+	Count    int    `json:"count"`
+	Function string `json:"function,omitempty"`
+	Location string `json:"location,omitempty"`
+	Package  string `json:"package,omitempty"`
+	Test     string `json:"test,omitempty"`
+	Rule     string `json:"rule"`
+	Reason   string `json:"reason"`
+}
+
+type jsonBaselineEntry struct {
+	Kind        string `json:"kind"`
+	Count       int    `json:"count,omitempty"`
+	Function    string `json:"function,omitempty"`
+	Location    string `json:"location,omitempty"`
+	CreatedAt   string `json:"created_at,omitempty"`
+	Fingerprint string `json:"fingerprint,omitempty"`
+}
+
+type jsonBaseline struct {
+	File       string              `json:"file"`
+	New        int                 `json:"new"`
+	Persisting []jsonBaselineEntry `json:"persisting"`
+	Resolved   []jsonBaselineEntry `json:"resolved"`
+}
+
+type jsonRepeat struct {
+	Runs    int           `json:"runs"`
+	MinHits int           `json:"min_hits"`
+	Envs    []string      `json:"envs"`
+	Flaky   []jsonFinding `json:"flaky"`
 }
 
 type jsonReport struct {
-	TraceFile          string        `json:"trace_file"`
-	DurationMs         int64         `json:"duration_ms"`
-	GoroutinesAnalyzed int           `json:"goroutines_analyzed"`
-	Findings           []jsonFinding `json:"findings"`
+	TraceFile          string           `json:"trace_file"`
+	DurationMs         int64            `json:"duration_ms"`
+	GoroutinesAnalyzed int              `json:"goroutines_analyzed"`
+	Window             *jsonWindow      `json:"window,omitempty"`
+	Packages           []jsonPackage    `json:"packages,omitempty"`
+	Findings           []jsonFinding    `json:"findings"`
+	Patterns           []jsonPattern    `json:"patterns,omitempty"`
+	LeakRates          []jsonLeakRate   `json:"leak_rates,omitempty"`
+	PeakHeapBytes      uint64           `json:"peak_heap_bytes,omitempty"`
+	Suppressed         []jsonSuppressed `json:"suppressed,omitempty"`
+	UnusedSuppressions []string         `json:"unused_suppressions,omitempty"`
+	Baseline           *jsonBaseline    `json:"baseline,omitempty"`
+	Repeat             *jsonRepeat      `json:"repeat,omitempty"`
 }
 
 // WriteJSON writes findings as JSON to the given writer.
@@ -36,30 +126,111 @@ func WriteJSON(w io.Writer, result *detector.Result, explanation string) error {
 		DurationMs:         result.DurationMs,
 		GoroutinesAnalyzed: result.GoroutinesAnalyzed,
 		Findings:           make([]jsonFinding, 0, len(result.Findings)),
+		PeakHeapBytes:      result.PeakHeapBytes,
+		UnusedSuppressions: result.UnusedSuppressions,
 	}
 
-	for _, f := range result.Findings {
-		count := f.Count
-		if count < 1 {
-			count = 1
+	if win := result.Window; win != nil {
+		report.Window = &jsonWindow{
+			FromMs: win.From.Milliseconds(),
+			ToMs:   win.To.Milliseconds(),
+			Region: win.Region,
+		}
+	}
+
+	for _, p := range result.Packages {
+		report.Packages = append(report.Packages, jsonPackage{
+			Package:            p.Package,
+			TraceFile:          p.TraceFile,
+			DurationMs:         p.DurationMs,
+			GoroutinesAnalyzed: p.GoroutinesAnalyzed,
+		})
+	}
+
+	for _, sf := range result.Suppressed {
+		report.Suppressed = append(report.Suppressed, jsonSuppressed{
+			Kind:     string(sf.Finding.Kind),
+			Count:    max(sf.Finding.Count, 1),
+			Function: sf.Finding.Function,
+			Location: sf.Finding.Location,
+			Package:  sf.Finding.Package,
+			Test:     sf.Finding.Test,
+			Rule:     sf.Rule,
+			Reason:   sf.Reason,
+		})
+	}
+
+	if b := result.Baseline; b != nil {
+		report.Baseline = &jsonBaseline{
+			File:       b.File,
+			New:        len(result.Findings),
+			Persisting: []jsonBaselineEntry{},
+			Resolved:   []jsonBaselineEntry{},
+		}
+		for _, f := range b.Persisting {
+			report.Baseline.Persisting = append(report.Baseline.Persisting, jsonBaselineEntry{
+				Kind:        string(f.Kind),
+				Count:       max(f.Count, 1),
+				Function:    f.Function,
+				Location:    f.Location,
+				CreatedAt:   f.CreationLocation,
+				Fingerprint: f.Fingerprint(),
+			})
 		}
-		jf := jsonFinding{
-			Kind:         string(f.Kind),
-			Confidence:   string(f.Confidence),
-			GoroutineID:  uint64(f.GoroutineID),
-			Count:        count,
-			BlockedOn:    f.BlockedOn,
-			BlockedForMs: f.BlockedFor.Round(time.Millisecond).Milliseconds(),
-			Function:     f.Function,
-			Location:     f.Location,
-			Stack:        f.Stack,
+		for _, e := range b.Resolved {
+			report.Baseline.Resolved = append(report.Baseline.Resolved, jsonBaselineEntry{
+				Kind:        string(e.Kind),
+				Function:    e.Function,
+				Location:    e.Location,
+				Fingerprint: e.Fingerprint,
+			})
 		}
+	}
+
+	for _, lr := range result.LeakRates {
+		report.LeakRates = append(report.LeakRates, jsonLeakRate{
+			Test:          lr.Test,
+			Package:       lr.Package,
+			Invocations:   lr.Invocations,
+			Leaked:        lr.Leaked,
+			PerInvocation: lr.PerInvocation,
+			LeakRate:      lr.Rate,
+			Linear:        lr.Linear,
+		})
+	}
+
+	for _, f := range result.Findings {
+		jf := toJSONFinding(f)
 		if explanation != "" && len(report.Findings) == 0 {
 			jf.Explanation = explanation
 		}
 		report.Findings = append(report.Findings, jf)
 	}
 
+	index := make(map[findingKey]int, len(result.Findings))
+	for i, f := range result.Findings {
+		index[keyOf(f)] = i
+	}
+	for _, p := range detector.Patterns(result.Findings) {
+		jp := jsonPattern{SpawnPath: toJSONSpawnPath(p.SpawnPath), Goroutines: p.Goroutines}
+		for _, f := range p.Findings {
+			jp.Findings = append(jp.Findings, index[keyOf(f)])
+		}
+		report.Patterns = append(report.Patterns, jp)
+	}
+
+	if rp := result.Repeat; rp != nil {
+		report.Repeat = &jsonRepeat{
+			Runs:    rp.Runs,
+			MinHits: rp.MinHits,
+			Envs:    rp.Envs,
+			Flaky:   make([]jsonFinding, 0, len(rp.Flaky)),
+		}
+		for _, f := range rp.Flaky {
+			report.Repeat.Flaky = append(report.Repeat.Flaky, toJSONFinding(f))
+		}
+	}
+
 	// Attach explanation to the first finding, or as a top-level note
 	enc := json.NewEncoder(w)
 	enc.SetIndent("", "  ")
@@ -83,3 +254,43 @@ func WriteJSON(w io.Writer, result *detector.Result, explanation string) error {
 	}
 	return nil
 }
+
+func toJSONFinding(f detector.Finding) jsonFinding {
+	count := f.Count
+	if count < 1 {
+		count = 1
+	}
+	jf := jsonFinding{
+		Kind:         string(f.Kind),
+		Confidence:   string(f.Confidence),
+		GoroutineID:  uint64(f.GoroutineID),
+		Count:        count,
+		BlockedOn:    f.BlockedOn,
+		BlockedForMs: f.BlockedFor.Round(time.Millisecond).Milliseconds(),
+		Function:     f.Function,
+		Location:     f.Location,
+		Package:      f.Package,
+		Test:         f.Test,
+		Tests:        f.Tests,
+		Evidence:     f.Evidence,
+		CreatedBy:    f.CreationFunction,
+		CreatedAt:    f.CreationLocation,
+		SpawnPath:    toJSONSpawnPath(f.SpawnPath),
+		Stack:        f.Stack,
+		Fingerprint:  f.Fingerprint(),
+		Hits:         f.Hits,
+		Runs:         f.Runs,
+	}
+	if f.Runs > 0 {
+		jf.HitRatio = float64(f.Hits) / float64(f.Runs)
+	}
+	return jf
+}
+
+func toJSONSpawnPath(path []detector.SpawnSite) []jsonSpawnSite {
+	var out []jsonSpawnSite
+	for _, s := range path {
+		out = append(out, jsonSpawnSite{Function: s.Function, Location: s.Location})
+	}
+	return out
+}
@@ -0,0 +1,218 @@
+package reporter
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name      string      `xml:"name,attr"`
+	Tests     int         `xml:"tests,attr"`
+	Failures  int         `xml:"failures,attr"`
+	Errors    int         `xml:"errors,attr"`
+	Time      string      `xml:"time,attr"`
+	Cases     []junitCase `xml:"testcase"`
+	SystemOut *junitText  `xml:"system-out,omitempty"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",cdata"`
+}
+
+type junitText struct {
+	Text string `xml:",cdata"`
+}
+
+// unattributedCase names the test case of findings that belong to no test,
+// such as static analysis and race findings or goroutines started by
+// TestMain.
+const unattributedCase = "(no test)"
+
+// WriteJUnit writes result as JUnit XML: one testsuite per package and one
+// testcase per test the trace shows (Result.Tests). A test fails if any
+// finding is attributed to it (Finding.TestNames); the failure lists each
+// finding's kind, location and stack. The explanation, if any, is the
+// system-out of every suite with failures.
+func WriteJUnit(w io.Writer, result *detector.Result, explanation string) error {
+	type pkgInfo struct {
+		name       string
+		durationMs int64
+		tests      []string
+	}
+	var pkgs []pkgInfo
+	if len(result.Packages) > 0 {
+		for _, p := range result.Packages {
+			pkgs = append(pkgs, pkgInfo{p.Package, p.DurationMs, p.Tests})
+		}
+	} else {
+		name := result.Package
+		if name == "" {
+			name = result.TraceFile
+		}
+		pkgs = append(pkgs, pkgInfo{name, result.DurationMs, result.Tests})
+	}
+
+	// Group findings by package and top-level test. With a single result,
+	// Finding.Package is empty and everything belongs to the one suite.
+	known := make(map[string]bool)
+	for _, p := range pkgs {
+		known[p.name] = true
+	}
+	byTest := make(map[[2]string][]detector.Finding)
+	var other []detector.Finding
+	for _, f := range result.Findings {
+		pkg := f.Package
+		if len(result.Packages) == 0 {
+			pkg = pkgs[0].name
+		}
+		if !known[pkg] {
+			other = append(other, f)
+			continue
+		}
+		// A finding fails every test whose goroutines it covers.
+		tests := []string{unattributedCase}
+		if names := f.TestNames(); len(names) > 0 {
+			tests = nil
+			for _, name := range names {
+				if top, _, _ := strings.Cut(name, "/"); !containsString(tests, top) {
+					tests = append(tests, top)
+				}
+			}
+		}
+		for _, test := range tests {
+			byTest[[2]string{pkg, test}] = append(byTest[[2]string{pkg, test}], f)
+		}
+	}
+	if len(other) > 0 {
+		pkgs = append(pkgs, pkgInfo{name: "threadgraph"})
+		for _, f := range other {
+			byTest[[2]string{"threadgraph", unattributedCase}] = append(byTest[[2]string{"threadgraph", unattributedCase}], f)
+		}
+	}
+
+	out := junitSuites{Name: "threadgraph"}
+	for _, p := range pkgs {
+		suite := junitSuite{Name: p.name, Time: seconds(p.durationMs)}
+		tests := p.tests
+		if len(byTest[[2]string{p.name, unattributedCase}]) > 0 {
+			tests = append(tests[:len(tests):len(tests)], unattributedCase)
+		}
+		for _, test := range tests {
+			tc := junitCase{Name: test, Classname: p.name, Time: "0"}
+			if findings := byTest[[2]string{p.name, test}]; len(findings) > 0 {
+				tc.Failure = junitFailureOf(findings)
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, tc)
+		}
+		// Findings of tests the trace did not name (e.g. a dump) still fail.
+		var extra []string
+		for key := range byTest {
+			if key[0] == p.name && !containsString(tests, key[1]) {
+				extra = append(extra, key[1])
+			}
+		}
+		sort.Strings(extra)
+		for _, test := range extra {
+			suite.Cases = append(suite.Cases, junitCase{
+				Name: test, Classname: p.name, Time: "0", Failure: junitFailureOf(byTest[[2]string{p.name, test}]),
+			})
+			suite.Failures++
+		}
+		suite.Tests = len(suite.Cases)
+		if suite.Failures > 0 && explanation != "" {
+			suite.SystemOut = &junitText{strings.TrimSpace(explanation)}
+		}
+		out.Tests += suite.Tests
+		out.Failures += suite.Failures
+		out.Suites = append(out.Suites, suite)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(out); err != nil {
+		return fmt.Errorf("encode junit: %w", err)
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitFailureOf describes the findings of one test as a single failure.
+func junitFailureOf(findings []detector.Finding) *junitFailure {
+	f := findings[0]
+	msg := fmt.Sprintf("%s at %s", f.Kind, f.Location)
+	if len(findings) > 1 {
+		msg = fmt.Sprintf("%d concurrency issues, first: %s", len(findings), msg)
+	}
+	var b strings.Builder
+	for i, f := range findings {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "%s (%s confidence): %s\n", f.Kind, f.Confidence, f.BlockedOn)
+		if f.Location != "" {
+			fmt.Fprintf(&b, "  Location: %s\n", f.Location)
+		}
+		if f.BlockedFor > 0 {
+			fmt.Fprintf(&b, "  Blocked for: %v\n", f.BlockedFor.Round(time.Millisecond))
+		}
+		if f.Count > 1 {
+			fmt.Fprintf(&b, "  Goroutines: %d\n", f.Count)
+		}
+		if f.CreationLocation != "" {
+			fmt.Fprintf(&b, "  Created by: %s at %s\n", f.CreationFunction, f.CreationLocation)
+		}
+		if len(f.SpawnPath) > 1 {
+			fmt.Fprintf(&b, "  Spawned via: %s\n", spawnPathString(f.SpawnPath))
+		}
+		for _, e := range f.Evidence {
+			fmt.Fprintf(&b, "  %s\n", e)
+		}
+		if f.Stack != "" {
+			b.WriteString("  Stack:\n")
+			for _, line := range strings.Split(strings.TrimRight(f.Stack, "\n"), "\n") {
+				fmt.Fprintf(&b, "    %s\n", strings.TrimSpace(line))
+			}
+		}
+	}
+	return &junitFailure{Message: msg, Type: string(f.Kind), Text: b.String()}
+}
+
+func seconds(ms int64) string {
+	return fmt.Sprintf("%.3f", float64(ms)/1000)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
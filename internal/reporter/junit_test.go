@@ -0,0 +1,140 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/xml"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// checkGolden compares got with testdata/name.
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s differs (rerun with -update to accept):\n%s", path, got)
+	}
+}
+
+// junitResult is a merged result of two packages. Its findings cover a
+// subtest, two tests at once, a test the trace did not list, no test, and a
+// package that is not in Packages; their text needs XML escaping.
+func junitResult() *detector.Result {
+	return &detector.Result{
+		Packages: []detector.PackageSummary{
+			{Package: "example.com/app", DurationMs: 1500, Tests: []string{"TestServe", "TestCache", "TestOK"}},
+			{Package: "example.com/app/pool", DurationMs: 250, Tests: []string{"TestPool"}},
+		},
+		Findings: []detector.Finding{
+			{
+				Kind:             detector.KindGoroutineLeak,
+				Confidence:       detector.ConfidenceHigh,
+				BlockedOn:        "chan receive <-done & never closed",
+				BlockedFor:       1234567891 * time.Nanosecond,
+				Function:         "example.com/app.(*Server).handle",
+				Location:         "/src/app/server.go:42",
+				Count:            3,
+				Package:          "example.com/app",
+				Test:             "TestServe/slow_client",
+				CreationFunction: "example.com/app.(*Server).Serve",
+				CreationLocation: "/src/app/server.go:20",
+				SpawnPath: []detector.SpawnSite{
+					{Function: "example.com/app.TestServe", Location: "/src/app/server_test.go:15"},
+					{Function: "example.com/app.(*Server).Serve", Location: "/src/app/server.go:20"},
+				},
+				Stack: "      example.com/app.(*Server).handle (/src/app/server.go:42)\n      example.com/app.(*Server).Serve.func1 (/src/app/server.go:21)\n",
+			},
+			{
+				Kind:       detector.KindDeadlock,
+				Confidence: detector.ConfidenceMedium,
+				BlockedOn:  `sync.Mutex "cache"`,
+				Function:   "example.com/app.(*Cache).Get",
+				Location:   "/src/app/cache.go:30",
+				Package:    "example.com/app",
+				Tests:      []string{"TestCache", "TestServe"},
+				Evidence:   []string{"wait-for cycle: G7 → G9 → G7", "stack has ]]> in it"},
+			},
+			{
+				Kind:       detector.KindLongBlock,
+				Confidence: detector.ConfidenceLow,
+				BlockedOn:  "select",
+				Location:   "/src/app/pool/pool.go:12",
+				Package:    "example.com/app/pool",
+				Test:       "TestUnlisted",
+			},
+			{
+				Kind:       detector.KindLockOrder,
+				Confidence: detector.ConfidenceMedium,
+				BlockedOn:  "lock order A → B → A",
+				Function:   "example.com/app/pool.(*Pool).Put",
+				Location:   "/src/app/pool/pool.go:50",
+				Package:    "example.com/app/pool",
+			},
+			{
+				Kind:       detector.KindDataRace,
+				Confidence: detector.ConfidenceHigh,
+				BlockedOn:  "write at x < y",
+				Location:   "/src/R&D/x.go:3",
+				Package:    "example.com/other",
+			},
+		},
+	}
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, junitResult(), "The handler waits on <-done, which Close never closes.\n"); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "junit.xml", buf.Bytes())
+
+	// The output is well-formed and its counts add up.
+	var suites junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatal(err)
+	}
+	tests, failures := 0, 0
+	for _, s := range suites.Suites {
+		if s.Tests != len(s.Cases) {
+			t.Errorf("suite %s: tests=%d, %d cases", s.Name, s.Tests, len(s.Cases))
+		}
+		tests += s.Tests
+		failures += s.Failures
+	}
+	if suites.Tests != tests || suites.Failures != failures || failures != 5 {
+		t.Errorf("testsuites tests=%d failures=%d; suites add up to %d and %d, want 5 failures", suites.Tests, suites.Failures, tests, failures)
+	}
+}
+
+func TestWriteJUnitSingleResult(t *testing.T) {
+	result := &detector.Result{
+		TraceFile:  "trace.out",
+		DurationMs: 300,
+		Tests:      []string{"TestA", "TestB"},
+		Findings: []detector.Finding{
+			{Kind: detector.KindGoroutineLeak, Confidence: detector.ConfidenceHigh, BlockedOn: "chan send", Location: "/src/a.go:7", Test: "TestB"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, result, ""); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "junit-single.xml", buf.Bytes())
+}
@@ -0,0 +1,395 @@
+package reporter
+
+import (
+	"cmp"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+// MarkdownMarker is the first line of every Markdown report, an HTML comment
+// that CI scripts can search a pull request's comments for to update their
+// previous report instead of adding another.
+const MarkdownMarker = "<!-- threadgraph-report -->"
+
+// markdownKinds names each Kind in a Markdown report, singular and plural.
+var markdownKinds = map[detector.Kind][2]string{
+	detector.KindGoroutineLeak: {"goroutine leak", "goroutine leaks"},
+	detector.KindDeadlock:      {"deadlock", "deadlocks"},
+	detector.KindLongBlock:     {"long block", "long blocks"},
+	detector.KindLockLeak:      {"lock leak", "lock leaks"},
+	detector.KindLockOrder:     {"lock ordering cycle", "lock ordering cycles"},
+	detector.KindDataRace:      {"data race", "data races"},
+}
+
+// WriteMarkdown writes result as a compact Markdown summary for a pull
+// request comment: a headline counting the findings by kind, then each
+// finding as a collapsed <details> block with its stack folded inside,
+// followed by the --baseline comparison (known and resolved findings), flaky
+// and suppressed findings and the explanation, each collapsed.
+//
+// Locations below the working directory link to "path#Lline", relative to
+// the working directory, prefixed with linkBase (such as
+// "https://github.com/org/repo/blob/<sha>/") if it is not empty. Findings
+// are sorted, and nothing that varies between runs of the same trace (trace
+// durations, heap usage) is included, so an unchanged result renders to the
+// same bytes.
+func WriteMarkdown(w io.Writer, result *detector.Result, explanation, linkBase string) error {
+	m := &markdownWriter{linkBase: linkBase}
+	if wd, err := os.Getwd(); err == nil {
+		m.root = filepath.ToSlash(wd)
+	}
+	b := result.Baseline
+	findings := sortedFindings(result.Findings)
+
+	m.line(MarkdownMarker)
+	icon, headline := "✅", "no concurrency issues found"
+	if len(findings) > 0 {
+		icon, headline = markdownIcon(findings), markdownCounts(findings)
+		if b != nil {
+			headline = "this change introduces " + headline
+		} else {
+			headline += " found"
+		}
+	} else if b != nil {
+		headline = "no new concurrency issues"
+	}
+	m.line("### %s ThreadGraph: %s", icon, headline)
+	m.line("")
+	if b != nil {
+		m.line("**%d new** · %d resolved · %d known, compared with baseline %s",
+			len(findings), len(b.Resolved), len(b.Persisting), code(filepath.Base(b.File)))
+		m.line("")
+	}
+
+	for _, f := range findings {
+		m.finding(f)
+	}
+
+	if b != nil && len(b.Resolved) > 0 {
+		resolved := slices.Clone(b.Resolved)
+		slices.SortStableFunc(resolved, func(x, y detector.BaselineEntry) int {
+			return cmp.Or(
+				cmp.Compare(slices.Index(detector.Kinds, x.Kind), slices.Index(detector.Kinds, y.Kind)),
+				cmp.Compare(x.Location, y.Location),
+				cmp.Compare(x.Function, y.Function),
+			)
+		})
+		m.line("<details><summary>✅ Resolved (%d): in the baseline but no longer found</summary>", len(resolved))
+		m.line("")
+		for _, e := range resolved {
+			m.line("- %s %s", kindName(e.Kind, 1), m.where(detector.Finding{Function: e.Function, Location: e.Location}, false))
+		}
+		m.line("")
+		m.line("</details>")
+		m.line("")
+	}
+	if b != nil && len(b.Persisting) > 0 {
+		m.line("<details><summary>Known (%d): already in the baseline</summary>", len(b.Persisting))
+		m.line("")
+		for _, f := range sortedFindings(b.Persisting) {
+			m.line("- %s %s%s", kindName(f.Kind, 1), m.where(f, true), countSuffix(f.Count))
+		}
+		m.line("")
+		m.line("</details>")
+		m.line("")
+	}
+
+	if rp := result.Repeat; rp != nil && len(rp.Flaky) > 0 {
+		m.line("<details><summary>Flaky (%d): seen in fewer than %d of %d runs</summary>", len(rp.Flaky), rp.MinHits, rp.Runs)
+		m.line("")
+		for _, f := range sortedFindings(rp.Flaky) {
+			m.line("- %s %s, %d/%d runs", kindName(f.Kind, 1), m.where(f, true), f.Hits, f.Runs)
+		}
+		m.line("")
+		m.line("</details>")
+		m.line("")
+	}
+
+	if len(result.Suppressed) > 0 {
+		suppressed := slices.Clone(result.Suppressed)
+		slices.SortStableFunc(suppressed, func(x, y detector.Suppressed) int {
+			return compareFindings(x.Finding, y.Finding)
+		})
+		m.line("<details><summary>Suppressed (%d)</summary>", len(suppressed))
+		m.line("")
+		for _, sf := range suppressed {
+			m.line("- %s %s%s: %s", kindName(sf.Finding.Kind, 1), m.where(sf.Finding, false), countSuffix(sf.Finding.Count), markdownEscape(sf.Reason))
+		}
+		m.line("")
+		m.line("</details>")
+		m.line("")
+	}
+
+	if explanation = strings.TrimSpace(explanation); explanation != "" {
+		m.line("<details><summary>Claude's analysis</summary>")
+		m.line("")
+		m.line("%s", explanation)
+		m.line("")
+		m.line("</details>")
+		m.line("")
+	}
+
+	analyzed := "trace " + code(filepath.Base(result.TraceFile))
+	if len(result.Packages) > 0 {
+		analyzed = pluralize(len(result.Packages), "package")
+	}
+	m.line("<sub>[ThreadGraph](https://github.com/Heman10x-NGU/threadgraph) · %s</sub>", analyzed)
+
+	_, err := io.WriteString(w, m.b.String())
+	return err
+}
+
+type markdownWriter struct {
+	b        strings.Builder
+	root     string // working directory, "" if unknown
+	linkBase string
+}
+
+func (m *markdownWriter) line(format string, args ...any) {
+	fmt.Fprintf(&m.b, format, args...)
+	m.b.WriteString("\n")
+}
+
+// finding writes f as a <details> block: kind, blocking operation and
+// location in the summary, everything else folded.
+func (m *markdownWriter) finding(f detector.Finding) {
+	summary := fmt.Sprintf("%s <b>%s</b>", confidenceIcon(f), html.EscapeString(kindName(f.Kind, 1)))
+	if f.BlockedOn != "" {
+		summary += ": " + html.EscapeString(f.BlockedOn)
+	}
+	if f.Location != "" {
+		summary += " at " + m.htmlLink(f.Location)
+	}
+	if f.Count > 1 {
+		summary += fmt.Sprintf(" (×%d)", f.Count)
+	}
+	m.line("<details><summary>%s</summary>", summary)
+	m.line("")
+
+	if f.Function != "" {
+		m.line("- **Function:** %s", code(f.Function))
+	}
+	if f.Package != "" {
+		m.line("- **Package:** %s", code(f.Package))
+	}
+	if len(f.Tests) > 1 {
+		tests := make([]string, len(f.Tests))
+		for i, t := range f.Tests {
+			tests[i] = code(t)
+		}
+		m.line("- **Tests:** %s", strings.Join(tests, ", "))
+	} else if f.Test != "" {
+		m.line("- **Test:** %s", code(f.Test))
+	}
+	m.line("- **Confidence:** %s", f.Confidence)
+	if f.Count > 1 {
+		m.line("- **Goroutines:** %d", f.Count)
+	}
+	if f.CreationLocation != "" {
+		m.line("- **Created by:** %s", m.where(detector.Finding{Function: f.CreationFunction, Location: f.CreationLocation}, true))
+	}
+	if len(f.SpawnPath) > 1 {
+		sites := make([]string, len(f.SpawnPath))
+		for i, s := range f.SpawnPath {
+			sites[i] = code(siteLabel(s))
+		}
+		m.line("- **Spawned via:** %s", strings.Join(sites, " → "))
+	}
+	if f.Runs > 0 {
+		m.line("- **Reproduced:** %d/%d runs", f.Hits, f.Runs)
+	}
+	for _, e := range f.Evidence {
+		m.line("- %s", markdownEscape(m.trimRoot(e)))
+	}
+	if f.Stack != "" {
+		m.line("")
+		m.line("```text")
+		for _, line := range strings.Split(strings.TrimRight(f.Stack, "\n"), "\n") {
+			line = m.trimRoot(strings.TrimSpace(line))
+			m.line("%s", strings.ReplaceAll(line, "```", "` ` `"))
+		}
+		m.line("```")
+	}
+	m.line("")
+	m.line("</details>")
+	m.line("")
+}
+
+// where describes a finding's location in a list item: its function and
+// location, linked if link is set.
+func (m *markdownWriter) where(f detector.Finding, link bool) string {
+	var parts []string
+	if f.Function != "" {
+		parts = append(parts, code(f.Function))
+	}
+	if f.Location != "" {
+		loc := code(m.relative(f.Location))
+		if link {
+			loc = m.link(f.Location)
+		}
+		parts = append(parts, "at "+loc)
+	}
+	return strings.Join(parts, " ")
+}
+
+// trimRoot makes the paths below the working directory in s relative.
+func (m *markdownWriter) trimRoot(s string) string {
+	if m.root == "" {
+		return s
+	}
+	return strings.ReplaceAll(s, m.root+"/", "")
+}
+
+// relative returns location relative to the working directory if it lies
+// below it.
+func (m *markdownWriter) relative(location string) string {
+	location = filepath.ToSlash(location)
+	if m.root != "" && strings.HasPrefix(location, m.root+"/") {
+		return strings.TrimPrefix(location, m.root+"/")
+	}
+	return location
+}
+
+// href returns the link target of a "file:line" location, or "" for one
+// outside the working directory, such as the standard library.
+func (m *markdownWriter) href(location string) string {
+	file, anchor := location, ""
+	if i := strings.LastIndex(location, ":"); i >= 0 {
+		if n, err := strconv.Atoi(location[i+1:]); err == nil {
+			file, anchor = location[:i], "#L"+strconv.Itoa(n)
+		}
+	}
+	file = filepath.ToSlash(file)
+	switch {
+	case m.root != "" && strings.HasPrefix(file, m.root+"/"):
+		file = strings.TrimPrefix(file, m.root+"/")
+	case filepath.IsAbs(file):
+		return ""
+	}
+	return m.linkBase + uriPath(file) + anchor
+}
+
+// link is a Markdown link to location, or location as code if it has no
+// link target.
+func (m *markdownWriter) link(location string) string {
+	href := m.href(location)
+	if href == "" {
+		return code(m.relative(location))
+	}
+	return fmt.Sprintf("[%s](%s)", code(m.relative(location)), href)
+}
+
+// htmlLink is link for use inside HTML, such as a <summary>.
+func (m *markdownWriter) htmlLink(location string) string {
+	text := "<code>" + html.EscapeString(m.relative(location)) + "</code>"
+	if href := m.href(location); href != "" {
+		return fmt.Sprintf(`<a href="%s">%s</a>`, html.EscapeString(href), text)
+	}
+	return text
+}
+
+// sortedFindings returns a sorted copy of findings, so that reports do not
+// depend on the order detectors produced them in.
+func sortedFindings(findings []detector.Finding) []detector.Finding {
+	sorted := slices.Clone(findings)
+	slices.SortStableFunc(sorted, compareFindings)
+	return sorted
+}
+
+// compareFindings orders findings by kind (in detector.Kinds order), then
+// package, location, function, test and fingerprint.
+func compareFindings(x, y detector.Finding) int {
+	return cmp.Or(
+		cmp.Compare(slices.Index(detector.Kinds, x.Kind), slices.Index(detector.Kinds, y.Kind)),
+		cmp.Compare(x.Package, y.Package),
+		cmp.Compare(x.Location, y.Location),
+		cmp.Compare(x.Function, y.Function),
+		cmp.Compare(x.Test, y.Test),
+		cmp.Compare(x.Fingerprint(), y.Fingerprint()),
+	)
+}
+
+// markdownCounts counts the goroutines of findings by kind, as in
+// "2 goroutine leaks and 1 deadlock".
+func markdownCounts(findings []detector.Finding) string {
+	var parts []string
+	for _, k := range detector.Kinds {
+		if n := countKind(findings, k); n > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, kindName(k, n)))
+		}
+	}
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return strings.Join(parts[:len(parts)-1], ", ") + " and " + parts[len(parts)-1]
+}
+
+// markdownIcon is the headline icon: red if any finding is of a kind the
+// terminal report shows in red, yellow otherwise.
+func markdownIcon(findings []detector.Finding) string {
+	for _, f := range findings {
+		if f.Kind != detector.KindLongBlock && f.Kind != detector.KindLockLeak {
+			return "🔴"
+		}
+	}
+	return "🟡"
+}
+
+func confidenceIcon(f detector.Finding) string {
+	switch f.Confidence {
+	case detector.ConfidenceHigh:
+		return "🔴"
+	case detector.ConfidenceMedium:
+		return "🟡"
+	default:
+		return "⚪"
+	}
+}
+
+func kindName(k detector.Kind, n int) string {
+	names, ok := markdownKinds[k]
+	if !ok {
+		return string(k)
+	}
+	if n == 1 {
+		return names[0]
+	}
+	return names[1]
+}
+
+func countSuffix(count int) string {
+	if count > 1 {
+		return fmt.Sprintf(" (×%d)", count)
+	}
+	return ""
+}
+
+// code formats s as an inline code span.
+func code(s string) string {
+	if strings.Contains(s, "`") {
+		return "`` " + s + " ``"
+	}
+	return "`" + s + "`"
+}
+
+// markdownEscape escapes the characters of s that Markdown or the HTML it
+// allows would interpret.
+func markdownEscape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '`', '*', '_', '[', ']', '<', '>', '|', '#', '~':
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,171 @@
+package reporter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+// markdownResult is a result compared with a baseline: new, known and
+// resolved findings, in an order WriteMarkdown must sort, plus flaky and
+// suppressed ones. Locations below dir are linked; the others, such as the
+// standard library's, are not. Some text needs Markdown or HTML escaping.
+func markdownResult(dir string) *detector.Result {
+	leak := detector.Finding{
+		Kind:             detector.KindGoroutineLeak,
+		Confidence:       detector.ConfidenceHigh,
+		BlockedOn:        "chan receive <-done",
+		BlockedFor:       2 * time.Second,
+		Function:         "example.com/app.(*Server).handle",
+		Location:         dir + "/app/server.go:42",
+		Count:            3,
+		Package:          "example.com/app",
+		Test:             "TestServe",
+		CreationFunction: "example.com/app.(*Server).Serve",
+		CreationLocation: dir + "/app/server.go:20",
+		SpawnPath: []detector.SpawnSite{
+			{Function: "example.com/app.TestServe", Location: dir + "/app/server_test.go:15"},
+			{Function: "example.com/app.(*Server).Serve", Location: dir + "/app/server.go:20"},
+		},
+		Evidence: []string{"never closed in " + dir + "/app/server.go, see *Close*"},
+		Stack:    "      example.com/app.(*Server).handle (" + dir + "/app/server.go:42)\n      uses ```fences```\n",
+	}
+	return &detector.Result{
+		TraceFile: "/tmp/run/trace.out",
+		Findings: []detector.Finding{
+			{
+				Kind:       detector.KindLongBlock,
+				Confidence: detector.ConfidenceLow,
+				BlockedOn:  "select",
+				Function:   "example.com/app/pool.(*Pool).wait",
+				Location:   dir + "/app/pool/pool.go:12",
+				Package:    "example.com/app/pool",
+				Tests:      []string{"TestPool", "TestPool/drain"},
+			},
+			leak,
+			{
+				Kind:       detector.KindDeadlock,
+				Confidence: detector.ConfidenceMedium,
+				BlockedOn:  "sync.Mutex.Lock",
+				Function:   "sync.(*Mutex).Lock",
+				Location:   "/usr/local/go/src/sync/mutex.go:81",
+				Package:    "example.com/app",
+				Runs:       5,
+				Hits:       4,
+			},
+		},
+		Baseline: &detector.BaselineComparison{
+			File: "/src/app/threadgraph-baseline.json",
+			Persisting: []detector.Finding{
+				{Kind: detector.KindLockLeak, Function: "example.com/app.(*Cache).Put", Location: dir + "/app/cache.go:55"},
+				{Kind: detector.KindGoroutineLeak, Function: "example.com/app.poll", Location: dir + "/app/poll.go:9", Count: 2},
+			},
+			Resolved: []detector.BaselineEntry{
+				{Kind: detector.KindLockOrder, Function: "example.com/app.swap", Location: dir + "/app/swap.go:30"},
+				{Kind: detector.KindGoroutineLeak, Function: "example.com/app.tick", Location: dir + "/app/tick.go:7"},
+				{Kind: detector.KindGoroutineLeak, Function: "example.com/app.old", Location: "app/old.go:3"},
+			},
+		},
+		Repeat: &detector.Repeat{
+			Runs:    5,
+			MinHits: 3,
+			Flaky: []detector.Finding{
+				{Kind: detector.KindDataRace, Function: "example.com/app.count", Location: dir + "/app/count.go:14", Hits: 1, Runs: 5},
+			},
+		},
+		Suppressed: []detector.Suppressed{
+			{
+				Finding: detector.Finding{Kind: detector.KindGoroutineLeak, Function: "example.com/app.watch", Location: dir + "/app/watch.go:25", Count: 4},
+				Rule:    "goroutine_leak in example.com/app.watch",
+				Reason:  "exits with the <process> — see [issue #12] *later*",
+			},
+		},
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.ToSlash(wd)
+
+	var buf bytes.Buffer
+	explanation := "\nThe handler waits on `<-done`, which Close never closes.\n\n"
+	if err := WriteMarkdown(&buf, markdownResult(dir), explanation, "https://github.com/org/repo/blob/abc123/"); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "markdown.md", buf.Bytes())
+}
+
+func TestWriteMarkdownNoLinkBase(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := markdownResult(filepath.ToSlash(wd))
+	result.Findings = result.Findings[1:2]
+	result.Baseline, result.Repeat, result.Suppressed = nil, nil, nil
+	result.Packages = []detector.PackageSummary{{Package: "example.com/app"}, {Package: "example.com/app/pool"}}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, result, "", ""); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "markdown-nobaseline.md", buf.Bytes())
+}
+
+func TestWriteMarkdownHeadline(t *testing.T) {
+	tests := []struct {
+		name   string
+		result *detector.Result
+		want   string
+	}{
+		{
+			name:   "clean",
+			result: &detector.Result{TraceFile: "trace.out"},
+			want:   "### ✅ ThreadGraph: no concurrency issues found\n",
+		},
+		{
+			name: "no new",
+			result: &detector.Result{
+				TraceFile: "trace.out",
+				Baseline: &detector.BaselineComparison{
+					File:       "threadgraph-baseline.json",
+					Persisting: []detector.Finding{{Kind: detector.KindGoroutineLeak, Location: "x.go:1"}},
+				},
+			},
+			want: "### ✅ ThreadGraph: no new concurrency issues\n\n**0 new** · 0 resolved · 1 known, compared with baseline `threadgraph-baseline.json`\n",
+		},
+		{
+			name: "yellow",
+			result: &detector.Result{
+				TraceFile: "trace.out",
+				Findings: []detector.Finding{
+					{Kind: detector.KindLongBlock, Location: "x.go:1"},
+					{Kind: detector.KindLockLeak, Location: "x.go:2"},
+					{Kind: detector.KindLongBlock, Location: "x.go:3", Count: 2},
+				},
+			},
+			want: "### 🟡 ThreadGraph: 3 long blocks and 1 lock leak found\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteMarkdown(&buf, tt.result, "", ""); err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			want := MarkdownMarker + "\n" + tt.want
+			if !strings.HasPrefix(got, want) {
+				t.Errorf("report starts\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package reporter
+
+import (
+	"cmp"
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"golang.org/x/exp/trace"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+// patternNode is a spawn site in the tree formed by the spawn paths of
+// detector.Patterns: patterns sharing a path prefix share its nodes, so a
+// node is the nearest common spawn ancestor of the patterns below it.
+type patternNode struct {
+	site       detector.SpawnSite
+	goroutines int
+	children   []*patternNode
+	// findings are those of the pattern whose path ends at this node.
+	findings []detector.Finding
+}
+
+// patternTree merges the spawn paths of patterns into a forest, children
+// ordered by goroutines affected, largest first.
+func patternTree(patterns []detector.Pattern) []*patternNode {
+	root := &patternNode{}
+	for _, p := range patterns {
+		n := root
+		for _, site := range p.SpawnPath {
+			i := slices.IndexFunc(n.children, func(c *patternNode) bool { return c.site == site })
+			if i < 0 {
+				i = len(n.children)
+				n.children = append(n.children, &patternNode{site: site})
+			}
+			n = n.children[i]
+			n.goroutines += p.Goroutines
+		}
+		n.findings = append(n.findings, p.Findings...)
+	}
+	var sortChildren func(n *patternNode)
+	sortChildren = func(n *patternNode) {
+		slices.SortStableFunc(n.children, func(a, b *patternNode) int {
+			return cmp.Compare(b.goroutines, a.goroutines)
+		})
+		for _, c := range n.children {
+			sortChildren(c)
+		}
+	}
+	sortChildren(root)
+	return root.children
+}
+
+// patternLine is one line of a rendered pattern tree: a spawn site, or a
+// finding of the pattern ending at the site above it.
+type patternLine struct {
+	prefix  string // tree-drawing characters
+	node    *patternNode
+	finding *detector.Finding
+}
+
+// patternLines lays out the pattern tree depth-first, with box-drawing
+// prefixes.
+func patternLines(nodes []*patternNode) []patternLine {
+	var lines []patternLine
+	var walk func(nodes []*patternNode, indent string, top bool)
+	walk = func(nodes []*patternNode, indent string, top bool) {
+		for i, n := range nodes {
+			last := i == len(nodes)-1
+			branch, next := "├─ ", "│  "
+			if last {
+				branch, next = "└─ ", "   "
+			}
+			if top {
+				branch, next = "", ""
+			}
+			lines = append(lines, patternLine{prefix: indent + branch, node: n})
+			child := indent + next
+			for j := range n.findings {
+				bar := "  "
+				if len(n.children) > 0 {
+					bar = "│ "
+				}
+				lines = append(lines, patternLine{prefix: child + bar + "  ", finding: &n.findings[j]})
+			}
+			walk(n.children, child, false)
+		}
+	}
+	walk(nodes, "", true)
+	return lines
+}
+
+// siteLabel is a spawn site as shown in reports: the function without its
+// import path, and the file name and line of the 'go' statement.
+func siteLabel(s detector.SpawnSite) string {
+	fn := s.Function
+	if slash := strings.LastIndex(fn, "/"); slash >= 0 {
+		fn = fn[slash+1:]
+	}
+	return fmt.Sprintf("%s (%s)", fn, filepath.Base(s.Location))
+}
+
+// spawnPathString is a spawn path on one line, outermost first.
+func spawnPathString(path []detector.SpawnSite) string {
+	parts := make([]string, len(path))
+	for i, s := range path {
+		parts[i] = siteLabel(s)
+	}
+	return strings.Join(parts, " → ")
+}
+
+// findingKey identifies a finding of a Result, to find a pattern's findings
+// among the Result's.
+type findingKey struct {
+	kind      detector.Kind
+	goroutine trace.GoID
+	location  string
+	test      string
+	pkg       string
+}
+
+func keyOf(f detector.Finding) findingKey {
+	return findingKey{f.Kind, f.GoroutineID, f.Location, f.Test, f.Package}
+}
+
+// showPatterns reports whether the pattern tree says more than the findings
+// themselves: some pattern covers more than one goroutine.
+func showPatterns(patterns []detector.Pattern) bool {
+	for _, p := range patterns {
+		if p.Goroutines > 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,45 @@
+package reporter
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/fatih/color"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+func TestPrintPatterns(t *testing.T) {
+	color.NoColor = true
+	serve := detector.SpawnSite{Function: "example.com/app.(*Server).Serve", Location: "/app/server.go:20"}
+	handle := detector.SpawnSite{Function: "example.com/app.(*Server).handle", Location: "/app/server.go:55"}
+	poll := detector.SpawnSite{Function: "example.com/app.(*Server).poll", Location: "/app/server.go:70"}
+	tick := detector.SpawnSite{Function: "example.com/app.startTicker", Location: "/app/tick.go:8"}
+
+	patterns := detector.Patterns([]detector.Finding{
+		{Kind: detector.KindGoroutineLeak, Location: "/app/handler.go:30", Count: 300, SpawnPath: []detector.SpawnSite{serve, handle}},
+		{Kind: detector.KindGoroutineLeak, Location: "/app/handler.go:41", Count: 200, SpawnPath: []detector.SpawnSite{serve, handle}},
+		{Kind: detector.KindLongBlock, Location: "/app/poll.go:9", Count: 2, SpawnPath: []detector.SpawnSite{serve, poll}},
+		{Kind: detector.KindGoroutineLeak, Location: "/app/server.go:90", SpawnPath: []detector.SpawnSite{serve}},
+		{Kind: detector.KindDeadlock, Function: "example.com/app.tick", SpawnPath: []detector.SpawnSite{tick}},
+	})
+
+	var buf bytes.Buffer
+	printPatterns(&buf, patterns)
+	want := `
+  Patterns  4 unique patterns → 504 goroutines affected, by spawn path
+
+    go app.(*Server).Serve (server.go:20)  503 goroutines
+    │   goroutine_leak /app/server.go:90
+    ├─ go app.(*Server).handle (server.go:55)  500 goroutines
+    │      goroutine_leak /app/handler.go:30 (×300)
+    │      goroutine_leak /app/handler.go:41 (×200)
+    └─ go app.(*Server).poll (server.go:70)  2 goroutines
+           long_block /app/poll.go:9 (×2)
+    go app.startTicker (tick.go:8)  1 goroutine
+        deadlock example.com/app.tick
+`
+	if got := buf.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
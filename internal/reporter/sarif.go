@@ -0,0 +1,464 @@
+package reporter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/suppress"
+)
+
+// SARIF 2.1.0 (https://docs.oasis-open.org/sarif/sarif/v2.1.0/), limited to
+// the properties code scanning services read.
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactLoc `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult               `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID                   string          `json:"id"`
+	Name                 string          `json:"name"`
+	ShortDescription     sarifText       `json:"shortDescription"`
+	FullDescription      sarifText       `json:"fullDescription"`
+	Help                 sarifHelp       `json:"help"`
+	DefaultConfiguration sarifRuleConfig `json:"defaultConfiguration"`
+	Properties           map[string]any  `json:"properties,omitempty"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifHelp struct {
+	Text string `json:"text"`
+}
+
+type sarifRuleConfig struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifText         `json:"message"`
+	Locations           []sarifLocation   `json:"locations,omitempty"`
+	RelatedLocations    []sarifLocation   `json:"relatedLocations,omitempty"`
+	CodeFlows           []sarifCodeFlow   `json:"codeFlows,omitempty"`
+	PartialFingerprints map[string]string `json:"partialFingerprints,omitempty"`
+	BaselineState       string            `json:"baselineState,omitempty"`
+	Suppressions        []sarifSuppress   `json:"suppressions,omitempty"`
+	Properties          map[string]any    `json:"properties,omitempty"`
+}
+
+type sarifLocation struct {
+	ID               int                    `json:"id,omitempty"`
+	PhysicalLocation *sarifPhysicalLocation `json:"physicalLocation,omitempty"`
+	Message          *sarifText             `json:"message,omitempty"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLoc `json:"artifactLocation"`
+	Region           *sarifRegion     `json:"region,omitempty"`
+}
+
+type sarifArtifactLoc struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+type sarifCodeFlow struct {
+	ThreadFlows []sarifThreadFlow `json:"threadFlows"`
+}
+
+type sarifThreadFlow struct {
+	Locations []sarifThreadFlowLoc `json:"locations"`
+}
+
+type sarifThreadFlowLoc struct {
+	Location sarifLocation `json:"location"`
+}
+
+type sarifSuppress struct {
+	Kind          string `json:"kind"`
+	Justification string `json:"justification,omitempty"`
+}
+
+// sarifFingerprintKey names Finding.Fingerprint in partialFingerprints.
+const sarifFingerprintKey = "threadgraphFingerprint/v1"
+
+// ruleInfo describes one finding kind as a SARIF rule.
+type ruleInfo struct {
+	name, short, full, help string
+	level                   string
+}
+
+var sarifRules = map[detector.Kind]ruleInfo{
+	detector.KindGoroutineLeak: {
+		name:  "GoroutineLeak",
+		short: "Goroutine blocked forever",
+		full:  "A goroutine started by the code under test was still blocked when the trace ended and nothing could unblock it.",
+		help:  "Make sure every goroutine can finish: give channel sends a receiver (or a buffer), close channels that receivers range over, and select on a context or done channel next to any operation that may never complete.",
+		level: "error",
+	},
+	detector.KindDeadlock: {
+		name:  "Deadlock",
+		short: "Goroutines stuck on a mutex",
+		full:  "Several goroutines were blocked acquiring the same mutex until the end of the trace.",
+		help:  "Find the goroutine holding the mutex and why it never releases it: a missing Unlock on an early return, a lock held across a blocking operation, or two locks taken in opposite orders. Prefer defer mu.Unlock() right after Lock.",
+		level: "error",
+	},
+	detector.KindLongBlock: {
+		name:  "LongBlock",
+		short: "Goroutine blocked for a long time",
+		full:  "A goroutine was blocked for longer than --min-block, but eventually continued or was not provably stuck.",
+		help:  "Check whether the wait is expected. Long waits on locks or channels often hide contention or a timeout-based recovery from a deadlock.",
+		level: "warning",
+	},
+	detector.KindLockLeak: {
+		name:  "LockLeak",
+		short: "Lock not released on every path",
+		full:  "Static analysis found a path through the function that acquires a mutex and returns without releasing it.",
+		help:  "Release the lock on every return path, ideally with defer mu.Unlock() right after acquiring it.",
+		level: "warning",
+	},
+	detector.KindLockOrder: {
+		name:  "LockOrderCycle",
+		short: "Locks acquired in inconsistent order",
+		full:  "Two or more locks are acquired in different orders on different code paths (or a mutex is held while blocking on a channel), which can deadlock under the right interleaving.",
+		help:  "Always acquire locks in one global order, and do not hold a mutex across channel operations whose other side needs the same mutex.",
+		level: "error",
+	},
+	detector.KindDataRace: {
+		name:  "DataRace",
+		short: "Data race",
+		full:  "The race detector observed concurrent accesses to the same memory, at least one of them a write, without synchronization.",
+		help:  "Protect the shared variable with a mutex, use sync/atomic, or hand ownership over through a channel.",
+		level: "error",
+	},
+}
+
+// WriteSARIF writes result as a SARIF 2.1.0 log with one rule per finding
+// kind. Paths under the working directory are made relative to %SRCROOT% so
+// code scanning services can place the results in the repository.
+func WriteSARIF(w io.Writer, result *detector.Result) error {
+	driver := sarifDriver{
+		Name:           "ThreadGraph",
+		InformationURI: "https://github.com/Heman10x-NGU/threadgraph",
+	}
+	ruleIndex := make(map[detector.Kind]int)
+	for i, k := range detector.Kinds {
+		info := sarifRules[k]
+		ruleIndex[k] = i
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   string(k),
+			Name:                 info.name,
+			ShortDescription:     sarifText{info.short},
+			FullDescription:      sarifText{info.full},
+			Help:                 sarifHelp{Text: info.help},
+			DefaultConfiguration: sarifRuleConfig{Level: info.level},
+			Properties:           map[string]any{"tags": []string{"concurrency"}},
+		})
+	}
+
+	s := newSARIFPaths()
+	run := sarifRun{Tool: sarifTool{Driver: driver}, Results: []sarifResult{}}
+	rule := func(k detector.Kind) int {
+		if i, ok := ruleIndex[k]; ok {
+			return i
+		}
+		// A kind this reporter does not know, e.g. from a newer detector,
+		// gets a generic rule rather than index 0, another kind's rule.
+		ruleIndex[k] = len(run.Tool.Driver.Rules)
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{
+			ID:                   string(k),
+			Name:                 string(k),
+			ShortDescription:     sarifText{string(k)},
+			FullDescription:      sarifText{"A concurrency issue of kind " + string(k) + " reported by ThreadGraph."},
+			Help:                 sarifHelp{Text: "See the finding's message and stack."},
+			DefaultConfiguration: sarifRuleConfig{Level: "warning"},
+			Properties:           map[string]any{"tags": []string{"concurrency"}},
+		})
+		return ruleIndex[k]
+	}
+
+	baselineState := func(state string) string {
+		if result.Baseline == nil {
+			return ""
+		}
+		return state
+	}
+	for _, f := range result.Findings {
+		r := s.result(f, rule(f.Kind))
+		r.BaselineState = baselineState("new")
+		run.Results = append(run.Results, r)
+	}
+	if b := result.Baseline; b != nil {
+		for _, f := range b.Persisting {
+			r := s.result(f, rule(f.Kind))
+			r.BaselineState = "unchanged"
+			run.Results = append(run.Results, r)
+		}
+		for _, e := range b.Resolved {
+			r := s.result(detector.Finding{
+				Kind:      e.Kind,
+				Function:  e.Function,
+				Location:  e.Location,
+				BlockedOn: "no longer reproduces",
+			}, rule(e.Kind))
+			r.Level = "none"
+			r.BaselineState = "absent"
+			if e.Fingerprint != "" {
+				r.PartialFingerprints = map[string]string{sarifFingerprintKey: e.Fingerprint}
+			}
+			run.Results = append(run.Results, r)
+		}
+	}
+	if rp := result.Repeat; rp != nil {
+		for _, f := range rp.Flaky {
+			r := s.result(f, rule(f.Kind))
+			r.Level = "note"
+			r.Message.Text += fmt.Sprintf(" (flaky: seen in %d of %d runs)", f.Hits, f.Runs)
+			r.BaselineState = baselineState("new")
+			run.Results = append(run.Results, r)
+		}
+	}
+	for _, sf := range result.Suppressed {
+		r := s.result(sf.Finding, rule(sf.Finding.Kind))
+		kind := "external"
+		if strings.HasPrefix(sf.Rule, suppress.Directive) {
+			kind = "inSource"
+		}
+		r.Suppressions = []sarifSuppress{{Kind: kind, Justification: sf.Reason}}
+		run.Results = append(run.Results, r)
+	}
+
+	if s.root != "" {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactLoc{
+			"SRCROOT": {URI: fileURI(s.root) + "/"},
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{run},
+	}); err != nil {
+		return fmt.Errorf("encode sarif: %w", err)
+	}
+	return nil
+}
+
+// sarifPaths converts file:line locations to SARIF locations relative to
+// the working directory when possible.
+type sarifPaths struct {
+	root string // working directory, "" if unknown
+}
+
+func newSARIFPaths() *sarifPaths {
+	wd, err := os.Getwd()
+	if err != nil {
+		return &sarifPaths{}
+	}
+	return &sarifPaths{root: filepath.ToSlash(wd)}
+}
+
+// result converts f to a SARIF result: its location, its stack as a code
+// flow from the outermost frame down to the blocking one, and the 'go'
+// statement that started the goroutine as a related location.
+func (s *sarifPaths) result(f detector.Finding, ruleIndex int) sarifResult {
+	r := sarifResult{
+		RuleID:    string(f.Kind),
+		RuleIndex: ruleIndex,
+		Level:     sarifLevel(f.Confidence),
+		Message:   sarifText{sarifMessage(f)},
+	}
+	if loc := s.location(f.Location, ""); loc != nil {
+		r.Locations = []sarifLocation{*loc}
+	}
+	if loc := s.location(f.CreationLocation, "goroutine started here by "+f.CreationFunction); loc != nil {
+		loc.ID = 1
+		r.RelatedLocations = []sarifLocation{*loc}
+	}
+
+	// The flow runs from the outermost 'go' statement of the spawn path,
+	// through the one that started the goroutine, down its stack.
+	var flow []sarifThreadFlowLoc
+	for i, site := range f.SpawnPath {
+		if i == len(f.SpawnPath)-1 && site.Location == f.CreationLocation {
+			break
+		}
+		if loc := s.location(site.Location, "go statement in "+site.Function); loc != nil {
+			flow = append(flow, sarifThreadFlowLoc{Location: *loc})
+		}
+	}
+	if loc := s.location(f.CreationLocation, "go statement in "+f.CreationFunction); loc != nil {
+		flow = append(flow, sarifThreadFlowLoc{Location: *loc})
+	}
+	frames := stackFrames(f.Stack)
+	slices.Reverse(frames)
+	for _, fr := range frames {
+		if loc := s.location(fr[1], fr[0]); loc != nil {
+			flow = append(flow, sarifThreadFlowLoc{Location: *loc})
+		}
+	}
+	if len(flow) > 0 {
+		r.CodeFlows = []sarifCodeFlow{{ThreadFlows: []sarifThreadFlow{{Locations: flow}}}}
+	}
+
+	if f.Kind != "" && (f.Stack != "" || f.Function != "") {
+		r.PartialFingerprints = map[string]string{sarifFingerprintKey: f.Fingerprint()}
+	}
+	props := map[string]any{"confidence": string(f.Confidence)}
+	if f.GoroutineID != 0 {
+		props["goroutineId"] = uint64(f.GoroutineID)
+	}
+	if f.Count > 1 {
+		props["count"] = f.Count
+	}
+	if f.BlockedFor > 0 {
+		props["blockedForMs"] = f.BlockedFor.Milliseconds()
+	}
+	if f.Test != "" {
+		props["test"] = f.Test
+	}
+	if len(f.Tests) > 1 {
+		props["tests"] = f.Tests
+	}
+	if f.Package != "" {
+		props["package"] = f.Package
+	}
+	if f.Runs > 0 {
+		props["hits"], props["runs"] = f.Hits, f.Runs
+	}
+	if len(f.SpawnPath) > 0 {
+		path := make([]string, len(f.SpawnPath))
+		for i, site := range f.SpawnPath {
+			path[i] = site.Function + " (" + site.Location + ")"
+		}
+		props["spawnPath"] = path
+	}
+	r.Properties = props
+	return r
+}
+
+// location converts "file:line" to a SARIF location, or returns nil if
+// location is empty.
+func (s *sarifPaths) location(location, message string) *sarifLocation {
+	if location == "" {
+		return nil
+	}
+	file, line := location, 0
+	if i := strings.LastIndex(location, ":"); i >= 0 {
+		if n, err := strconv.Atoi(location[i+1:]); err == nil {
+			file, line = location[:i], n
+		}
+	}
+	file = filepath.ToSlash(file)
+
+	art := sarifArtifactLoc{URI: fileURI(file)}
+	if s.root != "" && strings.HasPrefix(file, s.root+"/") {
+		art = sarifArtifactLoc{URI: uriPath(strings.TrimPrefix(file, s.root+"/")), URIBaseID: "SRCROOT"}
+	} else if !filepath.IsAbs(file) {
+		art = sarifArtifactLoc{URI: uriPath(file)}
+	}
+	loc := &sarifLocation{PhysicalLocation: &sarifPhysicalLocation{ArtifactLocation: art}}
+	if line > 0 {
+		loc.PhysicalLocation.Region = &sarifRegion{StartLine: line}
+	}
+	if message != "" {
+		loc.Message = &sarifText{message}
+	}
+	return loc
+}
+
+// stackFrames splits a formatted stack ("      pkg.Func (/path/file.go:42)"
+// per line) into [function, location] pairs, innermost first.
+func stackFrames(stack string) [][2]string {
+	var frames [][2]string
+	for _, line := range strings.Split(stack, "\n") {
+		line = strings.TrimSpace(line)
+		i := strings.LastIndex(line, " (")
+		if i < 0 || !strings.HasSuffix(line, ")") {
+			continue
+		}
+		frames = append(frames, [2]string{line[:i], line[i+2 : len(line)-1]})
+	}
+	return frames
+}
+
+func sarifLevel(c detector.Confidence) string {
+	switch c {
+	case detector.ConfidenceHigh:
+		return "error"
+	case detector.ConfidenceMedium:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+func sarifMessage(f detector.Finding) string {
+	msg := sarifRules[f.Kind].short
+	if msg == "" {
+		msg = string(f.Kind)
+	}
+	if f.BlockedOn != "" {
+		msg += ": " + f.BlockedOn
+	}
+	if f.BlockedFor > 0 {
+		msg += fmt.Sprintf(" for %v", f.BlockedFor.Round(time.Millisecond))
+	}
+	if f.Function != "" {
+		msg += " in " + f.Function
+	}
+	if f.Count > 1 {
+		msg += fmt.Sprintf(" (%d goroutines)", f.Count)
+	}
+	return msg
+}
+
+// fileURI returns the file:// URI of an absolute slash-separated path.
+func fileURI(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path // Windows drive letter
+	}
+	return (&url.URL{Scheme: "file", Path: path}).String()
+}
+
+// uriPath escapes a relative slash-separated path for use as a URI.
+func uriPath(path string) string {
+	return (&url.URL{Path: path}).String()
+}
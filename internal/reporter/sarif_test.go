@@ -0,0 +1,77 @@
+package reporter
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+func TestWriteSARIFRules(t *testing.T) {
+	result := &detector.Result{Findings: []detector.Finding{
+		{
+			Kind:       detector.KindGoroutineLeak,
+			Confidence: detector.ConfidenceHigh,
+			BlockedOn:  "chan receive",
+			BlockedFor: 1234567891 * time.Nanosecond,
+			Function:   "example.com/app.worker",
+			Location:   "/app/worker.go:7",
+		},
+		{
+			Kind:       "goroutine_storm",
+			Confidence: detector.ConfidenceMedium,
+			Function:   "example.com/app.spawn",
+			Location:   "/app/spawn.go:3",
+		},
+	}}
+	var buf bytes.Buffer
+	if err := WriteSARIF(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+
+	var log struct {
+		Runs []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID string `json:"id"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Message   struct {
+					Text string `json:"text"`
+				} `json:"message"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	run := log.Runs[0]
+	rules := run.Tool.Driver.Rules
+	if len(rules) != len(detector.Kinds)+1 {
+		t.Fatalf("got %d rules, want one per kind and one for goroutine_storm", len(rules))
+	}
+	if len(run.Results) != 2 {
+		t.Fatalf("got %d results, want 2", len(run.Results))
+	}
+	for _, r := range run.Results {
+		if r.RuleIndex < 0 || r.RuleIndex >= len(rules) || rules[r.RuleIndex].ID != r.RuleID {
+			t.Errorf("result of %s points at rule %d", r.RuleID, r.RuleIndex)
+		}
+	}
+
+	leak, storm := run.Results[0], run.Results[1]
+	if want := "Goroutine blocked forever: chan receive for 1.235s in example.com/app.worker"; leak.Message.Text != want || leak.Level != "error" {
+		t.Errorf("leak: %s %q, want error %q", leak.Level, leak.Message.Text, want)
+	}
+	if want := "goroutine_storm in example.com/app.spawn"; storm.Message.Text != want || storm.Level != "warning" {
+		t.Errorf("unknown kind: %s %q, want warning %q", storm.Level, storm.Message.Text, want)
+	}
+}
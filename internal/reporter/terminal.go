@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"time"
 
 	"github.com/Heman10x-NGU/threadgraph/internal/detector"
 	"github.com/fatih/color"
@@ -70,12 +71,83 @@ func WriteTerminal(w io.Writer, result *detector.Result, explanation string) {
 		green.Fprintln(w, "  No concurrency issues detected.")
 	}
 
+	// Findings grouped by spawn path
+	if patterns := detector.Patterns(result.Findings); showPatterns(patterns) {
+		printPatterns(w, patterns)
+	}
+
 	// Individual findings
 	for _, f := range result.Findings {
 		fmt.Fprintln(w)
 		printFinding(w, f)
 	}
 
+	// Stress-run leak rates
+	if len(result.LeakRates) > 0 {
+		fmt.Fprintln(w)
+		bold.Fprintln(w, "  Leak Rate")
+		fmt.Fprintln(w)
+		for _, lr := range result.LeakRates {
+			printLeakRate(w, lr)
+		}
+	}
+
+	// Findings below --min-hits
+	if rp := result.Repeat; rp != nil && len(rp.Flaky) > 0 {
+		fmt.Fprintln(w)
+		yellow.Fprintf(w, "  Flaky (%d) — seen in fewer than %d of %d runs\n", len(rp.Flaky), rp.MinHits, rp.Runs)
+		fmt.Fprintln(w)
+		for _, f := range rp.Flaky {
+			where := f.Location
+			if where == "" {
+				where = f.Function
+			}
+			fmt.Fprintf(w, "    %-15s %s", f.Kind, where)
+			dim.Fprintf(w, "  %d/%d runs\n", f.Hits, f.Runs)
+		}
+	}
+
+	// Suppression rules
+	if len(result.Suppressed) > 0 {
+		fmt.Fprintln(w)
+		bold.Fprintf(w, "  Suppressed (%d)\n", len(result.Suppressed))
+		fmt.Fprintln(w)
+		for _, sf := range result.Suppressed {
+			printSuppressed(w, sf)
+		}
+	}
+	if len(result.UnusedSuppressions) > 0 {
+		fmt.Fprintln(w)
+		yellow.Fprintln(w, "  Unused suppression rules")
+		fmt.Fprintln(w)
+		for _, r := range result.UnusedSuppressions {
+			dim.Fprintf(w, "    %s\n", r)
+		}
+	}
+
+	// Baseline comparison
+	if b := result.Baseline; b != nil {
+		fmt.Fprintln(w)
+		bold.Fprintf(w, "  Baseline %s", b.File)
+		dim.Fprintf(w, "  (%d new · %d still present · %d resolved)\n",
+			len(result.Findings), len(b.Persisting), len(b.Resolved))
+		if len(b.Persisting) > 0 {
+			fmt.Fprintln(w)
+			yellow.Fprintf(w, "    Still present (%d)\n", len(b.Persisting))
+			for _, f := range b.Persisting {
+				printBaselineEntry(w, f.Kind, f.Location, f.Function, f.Count)
+			}
+		}
+		if len(b.Resolved) > 0 {
+			fmt.Fprintln(w)
+			green.Fprintf(w, "    Resolved (%d)\n", len(b.Resolved))
+			for _, e := range b.Resolved {
+				printBaselineEntry(w, e.Kind, e.Location, e.Function, 0)
+			}
+			dim.Fprintln(w, "    Run 'threadgraph baseline prune' to drop resolved entries.")
+		}
+	}
+
 	// LLM explanation
 	if explanation != "" {
 		fmt.Fprintln(w)
@@ -89,8 +161,26 @@ func WriteTerminal(w io.Writer, result *detector.Result, explanation string) {
 	// Footer
 	fmt.Fprintln(w)
 	fmt.Fprintln(w, separator)
-	dim.Fprintf(w, "  Analyzed %d goroutines · %dms window · %s\n",
-		result.GoroutinesAnalyzed, result.DurationMs, result.TraceFile)
+	if len(result.Packages) > 0 {
+		dim.Fprintf(w, "  Analyzed %d goroutines · %dms total · %s\n",
+			result.GoroutinesAnalyzed, result.DurationMs, pluralize(len(result.Packages), "package"))
+		for _, p := range result.Packages {
+			dim.Fprintf(w, "    %-50s %5d goroutines · %dms\n", p.Package, p.GoroutinesAnalyzed, p.DurationMs)
+		}
+	} else {
+		dim.Fprintf(w, "  Analyzed %d goroutines · %dms window · %s\n",
+			result.GoroutinesAnalyzed, result.DurationMs, result.TraceFile)
+	}
+	if win := result.Window; win != nil {
+		if win.Region != "" {
+			dim.Fprintf(w, "  Window: region %q (%v – %v into the trace)\n", win.Region, win.From.Round(time.Millisecond), win.To.Round(time.Millisecond))
+		} else {
+			dim.Fprintf(w, "  Window: %v – %v into the trace\n", win.From.Round(time.Millisecond), win.To.Round(time.Millisecond))
+		}
+	}
+	if result.PeakHeapBytes > 0 {
+		dim.Fprintf(w, "  Peak analyzer heap: %.1f MB\n", float64(result.PeakHeapBytes)/(1<<20))
+	}
 	fmt.Fprintln(w)
 }
 
@@ -131,10 +221,40 @@ func printFinding(w io.Writer, f detector.Finding) {
 		cyan.Fprintf(w, "%s\n", f.Location)
 	}
 
+	if f.Package != "" {
+		fmt.Fprintf(w, "  Package: ")
+		cyan.Fprintf(w, "%s\n", f.Package)
+	}
+
+	if len(f.Tests) > 1 {
+		fmt.Fprintf(w, "  Tests: ")
+		cyan.Fprintf(w, "%s\n", strings.Join(f.Tests, ", "))
+	} else if f.Test != "" {
+		fmt.Fprintf(w, "  Test: ")
+		cyan.Fprintf(w, "%s\n", f.Test)
+	}
+
 	if f.Count > 1 {
 		dim.Fprintf(w, "  × %d goroutines affected\n", f.Count)
 	}
 
+	if len(f.SpawnPath) > 0 {
+		fmt.Fprintf(w, "  Spawned via: ")
+		cyan.Fprintf(w, "%s\n", spawnPathString(f.SpawnPath))
+	}
+
+	if f.Runs > 0 {
+		fmt.Fprintf(w, "  Reproduced: ")
+		cyan.Fprintf(w, "%d/%d runs\n", f.Hits, f.Runs)
+	}
+
+	if len(f.Evidence) > 0 {
+		fmt.Fprintln(w, "  Evidence:")
+		for _, e := range f.Evidence {
+			fmt.Fprintf(w, "    %s\n", e)
+		}
+	}
+
 	if f.Stack != "" {
 		fmt.Fprintln(w, "  Stack:")
 		for _, line := range strings.Split(strings.TrimRight(f.Stack, "\n"), "\n") {
@@ -143,6 +263,91 @@ func printFinding(w io.Writer, f detector.Finding) {
 	}
 }
 
+func printPatterns(w io.Writer, patterns []detector.Pattern) {
+	total := 0
+	for _, p := range patterns {
+		total += p.Goroutines
+	}
+	fmt.Fprintln(w)
+	bold.Fprintf(w, "  Patterns")
+	dim.Fprintf(w, "  %s → %s affected, by spawn path\n",
+		pluralizeWith(len(patterns), "unique pattern", "unique patterns"), pluralize(total, "goroutine"))
+	fmt.Fprintln(w)
+	for _, l := range patternLines(patternTree(patterns)) {
+		if l.node != nil {
+			fmt.Fprintf(w, "    %sgo %s", l.prefix, siteLabel(l.node.site))
+			dim.Fprintf(w, "  %s\n", pluralize(l.node.goroutines, "goroutine"))
+			continue
+		}
+		f := l.finding
+		where := f.Location
+		if where == "" {
+			where = f.Function
+		}
+		fmt.Fprintf(w, "    %s", l.prefix)
+		switch f.Kind {
+		case detector.KindGoroutineLeak, detector.KindDeadlock:
+			red.Fprintf(w, "%s", f.Kind)
+		default:
+			yellow.Fprintf(w, "%s", f.Kind)
+		}
+		fmt.Fprintf(w, " %s", where)
+		if f.Count > 1 {
+			dim.Fprintf(w, " (×%d)", f.Count)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func printSuppressed(w io.Writer, sf detector.Suppressed) {
+	f := sf.Finding
+	where := f.Location
+	if where == "" {
+		where = f.Function
+	}
+	fmt.Fprintf(w, "    %-15s %s", f.Kind, where)
+	if f.Count > 1 {
+		dim.Fprintf(w, " (×%d)", f.Count)
+	}
+	fmt.Fprintln(w)
+	dim.Fprintf(w, "      %s — %s\n", sf.Reason, sf.Rule)
+}
+
+func printBaselineEntry(w io.Writer, kind detector.Kind, location, function string, count int) {
+	if location == "" {
+		location = function
+	}
+	fmt.Fprintf(w, "      %-15s %s", kind, location)
+	if count > 1 {
+		dim.Fprintf(w, " (×%d)", count)
+	}
+	fmt.Fprintln(w)
+}
+
+func printLeakRate(w io.Writer, lr detector.LeakRate) {
+	leaking := 0
+	for _, n := range lr.PerInvocation {
+		if n > 0 {
+			leaking++
+		}
+	}
+	name := lr.Test
+	if lr.Package != "" {
+		name = lr.Package + " " + lr.Test
+	}
+	if lr.Linear && lr.Leaked%lr.Invocations == 0 {
+		red.Fprintf(w, "  ● %s leaks %s per invocation", name, pluralize(lr.Leaked/lr.Invocations, "goroutine"))
+	} else {
+		yellow.Fprintf(w, "  ● %s leaks %.2f goroutines per invocation on average", name, lr.Rate)
+	}
+	growth := "irregular growth"
+	if lr.Linear {
+		growth = "linear growth"
+	}
+	dim.Fprintf(w, "  (%d/%d invocations leaked · %s)\n", leaking, lr.Invocations, growth)
+	dim.Fprintf(w, "    per invocation: %v\n", lr.PerInvocation)
+}
+
 func countKind(findings []detector.Finding, kind detector.Kind) int {
 	n := 0
 	for _, f := range findings {
@@ -45,6 +45,25 @@ type LockOrderFinding struct {
 // maxCallDepth limits interprocedural expansion to avoid combinatorial blowup.
 const maxCallDepth = 4
 
+// LockGraph is the global lock-ordering graph AnalyzeLockOrder searches for
+// cycles. Nodes are type-level lock IDs; an edge From→To means some code
+// path acquires To while holding From.
+type LockGraph struct {
+	Edges []LockEdge // sorted by From and To
+	// Cycles are the strongly connected components of two or more locks,
+	// each sorted: the lock-ordering cycles AnalyzeLockOrder reports.
+	Cycles [][]string
+}
+
+// LockEdge is an edge of a LockGraph, with the first function and source
+// location seen acquiring To while holding From.
+type LockEdge struct {
+	From     string
+	To       string
+	Function string
+	Location string
+}
+
 // AnalyzeLockOrder loads the given Go package patterns and finds functions
 // that, together, acquire sync.Mutex / sync.RWMutex locks in an inconsistent
 // order — a necessary condition for AB-BA deadlocks.
@@ -53,6 +72,51 @@ const maxCallDepth = 4
 // same field accessed via different parameter names in different functions is
 // treated as the same lock.
 func AnalyzeLockOrder(pkgPatterns []string) ([]LockOrderFinding, error) {
+	g, err := LockOrderGraph(pkgPatterns)
+	if err != nil {
+		return nil, err
+	}
+
+	// --- Convert SCCs to findings ---
+	var findings []LockOrderFinding
+	for _, scc := range g.Cycles {
+		sccSet := make(map[string]bool, len(scc))
+		for _, n := range scc {
+			sccSet[n] = true
+		}
+
+		// Find a representative edge (best source location).
+		repFunc := ""
+		repLoc := ""
+		for _, e := range g.Edges {
+			if !sccSet[e.From] || !sccSet[e.To] {
+				continue
+			}
+			if repLoc == "" || e.Location != "" {
+				repFunc = e.Function
+				repLoc = e.Location
+			}
+		}
+
+		desc := "AB-BA lock inversion"
+		if len(scc) > 2 {
+			desc = fmt.Sprintf("%d-way lock cycle", len(scc))
+		}
+
+		findings = append(findings, LockOrderFinding{
+			Cycle:    scc,
+			Location: repLoc,
+			Function: repFunc,
+			Message:  fmt.Sprintf("lock ordering cycle (%s): %s", desc, strings.Join(scc, " → ")),
+		})
+	}
+
+	return findings, nil
+}
+
+// LockOrderGraph loads the given Go package patterns and builds their
+// lock-ordering graph, as AnalyzeLockOrder does.
+func LockOrderGraph(pkgPatterns []string) (*LockGraph, error) {
 	cfg := &packages.Config{
 		Mode: packages.NeedName |
 			packages.NeedFiles |
@@ -325,54 +389,25 @@ func AnalyzeLockOrder(pkgPatterns []string) ([]LockOrderFinding, error) {
 		}
 	}
 
-	// --- Convert SCCs to findings ---
-	var findings []LockOrderFinding
-	seenSCC := make(map[string]bool)
-
-	for _, scc := range sccs {
-		sorted := make([]string, len(scc))
-		copy(sorted, scc)
-		sort.Strings(sorted)
-		key := strings.Join(sorted, "|")
-		if seenSCC[key] {
-			continue
-		}
-		seenSCC[key] = true
-
-		// Find a representative edge (best source location).
-		repFunc := ""
-		repLoc := ""
-		for _, from := range scc {
-			sccSet := make(map[string]bool, len(scc))
-			for _, n := range scc {
-				sccSet[n] = true
-			}
-			for to := range lockGraph[from] {
-				if !sccSet[to] {
-					continue
-				}
-				info := lockGraph[from][to]
-				if repLoc == "" || info.location != "" {
-					repFunc = info.function
-					repLoc = info.location
-				}
-			}
+	// --- Export the graph, sorted ---
+	g := &LockGraph{}
+	for from, tos := range lockGraph {
+		for to, info := range tos {
+			g.Edges = append(g.Edges, LockEdge{From: from, To: to, Function: info.function, Location: info.location})
 		}
-
-		desc := "AB-BA lock inversion"
-		if len(scc) > 2 {
-			desc = fmt.Sprintf("%d-way lock cycle", len(scc))
+	}
+	sort.Slice(g.Edges, func(i, j int) bool {
+		if g.Edges[i].From != g.Edges[j].From {
+			return g.Edges[i].From < g.Edges[j].From
 		}
-
-		findings = append(findings, LockOrderFinding{
-			Cycle:    sorted,
-			Location: repLoc,
-			Function: repFunc,
-			Message:  fmt.Sprintf("lock ordering cycle (%s): %s", desc, strings.Join(sorted, " → ")),
-		})
+		return g.Edges[i].To < g.Edges[j].To
+	})
+	for _, scc := range sccs {
+		sort.Strings(scc)
+		g.Cycles = append(g.Cycles, scc)
 	}
-
-	return findings, nil
+	sort.Slice(g.Cycles, func(i, j int) bool { return g.Cycles[i][0] < g.Cycles[j][0] })
+	return g, nil
 }
 
 // typeLockID returns a type-level structural identity for a mutex receiver value.
@@ -0,0 +1,174 @@
+package suppress
+
+import (
+	"bufio"
+	"fmt"
+	"go/build"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+// Directive is the comment prefix of an inline suppression:
+//
+//	go worker(ch) //threadgraph:ignore goroutine_leak drained on shutdown
+//
+// It names one or more comma-separated finding kinds, followed by the
+// mandatory reason. It applies to the line it ends, or, written on its own
+// line, to the first non-comment line below it.
+const Directive = "//threadgraph:ignore"
+
+// inline is one parsed //threadgraph:ignore directive.
+type inline struct {
+	kinds  []string
+	reason string
+	at     string // file:line of the directive
+}
+
+// ApplyInline removes the findings whose location, blocking line, or
+// goroutine's 'go' statement carries a //threadgraph:ignore directive for
+// the finding's kind, and records them in result.Suppressed. Source files are read from the
+// locations in the findings; files that cannot be read (stdlib, other
+// machines) are skipped. Malformed directives are not applied and are
+// returned as warnings.
+func ApplyInline(result *detector.Result) (warnings []string) {
+	files := make(map[string][]string)
+	warned := make(map[string]bool)
+	lookup := func(location string) *inline {
+		d, err := directiveAt(files, location)
+		if err != nil {
+			if !warned[err.Error()] {
+				warned[err.Error()] = true
+				warnings = append(warnings, err.Error())
+			}
+			return nil
+		}
+		return d
+	}
+
+	var kept []detector.Finding
+	for _, f := range result.Findings {
+		var d *inline
+		for _, loc := range []string{f.Location, callerLocation(f), f.CreationLocation} {
+			if d = lookup(loc); d != nil && slices.Contains(d.kinds, string(f.Kind)) {
+				break
+			}
+			d = nil
+		}
+		if d == nil {
+			kept = append(kept, f)
+			continue
+		}
+		result.Suppressed = append(result.Suppressed, detector.Suppressed{
+			Finding: f,
+			Rule:    fmt.Sprintf("%s %s at %s", Directive, strings.Join(d.kinds, ","), d.at),
+			Reason:  d.reason,
+		})
+	}
+	result.Findings = kept
+	return warnings
+}
+
+// callerLocation returns the first frame of f's stack outside GOROOT when
+// f.Location lies inside it, as for a mutex finding located at
+// sync.(*Mutex).Lock: the line that blocked is the caller's.
+func callerLocation(f detector.Finding) string {
+	goroot := filepath.ToSlash(build.Default.GOROOT) + "/"
+	if build.Default.GOROOT == "" || !strings.HasPrefix(f.Location, goroot) {
+		return ""
+	}
+	for _, frame := range strings.Split(f.Stack, "\n") {
+		// "      pkg.Func (/path/file.go:42)"
+		i := strings.LastIndex(frame, " (")
+		if i < 0 || !strings.HasSuffix(frame, ")") {
+			continue
+		}
+		if loc := frame[i+2 : len(frame)-1]; !strings.HasPrefix(loc, goroot) {
+			return loc
+		}
+	}
+	return ""
+}
+
+// directiveAt returns the directive that applies to the "file:line"
+// location, or nil if there is none or the file cannot be read. files caches
+// the lines of every file read so far.
+func directiveAt(files map[string][]string, location string) (*inline, error) {
+	i := strings.LastIndex(location, ":")
+	if i < 0 {
+		return nil, nil
+	}
+	path := location[:i]
+	lineNo, err := strconv.Atoi(location[i+1:])
+	if err != nil || lineNo <= 0 {
+		return nil, nil
+	}
+	lines, ok := files[path]
+	if !ok {
+		lines = readLines(path)
+		files[path] = lines
+	}
+	if lineNo > len(lines) {
+		return nil, nil
+	}
+
+	// A trailing comment on the line itself.
+	if j := strings.Index(lines[lineNo-1], Directive); j >= 0 {
+		return parseDirective(lines[lineNo-1][j:], fmt.Sprintf("%s:%d", path, lineNo))
+	}
+	// The comment block directly above the line.
+	for n := lineNo - 1; n >= 1; n-- {
+		text := strings.TrimSpace(lines[n-1])
+		if !strings.HasPrefix(text, "//") {
+			break
+		}
+		if strings.HasPrefix(text, Directive) {
+			return parseDirective(text, fmt.Sprintf("%s:%d", path, n))
+		}
+	}
+	return nil, nil
+}
+
+// parseDirective parses "//threadgraph:ignore kind[,kind] reason".
+func parseDirective(text, at string) (*inline, error) {
+	rest := strings.TrimPrefix(text, Directive)
+	if rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+		return nil, nil // e.g. //threadgraph:ignored
+	}
+	fields := strings.Fields(rest)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("%s: %s needs a finding kind and a reason", at, Directive)
+	}
+	d := &inline{at: at, kinds: strings.Split(fields[0], ",")}
+	for _, k := range d.kinds {
+		if !slices.Contains(detector.Kinds, detector.Kind(k)) {
+			return nil, fmt.Errorf("%s: %s: unknown kind %q", at, Directive, k)
+		}
+	}
+	d.reason = strings.Join(fields[1:], " ")
+	if d.reason == "" {
+		return nil, fmt.Errorf("%s: %s: reason is required", at, Directive)
+	}
+	return d, nil
+}
+
+// readLines returns the lines of the file at path, or nil if it cannot be
+// read.
+func readLines(path string) []string {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil // file not accessible (e.g. stdlib, vendor)
+	}
+	defer f.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
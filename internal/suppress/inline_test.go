@@ -0,0 +1,165 @@
+package suppress
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+// inlineSource is a Go file with directives in every position; a finding's
+// line is the index of its line here plus one.
+var inlineSource = []string{
+	/* 1 */ "package app",
+	/* 2 */ "",
+	/* 3 */ "func run(ch chan int) {",
+	/* 4 */ "\tgo worker(ch) //threadgraph:ignore goroutine_leak drained on shutdown",
+	/* 5 */ "",
+	/* 6 */ "\t//threadgraph:ignore goroutine_leak,long_block pool workers",
+	/* 7 */ "\t// live until Close.",
+	/* 8 */ "\tgo worker(ch)",
+	/* 9 */ "",
+	/* 10 */ "\t//threadgraph:ignore goroutine_leak",
+	/* 11 */ "\tgo worker(ch)",
+	/* 12 */ "",
+	/* 13 */ "\t//threadgraph:ignore leak,deadlock typo in the kind",
+	/* 14 */ "\tgo worker(ch)",
+	/* 15 */ "",
+	/* 16 */ "\t//threadgraph:ignore deadlock not for the line below the code",
+	/* 17 */ "\tx := 1",
+	/* 18 */ "\tgo worker(ch)",
+	/* 19 */ "\t//threadgraph:ignored goroutine_leak not a directive",
+	/* 20 */ "\tgo worker(ch)",
+	/* 21 */ "\tgo worker(ch) //threadgraph:ignore",
+	/* 22 */ "}",
+}
+
+func writeInlineSource(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "app.go")
+	if err := os.WriteFile(path, []byte(strings.Join(inlineSource, "\n")+"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestDirectiveAt(t *testing.T) {
+	path := writeInlineSource(t)
+	for _, tc := range []struct {
+		line   string
+		kinds  []string
+		reason string
+		at     string // line of the directive
+		err    string
+	}{
+		{line: "4", kinds: []string{"goroutine_leak"}, reason: "drained on shutdown", at: "4"},
+		{line: "8", kinds: []string{"goroutine_leak", "long_block"}, reason: "pool workers", at: "6"},
+		{line: "7", kinds: []string{"goroutine_leak", "long_block"}, reason: "pool workers", at: "6"},
+		{line: "11", err: ":10: //threadgraph:ignore: reason is required"},
+		{line: "14", err: `:13: //threadgraph:ignore: unknown kind "leak"`},
+		{line: "18"},
+		{line: "20"},
+		{line: "21", err: ":21: //threadgraph:ignore needs a finding kind and a reason"},
+		{line: "1"},
+		{line: "99"},
+		{line: "0"},
+	} {
+		d, err := directiveAt(make(map[string][]string), path+":"+tc.line)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("line %s: error = %v, want %q", tc.line, err, tc.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("line %s: %v", tc.line, err)
+			continue
+		}
+		if tc.kinds == nil {
+			if d != nil {
+				t.Errorf("line %s: got directive %+v, want none", tc.line, *d)
+			}
+			continue
+		}
+		if d == nil {
+			t.Errorf("line %s: no directive, want %v", tc.line, tc.kinds)
+			continue
+		}
+		if !reflect.DeepEqual(d.kinds, tc.kinds) || d.reason != tc.reason || d.at != path+":"+tc.at {
+			t.Errorf("line %s: got %v %q at %s, want %v %q at line %s", tc.line, d.kinds, d.reason, d.at, tc.kinds, tc.reason, tc.at)
+		}
+	}
+
+	if d, err := directiveAt(make(map[string][]string), "/no/such/file.go:4"); d != nil || err != nil {
+		t.Errorf("unreadable file: %v, %v; want nothing", d, err)
+	}
+}
+
+func TestApplyInline(t *testing.T) {
+	path := writeInlineSource(t)
+	at := func(line string) string { return path + ":" + line }
+	findings := []detector.Finding{
+		// Suppressed at its location.
+		{Kind: detector.KindGoroutineLeak, Function: "app.worker", Location: at("4")},
+		// Suppressed at its 'go' statement.
+		{Kind: detector.KindLongBlock, Function: "app.worker", Location: "/elsewhere/worker.go:3", CreationLocation: at("8")},
+		// The directive names another kind.
+		{Kind: detector.KindDeadlock, Function: "app.worker", Location: at("4")},
+		// Malformed directives, reported once each.
+		{Kind: detector.KindGoroutineLeak, Function: "app.worker", Location: at("11")},
+		{Kind: detector.KindGoroutineLeak, Function: "app.worker", Location: "/elsewhere/worker.go:3", CreationLocation: at("11")},
+		{Kind: detector.KindGoroutineLeak, Function: "app.worker", Location: at("14")},
+	}
+	result := &detector.Result{Findings: findings}
+	warnings := ApplyInline(result)
+
+	if !reflect.DeepEqual(result.Findings, findings[2:]) {
+		t.Errorf("kept %+v, want the last four findings", result.Findings)
+	}
+	if len(result.Suppressed) != 2 {
+		t.Fatalf("got %d suppressed findings, want 2", len(result.Suppressed))
+	}
+	if s := result.Suppressed[0]; s.Rule != Directive+" goroutine_leak at "+at("4") || s.Reason != "drained on shutdown" {
+		t.Errorf("suppressed[0] by %q (%q)", s.Rule, s.Reason)
+	}
+	if s := result.Suppressed[1]; s.Rule != Directive+" goroutine_leak,long_block at "+at("6") || s.Reason != "pool workers" {
+		t.Errorf("suppressed[1] by %q (%q)", s.Rule, s.Reason)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], ":10:") || !strings.Contains(warnings[1], ":13:") {
+		t.Errorf("warnings = %q, want one each for lines 10 and 13", warnings)
+	}
+}
+
+func TestParseDirective(t *testing.T) {
+	for _, tc := range []struct {
+		text, reason string
+		kinds        []string
+		err          string
+	}{
+		{text: "//threadgraph:ignore goroutine_leak owned by the pool", kinds: []string{"goroutine_leak"}, reason: "owned by the pool"},
+		{text: "//threadgraph:ignore\tdeadlock,long_block  waits for   the test", kinds: []string{"deadlock", "long_block"}, reason: "waits for the test"},
+		{text: "//threadgraph:ignored goroutine_leak reason"},
+		{text: "//threadgraph:ignore", err: "needs a finding kind and a reason"},
+		{text: "//threadgraph:ignore goroutine_leak", err: "reason is required"},
+		{text: "//threadgraph:ignore goroutine_leak,", err: `unknown kind ""`},
+		{text: "//threadgraph:ignore race fixed upstream", err: `unknown kind "race"`},
+	} {
+		d, err := parseDirective(tc.text, "a.go:1")
+		switch {
+		case tc.err != "":
+			if err == nil || !strings.HasPrefix(err.Error(), "a.go:1: ") || !strings.Contains(err.Error(), tc.err) {
+				t.Errorf("%q: error = %v, want %q", tc.text, err, tc.err)
+			}
+		case err != nil:
+			t.Errorf("%q: %v", tc.text, err)
+		case tc.kinds == nil:
+			if d != nil {
+				t.Errorf("%q: got directive %+v, want none", tc.text, *d)
+			}
+		case d == nil || !reflect.DeepEqual(d.kinds, tc.kinds) || d.reason != tc.reason:
+			t.Errorf("%q: got %+v, want %v %q", tc.text, d, tc.kinds, tc.reason)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Package suppress implements suppression rules: findings that a project
+// has reviewed and accepts, such as intentional long-lived workers, are
+// removed from a Result together with the reason they are accepted.
+//
+// Unlike a baseline, which matches exact file:line locations and goes stale
+// when code moves, a rule matches findings by kind, function, a location
+// glob, or any frame of the stack.
+package suppress
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+// lineSuffix matches a glob that names a line (":42" or ":*").
+var lineSuffix = regexp.MustCompile(`:(\d+|\*)$`)
+
+// Rule is one suppression rule. Every matcher that is set must match; at
+// least one must be set, and Reason is mandatory.
+type Rule struct {
+	// Kind restricts the rule to one finding kind (e.g. "goroutine_leak").
+	Kind string `yaml:"kind"`
+	// Function is a regexp matched against the finding's top user-code
+	// function.
+	Function string `yaml:"function"`
+	// Location is a glob matched against the finding's file:line location.
+	// "*" matches within a path element and "**" across elements; a glob
+	// without a line number matches any line, and a relative glob matches
+	// at any directory boundary ("pool/*.go" matches "/src/app/pool/w.go:12").
+	Location string `yaml:"location"`
+	// Stack is a regexp matched against each frame of the finding's stack
+	// ("pkg.Func (/path/file.go:42)"); any matching frame is enough.
+	Stack string `yaml:"stack"`
+	// Reason says why findings matching the rule are acceptable.
+	Reason string `yaml:"reason"`
+}
+
+// String describes r for reports, e.g. `function=^pool\. location=pool/*.go`.
+func (r Rule) String() string {
+	var parts []string
+	for _, m := range [][2]string{{"kind", r.Kind}, {"function", r.Function}, {"location", r.Location}, {"stack", r.Stack}} {
+		if m[1] != "" {
+			parts = append(parts, m[0]+"="+m[1])
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+// Set is a compiled list of rules.
+type Set struct {
+	rules []compiled
+}
+
+type compiled struct {
+	Rule
+	function *regexp.Regexp
+	location *regexp.Regexp
+	stack    *regexp.Regexp
+}
+
+// Compile validates and compiles rules. Errors name the rule by its index in
+// the list, prefixed with name (e.g. "ignore[2]").
+func Compile(name string, rules []Rule) (*Set, error) {
+	s := &Set{}
+	for i, r := range rules {
+		c := compiled{Rule: r}
+		where := fmt.Sprintf("%s[%d]", name, i)
+		if r.Kind == "" && r.Function == "" && r.Location == "" && r.Stack == "" {
+			return nil, fmt.Errorf("%s: needs at least one of kind, function, location, stack", where)
+		}
+		if strings.TrimSpace(r.Reason) == "" {
+			return nil, fmt.Errorf("%s: reason is required", where)
+		}
+		if r.Kind != "" && !slices.Contains(detector.Kinds, detector.Kind(r.Kind)) {
+			var kinds []string
+			for _, k := range detector.Kinds {
+				kinds = append(kinds, string(k))
+			}
+			return nil, fmt.Errorf("%s.kind: unknown kind %q (known: %s)", where, r.Kind, strings.Join(kinds, ", "))
+		}
+		var err error
+		if r.Function != "" {
+			if c.function, err = regexp.Compile(r.Function); err != nil {
+				return nil, fmt.Errorf("%s.function: %v", where, err)
+			}
+		}
+		if r.Location != "" {
+			c.location = globRegexp(r.Location)
+		}
+		if r.Stack != "" {
+			if c.stack, err = regexp.Compile(r.Stack); err != nil {
+				return nil, fmt.Errorf("%s.stack: %v", where, err)
+			}
+		}
+		s.rules = append(s.rules, c)
+	}
+	return s, nil
+}
+
+// Len returns the number of rules in s.
+func (s *Set) Len() int {
+	return len(s.rules)
+}
+
+// Apply removes the findings matched by a rule from result.Findings, records
+// them in result.Suppressed, and lists the rules that matched nothing in
+// result.UnusedSuppressions. The first matching rule wins.
+func (s *Set) Apply(result *detector.Result) {
+	used := make([]bool, len(s.rules))
+	var kept []detector.Finding
+	for _, f := range result.Findings {
+		i := s.match(f)
+		if i < 0 {
+			kept = append(kept, f)
+			continue
+		}
+		used[i] = true
+		result.Suppressed = append(result.Suppressed, detector.Suppressed{
+			Finding: f,
+			Rule:    s.rules[i].String(),
+			Reason:  s.rules[i].Reason,
+		})
+	}
+	result.Findings = kept
+	for i, r := range s.rules {
+		if !used[i] {
+			result.UnusedSuppressions = append(result.UnusedSuppressions,
+				fmt.Sprintf("%s (%s)", r.String(), r.Reason))
+		}
+	}
+}
+
+// match returns the index of the first rule matching f, or -1.
+func (s *Set) match(f detector.Finding) int {
+	for i, r := range s.rules {
+		if r.matches(f) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *compiled) matches(f detector.Finding) bool {
+	if r.Kind != "" && string(f.Kind) != r.Kind {
+		return false
+	}
+	if r.function != nil && !r.function.MatchString(f.Function) {
+		return false
+	}
+	if r.location != nil && !r.location.MatchString(f.Location) {
+		return false
+	}
+	if r.stack != nil && !r.stackMatches(f.Stack) {
+		return false
+	}
+	return true
+}
+
+func (r *compiled) stackMatches(stack string) bool {
+	for _, frame := range strings.Split(stack, "\n") {
+		if frame = strings.TrimSpace(frame); frame != "" && r.stack.MatchString(frame) {
+			return true
+		}
+	}
+	return false
+}
+
+// globRegexp converts a location glob to an anchored regexp. "**" matches
+// any run of characters, "*" any run without "/", "?" one non-"/"
+// character. A glob without ":line" matches any line.
+func globRegexp(glob string) *regexp.Regexp {
+	var b strings.Builder
+	if !strings.HasPrefix(glob, "/") && !strings.HasPrefix(glob, "**") {
+		b.WriteString("(?:^|/)")
+	} else {
+		b.WriteString("^")
+	}
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; {
+		case c == '*' && i+1 < len(glob) && glob[i+1] == '*':
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if !lineSuffix.MatchString(glob) {
+		b.WriteString(`(?::\d+)?`)
+	}
+	b.WriteString("$")
+	return regexp.MustCompile(b.String())
+}
@@ -0,0 +1,108 @@
+package suppress
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+)
+
+func TestGlobRegexp(t *testing.T) {
+	for _, tc := range []struct {
+		glob, location string
+		want           bool
+	}{
+		// "*" stays within a path element.
+		{"/src/app/pool/*.go", "/src/app/pool/worker.go:12", true},
+		{"/src/app/*.go", "/src/app/pool/worker.go:12", false},
+		{"/src/app/pool/w?rker.go", "/src/app/pool/worker.go:12", true},
+		// "**" crosses elements.
+		{"/src/**/worker.go", "/src/app/pool/worker.go:12", true},
+		{"**/pool/*.go", "/src/app/pool/worker.go:12", true},
+		{"/src/**.go", "/src/app/pool/worker.go:12", true},
+		{"/other/**", "/src/app/pool/worker.go:12", false},
+		// A relative glob matches at any directory boundary.
+		{"pool/*.go", "/src/app/pool/worker.go:12", true},
+		{"pool/*.go", "pool/worker.go:12", true},
+		{"app/pool/worker.go", "/src/app/pool/worker.go:12", true},
+		{"pool/*.go", "/src/app/mypool/worker.go:12", false},
+		{"worker.go", "/src/app/pool/worker.go:12", true},
+		{"orker.go", "/src/app/pool/worker.go:12", false},
+		// Without a line, any line matches; with one, only that line.
+		{"pool/worker.go", "/src/app/pool/worker.go", true},
+		{"pool/worker.go:12", "/src/app/pool/worker.go:12", true},
+		{"pool/worker.go:12", "/src/app/pool/worker.go:120", false},
+		{"pool/worker.go:1", "/src/app/pool/worker.go:12", false},
+		{"pool/worker.go:*", "/src/app/pool/worker.go:12", true},
+		// Regexp metacharacters are literal.
+		{"pool/worker+v2.go", "/src/app/pool/worker+v2.go:3", true},
+		{"pool/worker.go", "/src/app/pool/workerXgo:3", false},
+	} {
+		if got := globRegexp(tc.glob).MatchString(tc.location); got != tc.want {
+			t.Errorf("glob %q on %q = %v, want %v", tc.glob, tc.location, got, tc.want)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	for _, tc := range []struct {
+		rule Rule
+		err  string
+	}{
+		{Rule{Reason: "x"}, "ignore[0]: needs at least one of"},
+		{Rule{Kind: "goroutine_leak"}, "ignore[0]: reason is required"},
+		{Rule{Kind: "goroutine_leak", Reason: "  "}, "reason is required"},
+		{Rule{Kind: "leak", Reason: "x"}, `ignore[0].kind: unknown kind "leak"`},
+		{Rule{Function: "(", Reason: "x"}, "ignore[0].function:"},
+		{Rule{Stack: "[", Reason: "x"}, "ignore[0].stack:"},
+	} {
+		_, err := Compile("ignore", []Rule{tc.rule})
+		if err == nil || !strings.Contains(err.Error(), tc.err) {
+			t.Errorf("Compile(%+v) error = %v, want %q", tc.rule, err, tc.err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	set, err := Compile("ignore", []Rule{
+		{Kind: "goroutine_leak", Location: "pool/*.go", Reason: "workers live as long as the pool"},
+		{Function: `^example\.com/app\.\(\*Cache\)\.`, Reason: "cache lock is held briefly"},
+		{Stack: `^example\.com/app\.serve `, Reason: "serve loops until shutdown"},
+		{Location: "pool/*.go", Reason: "shadowed by the first rule"},
+		{Kind: "deadlock", Reason: "never matches"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	findings := []detector.Finding{
+		{Kind: detector.KindGoroutineLeak, Function: "example.com/app/pool.worker", Location: "/src/app/pool/worker.go:12"},
+		{Kind: detector.KindLongBlock, Function: "example.com/app.(*Cache).Get", Location: "/src/app/cache.go:30"},
+		{Kind: detector.KindGoroutineLeak, Function: "example.com/app.handle", Location: "/src/app/server.go:40",
+			Stack: "      example.com/app.handle (/src/app/server.go:40)\n      example.com/app.serve (/src/app/server.go:22)\n"},
+		{Kind: detector.KindGoroutineLeak, Function: "example.com/app.leak", Location: "/src/app/leak.go:5"},
+	}
+	result := &detector.Result{Findings: findings}
+	set.Apply(result)
+
+	if !reflect.DeepEqual(result.Findings, findings[3:]) {
+		t.Errorf("kept %+v, want only app.leak", result.Findings)
+	}
+	if len(result.Suppressed) != 3 {
+		t.Fatalf("got %d suppressed findings, want 3", len(result.Suppressed))
+	}
+	for i, want := range []struct{ rule, reason string }{
+		{"kind=goroutine_leak location=pool/*.go", "workers live as long as the pool"},
+		{`function=^example\.com/app\.\(\*Cache\)\.`, "cache lock is held briefly"},
+		{`stack=^example\.com/app\.serve `, "serve loops until shutdown"},
+	} {
+		s := result.Suppressed[i]
+		if s.Finding.Location != findings[i].Location || s.Rule != want.rule || s.Reason != want.reason {
+			t.Errorf("suppressed[%d] = %s by %q (%q), want %s by %q", i, s.Finding.Location, s.Rule, s.Reason, findings[i].Location, want.rule)
+		}
+	}
+	wantUnused := []string{"location=pool/*.go (shadowed by the first rule)", "kind=deadlock (never matches)"}
+	if !reflect.DeepEqual(result.UnusedSuppressions, wantUnused) {
+		t.Errorf("unused = %q, want %q", result.UnusedSuppressions, wantUnused)
+	}
+}
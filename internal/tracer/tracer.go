@@ -6,45 +6,90 @@ import (
 	"os/exec"
 	"path/filepath"
 	"strings"
+	"sync"
 	"time"
 )
 
+// Options controls how traced test runs are executed.
+type Options struct {
+	// Duration is the go test -timeout for each package run.
+	Duration time.Duration
+	// Parallel is the maximum number of packages tested concurrently.
+	// Values below 1 are treated as 1 (sequential).
+	Parallel int
+	// TestFlags are extra go test flags (e.g. -run TestFoo, -tags integration,
+	// -count 3) passed to every traced and race run. Build flags among them
+	// (-tags, -mod) are also passed to go list when expanding patterns.
+	TestFlags []string
+}
+
 // RunResult holds the output of a traced test run.
 type RunResult struct {
+	// Packages holds one entry per package that produced a trace, in the
+	// order the packages were listed. Single-package runs have exactly one.
+	Packages []PackageTrace
+	Output   string
+	ExitCode int
+}
+
+// PackageTrace is the trace and output of one package's `go test -trace` run.
+type PackageTrace struct {
+	Package string
+	// TraceFile is empty when the test binary died without writing a trace
+	// but printed a goroutine dump (e.g. "panic: test timed out"); Output
+	// then holds the dump.
 	TraceFile string
 	Output    string
 	ExitCode  int
 }
 
-// Run executes `go test -trace <tmpfile> -timeout <duration> <args...>` and
-// returns the path to the generated trace file.
+// Remove deletes every trace file referenced by r.
+func (r *RunResult) Remove() {
+	for _, p := range r.Packages {
+		if p.TraceFile != "" {
+			os.Remove(p.TraceFile)
+		}
+	}
+}
+
+// Run executes `go test -trace <tmpfile> -timeout <duration> <flags...> <pkg>`
+// for the package patterns in args and returns the generated trace files.
 //
 // If args contain a wildcard pattern like ./..., we enumerate packages first
-// (go list) and run each package separately, merging their traces into one.
+// (go list) and run each package separately, keeping one trace per package.
 //
 // extraEnv is a list of additional environment variable assignments (e.g.
 // "GOMAXPROCS=1") prepended to the process environment.
-func Run(args []string, duration time.Duration, extraEnv ...string) (*RunResult, error) {
-	pkgs, err := expandPackages(args)
+func Run(args []string, opts Options, extraEnv ...string) (*RunResult, error) {
+	pkgs, err := expandPackages(args, buildFlags(opts.TestFlags))
 	if err != nil {
 		return nil, fmt.Errorf("list packages: %w", err)
 	}
 	if len(pkgs) == 1 {
-		return runSingle(pkgs[0:], duration, extraEnv)
+		pt, err := runSingle(pkgs[0], opts, extraEnv)
+		if err != nil {
+			return nil, err
+		}
+		return &RunResult{
+			Packages: []PackageTrace{*pt},
+			Output:   pt.Output,
+			ExitCode: pt.ExitCode,
+		}, nil
 	}
-	return runMulti(pkgs, duration, extraEnv)
+	return runMulti(pkgs, opts, extraEnv)
 }
 
 // runSingle runs go test -trace on a single package.
-func runSingle(args []string, duration time.Duration, extraEnv []string) (*RunResult, error) {
+func runSingle(pkg string, opts Options, extraEnv []string) (*PackageTrace, error) {
 	traceFile, err := tempTraceFile()
 	if err != nil {
 		return nil, fmt.Errorf("create trace file: %w", err)
 	}
 
-	timeout := fmt.Sprintf("%.0fs", duration.Seconds())
+	timeout := fmt.Sprintf("%.0fs", opts.Duration.Seconds())
 	cmdArgs := []string{"test", "-trace", traceFile, "-timeout", timeout}
-	cmdArgs = append(cmdArgs, args...)
+	cmdArgs = append(cmdArgs, opts.TestFlags...)
+	cmdArgs = append(cmdArgs, pkg)
 
 	cmd := exec.Command("go", cmdArgs...)
 	cmd.Env = append(os.Environ(), extraEnv...)
@@ -60,68 +105,65 @@ func runSingle(args []string, duration time.Duration, extraEnv []string) (*RunRe
 		}
 	}
 
+	if fi, err := os.Stat(traceFile); (os.IsNotExist(err) || err == nil && fi.Size() == 0) && hasGoroutineDump(string(out)) {
+		// The test binary was killed before flushing its trace (timeout,
+		// SIGQUIT); the goroutine dump it printed can be analyzed instead.
+		os.Remove(traceFile)
+		return &PackageTrace{Package: pkg, Output: string(out), ExitCode: exitCode}, nil
+	}
+
 	if _, err := os.Stat(traceFile); os.IsNotExist(err) {
 		return nil, fmt.Errorf("trace file not created — did `go test` run? output:\n%s", strings.TrimSpace(string(out)))
 	}
 
-	return &RunResult{
+	return &PackageTrace{
+		Package:   pkg,
 		TraceFile: traceFile,
 		Output:    string(out),
 		ExitCode:  exitCode,
 	}, nil
 }
 
-// runMulti runs each package separately, picks the trace with the most events
-// (the most interesting one). For multi-package runs, the user sees per-package
-// output, and we report on the combined results printed to stderr.
-func runMulti(pkgs []string, duration time.Duration, extraEnv []string) (*RunResult, error) {
-	var allOutput strings.Builder
-	var bestTrace string
-	var bestSize int64
-	var worstExit int
-	var traceFiles []string
-
-	for _, pkg := range pkgs {
-		r, err := runSingle([]string{pkg}, duration, extraEnv)
+// runMulti runs each package separately (up to opts.Parallel at a time) and
+// keeps every package's trace so that each one can be analyzed on its own.
+// Results are collected in package listing order regardless of completion
+// order. The combined go test output, with every line prefixed by its
+// package, is returned in RunResult.Output.
+func runMulti(pkgs []string, opts Options, extraEnv []string) (*RunResult, error) {
+	traces := make([]*PackageTrace, len(pkgs))
+	forEachPackage(pkgs, opts.Parallel, func(i int, pkg string) {
+		pt, err := runSingle(pkg, opts, extraEnv)
 		if err != nil {
 			// Package may have no test files — skip silently
-			continue
-		}
-		traceFiles = append(traceFiles, r.TraceFile)
-		allOutput.WriteString(r.Output)
-		if r.ExitCode > worstExit {
-			worstExit = r.ExitCode
-		}
-		// Pick the largest trace file (most events = most interesting)
-		if fi, err := os.Stat(r.TraceFile); err == nil {
-			if fi.Size() > bestSize {
-				bestSize = fi.Size()
-				bestTrace = r.TraceFile
-			}
+			return
 		}
-	}
+		traces[i] = pt
+	})
 
-	// Clean up all traces except the best one
-	for _, f := range traceFiles {
-		if f != bestTrace {
-			os.Remove(f)
+	result := &RunResult{}
+	var allOutput strings.Builder
+	for _, pt := range traces {
+		if pt == nil {
+			continue
+		}
+		result.Packages = append(result.Packages, *pt)
+		allOutput.WriteString(prefixLines(pt.Package, pt.Output))
+		if pt.ExitCode > result.ExitCode {
+			result.ExitCode = pt.ExitCode
 		}
 	}
 
-	if bestTrace == "" {
+	if len(result.Packages) == 0 {
 		return nil, fmt.Errorf("no packages produced a trace (no test files?)")
 	}
 
-	return &RunResult{
-		TraceFile: bestTrace,
-		Output:    allOutput.String(),
-		ExitCode:  worstExit,
-	}, nil
+	result.Output = allOutput.String()
+	return result, nil
 }
 
-// expandPackages runs `go list <args>` to resolve package patterns to a list
-// of import paths. If args have no wildcards, returns args unchanged.
-func expandPackages(args []string) ([]string, error) {
+// expandPackages runs `go list <flags...> <args>` to resolve package patterns
+// to a list of import paths. If args have no wildcards, returns args unchanged.
+func expandPackages(args, flags []string) ([]string, error) {
 	hasWildcard := false
 	for _, a := range args {
 		if strings.Contains(a, "...") {
@@ -133,7 +175,8 @@ func expandPackages(args []string) ([]string, error) {
 		return args, nil
 	}
 
-	cmdArgs := append([]string{"list"}, args...)
+	cmdArgs := append([]string{"list"}, flags...)
+	cmdArgs = append(cmdArgs, args...)
 	out, err := exec.Command("go", cmdArgs...).Output()
 	if err != nil {
 		return nil, fmt.Errorf("go list: %w", err)
@@ -149,29 +192,56 @@ func expandPackages(args []string) ([]string, error) {
 	return pkgs, nil
 }
 
+// buildFlags returns the go test flags that also affect which packages
+// `go list` reports (-tags and -mod), in both "-flag=value" and
+// "-flag value" forms.
+func buildFlags(testFlags []string) []string {
+	var out []string
+	for i := 0; i < len(testFlags); i++ {
+		name := strings.TrimLeft(testFlags[i], "-")
+		if eq := strings.Index(name, "="); eq >= 0 {
+			name = name[:eq]
+			if name == "tags" || name == "mod" {
+				out = append(out, testFlags[i])
+			}
+			continue
+		}
+		if (name == "tags" || name == "mod") && i+1 < len(testFlags) {
+			out = append(out, testFlags[i], testFlags[i+1])
+			i++
+		}
+	}
+	return out
+}
+
 // RaceResult holds the combined output of a race-enabled test run.
 type RaceResult struct {
 	Output   string
 	ExitCode int
 }
 
-// RunRace executes `go test -race -timeout <duration> <args...>` and returns
+// RunRace executes `go test -race -timeout <duration> <flags...> <pkg>` and returns
 // the combined output (stdout+stderr), which contains any race detector reports.
 // Sets GORACE=atexit_sleep_ms=0 to suppress the default 1s shutdown delay.
-func RunRace(args []string, duration time.Duration, extraEnv ...string) (*RaceResult, error) {
-	pkgs, err := expandPackages(args)
+//
+// Packages are tested up to opts.Parallel at a time; their outputs are
+// concatenated in package listing order. Output is not prefixed, because
+// ParseRaceOutput relies on the race detector's line-start delimiters.
+func RunRace(args []string, opts Options, extraEnv ...string) (*RaceResult, error) {
+	pkgs, err := expandPackages(args, buildFlags(opts.TestFlags))
 	if err != nil {
 		return nil, fmt.Errorf("list packages: %w", err)
 	}
 
-	var allOutput strings.Builder
-	worstExit := 0
-
 	raceEnv := append([]string{"GORACE=atexit_sleep_ms=0"}, extraEnv...)
+	outputs := make([][]byte, len(pkgs))
+	exitCodes := make([]int, len(pkgs))
 
-	for _, pkg := range pkgs {
-		timeout := fmt.Sprintf("%.0fs", duration.Seconds())
-		cmdArgs := []string{"test", "-race", "-timeout", timeout, pkg}
+	forEachPackage(pkgs, opts.Parallel, func(i int, pkg string) {
+		timeout := fmt.Sprintf("%.0fs", opts.Duration.Seconds())
+		cmdArgs := []string{"test", "-race", "-timeout", timeout}
+		cmdArgs = append(cmdArgs, opts.TestFlags...)
+		cmdArgs = append(cmdArgs, pkg)
 
 		cmd := exec.Command("go", cmdArgs...)
 		cmd.Env = append(os.Environ(), raceEnv...)
@@ -179,13 +249,20 @@ func RunRace(args []string, duration time.Duration, extraEnv ...string) (*RaceRe
 		out, err := cmd.CombinedOutput()
 		if err != nil {
 			if exitErr, ok := err.(*exec.ExitError); ok {
-				if exitErr.ExitCode() > worstExit {
-					worstExit = exitErr.ExitCode()
-				}
+				exitCodes[i] = exitErr.ExitCode()
 			}
 			// Don't abort — race output may still be in out.
 		}
-		allOutput.Write(out)
+		outputs[i] = out
+	})
+
+	var allOutput strings.Builder
+	worstExit := 0
+	for i := range pkgs {
+		allOutput.Write(outputs[i])
+		if exitCodes[i] > worstExit {
+			worstExit = exitCodes[i]
+		}
 	}
 
 	return &RaceResult{
@@ -194,6 +271,54 @@ func RunRace(args []string, duration time.Duration, extraEnv ...string) (*RaceRe
 	}, nil
 }
 
+// forEachPackage calls fn once per package with at most parallel calls in
+// flight. fn receives the package's index so callers can store results in
+// listing order. A failure inside one call never affects the others.
+func forEachPackage(pkgs []string, parallel int, fn func(i int, pkg string)) {
+	if parallel < 1 {
+		parallel = 1
+	}
+	sem := make(chan struct{}, parallel)
+	var wg sync.WaitGroup
+	for i, pkg := range pkgs {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			fn(i, pkg)
+		}()
+	}
+	wg.Wait()
+}
+
+// prefixLines prefixes every line of out with "[pkg] " so that interleaved
+// per-package output stays attributable.
+func prefixLines(pkg, out string) string {
+	if out == "" {
+		return ""
+	}
+	var sb strings.Builder
+	for _, line := range strings.SplitAfter(out, "\n") {
+		if line == "" {
+			continue
+		}
+		sb.WriteString("[" + pkg + "] ")
+		sb.WriteString(line)
+	}
+	if !strings.HasSuffix(out, "\n") {
+		sb.WriteByte('\n')
+	}
+	return sb.String()
+}
+
+// hasGoroutineDump reports whether go test output contains a goroutine dump
+// printed by a dying test binary.
+func hasGoroutineDump(out string) bool {
+	return strings.Contains(out, "panic: test timed out") ||
+		strings.Contains(out, "SIGQUIT: quit")
+}
+
 func tempTraceFile() (string, error) {
 	dir := os.TempDir()
 	f, err := os.CreateTemp(dir, "threadgraph-*.out")
@@ -0,0 +1,214 @@
+package threadgraph
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/baseline"
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/reporter"
+	"github.com/Heman10x-NGU/threadgraph/internal/suppress"
+)
+
+// APIVersion is the version of this package's API; see Compatibility in the
+// package documentation. The major version changes only on a breaking change.
+const APIVersion = "1.8"
+
+// DefaultMinBlock is the MinBlock used when Options.MinBlock is zero, the
+// same as the CLI's --min-block default.
+const DefaultMinBlock = time.Second
+
+// Options controls an analysis. The zero value analyzes a whole trace of a
+// test binary with default thresholds.
+type Options struct {
+	// MinBlock is how long a goroutine blocked on something other than a
+	// channel must have been blocked to be reported. Zero means
+	// DefaultMinBlock.
+	MinBlock time.Duration
+	// Streaming bounds memory on traces with very many goroutines; findings
+	// are the same.
+	Streaming bool
+	// From and To restrict analysis to part of the trace, as offsets from
+	// its first event (To == 0 means the end). Region restricts it to the
+	// first instance of the named runtime/trace user region instead.
+	From   time.Duration
+	To     time.Duration
+	Region string
+	// Service analyzes a trace or dump of a long-running service instead of
+	// a test binary: findings are limited to the goroutine trees rooted at
+	// goroutines with a function matching ServiceRoots (every goroutine if
+	// ServiceRoots is empty) rather than those started by tests.
+	Service      bool
+	ServiceRoots []*regexp.Regexp
+	// Thresholds tunes the detectors; zero fields take the defaults of
+	// DefaultThresholds.
+	Thresholds Thresholds
+	// Disabled turns off the detectors whose names map to true: "leaks",
+	// "deadlocks", "transient_blocks", "lock_cycles", "chan_lock_cycle",
+	// "orphans", "waitgroup_deadlock" and "wait_for_cycles".
+	Disabled map[string]bool
+	// Timeline records every goroutine's lifecycle in Result.Timeline, as
+	// WriteHTML shows it.
+	Timeline bool
+	// LockWait records the lock-wait graph the lock_cycles detector
+	// searches for deadlocks in Result.LockWait.
+	LockWait bool
+}
+
+// DefaultThresholds returns the thresholds used for zero Thresholds fields.
+func DefaultThresholds() Thresholds {
+	return fromThresholds(detector.DefaultThresholds())
+}
+
+func (o Options) internal() detector.Options {
+	minBlock := o.MinBlock
+	if minBlock == 0 {
+		minBlock = DefaultMinBlock
+	}
+	return detector.Options{
+		MinBlock:     minBlock,
+		Streaming:    o.Streaming,
+		From:         o.From,
+		To:           o.To,
+		Region:       o.Region,
+		Service:      o.Service,
+		ServiceRoots: o.ServiceRoots,
+		Thresholds:   o.Thresholds.internal(),
+		Disabled:     o.Disabled,
+		Timeline:     o.Timeline,
+		LockWait:     o.LockWait,
+	}
+}
+
+// AnalyzeReader analyzes a Go execution trace, as written by runtime/trace,
+// `go test -trace` or /debug/pprof/trace, read from r.
+func AnalyzeReader(r io.Reader, opts Options) (*Result, error) {
+	if opts.Region != "" && (opts.From != 0 || opts.To != 0) {
+		return nil, fmt.Errorf("cannot combine Options.Region with From/To")
+	}
+	result, err := detector.AnalyzeReader(r, opts.internal())
+	if err != nil {
+		return nil, err
+	}
+	return fromResult(result), nil
+}
+
+// AnalyzeFile analyzes the execution trace in the file at path.
+func AnalyzeFile(path string, opts Options) (*Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	result, err := AnalyzeReader(f, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	result.TraceFile = path
+	return result, nil
+}
+
+// AnalyzeGoroutineDump analyzes a goroutine dump read from r: the output of a
+// test that hit "panic: test timed out", a SIGQUIT dump, or
+// /debug/pprof/goroutine?debug=2. A dump is a single snapshot, so only leaks,
+// mutex contention and WaitGroup deadlocks are detected; From, To and Region
+// do not apply.
+func AnalyzeGoroutineDump(r io.Reader, opts Options) (*Result, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	result, err := detector.GoroutineDumpResult(string(data), opts.internal())
+	if err != nil {
+		return nil, err
+	}
+	return fromResult(result), nil
+}
+
+// WriteTerminal writes result as the CLI's human-readable report. Colors are
+// used only when stdout is a terminal.
+func WriteTerminal(w io.Writer, result *Result) {
+	reporter.WriteTerminal(w, result.internal(), "")
+}
+
+// WriteJSON writes result in the CLI's --format json schema.
+func WriteJSON(w io.Writer, result *Result) error {
+	return reporter.WriteJSON(w, result.internal(), "")
+}
+
+// WriteSARIF writes result as a SARIF 2.1.0 log, like the CLI's
+// --format sarif.
+func WriteSARIF(w io.Writer, result *Result) error {
+	return reporter.WriteSARIF(w, result.internal())
+}
+
+// WriteJUnit writes result as JUnit XML, one test case per test with the
+// findings attributed to it as failures, like the CLI's --format junit.
+func WriteJUnit(w io.Writer, result *Result) error {
+	return reporter.WriteJUnit(w, result.internal(), "")
+}
+
+// WriteHTML writes result as a self-contained HTML page, like the CLI's
+// --format html. The goroutine timeline needs a result analyzed with
+// Options.Timeline.
+func WriteHTML(w io.Writer, result *Result) error {
+	return reporter.WriteHTML(w, result.internal(), "")
+}
+
+// WriteMarkdown writes result as a Markdown summary for a pull request
+// comment, like the CLI's --format markdown. Source links are relative to
+// the working directory, prefixed with linkBase if it is not empty.
+func WriteMarkdown(w io.Writer, result *Result, linkBase string) error {
+	return reporter.WriteMarkdown(w, result.internal(), "", linkBase)
+}
+
+// Patterns groups findings by Finding.SpawnPath, the chain of 'go'
+// statements that led to their goroutines, largest group first.
+func Patterns(findings []Finding) []Pattern {
+	return convert(detector.Patterns(convert(findings, Finding.internal)), fromPattern)
+}
+
+// SaveBaseline writes findings to a baseline file at path, like the CLI's
+// --save-baseline.
+func SaveBaseline(findings []Finding, path string) error {
+	return baseline.Save(convert(findings, Finding.internal), path)
+}
+
+// LoadBaseline reads a baseline file written by SaveBaseline or the CLI.
+func LoadBaseline(path string) (*Baseline, error) {
+	b, err := baseline.Load(path)
+	if err != nil {
+		return nil, err
+	}
+	return &Baseline{b}, nil
+}
+
+// NewFindings returns the findings not recorded in b. With a nil b, all
+// findings are new.
+func NewFindings(findings []Finding, b *Baseline) []Finding {
+	return convert(baseline.FilterNew(convert(findings, Finding.internal), b.internal()), fromFinding)
+}
+
+// CompareBaseline compares result with b, loaded from file, like the CLI's
+// --baseline: result.Findings keeps only the new findings, and
+// result.Baseline lists the known ones still present and the resolved
+// entries. A nil b counts every finding as new.
+func CompareBaseline(result *Result, b *Baseline, file string) {
+	r := result.internal()
+	baseline.Apply(r, b.internal(), file)
+	*result = *fromResult(r)
+}
+
+// ApplyInlineSuppressions moves the findings silenced by
+// //threadgraph:ignore comments in the source to result.Suppressed, like
+// the CLI does. It returns a warning for every malformed comment.
+func ApplyInlineSuppressions(result *Result) []string {
+	r := result.internal()
+	warnings := suppress.ApplyInline(r)
+	*result = *fromResult(r)
+	return warnings
+}
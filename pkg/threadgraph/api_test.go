@@ -0,0 +1,86 @@
+package threadgraph
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestAnalyzeFile(t *testing.T) {
+	result, err := AnalyzeFile("../../testdata/waitfor-cycle/trace.out", Options{Timeline: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cycle *Finding
+	for i, f := range result.Findings {
+		if f.Kind == KindDeadlock {
+			cycle = &result.Findings[i]
+		}
+	}
+	if cycle == nil {
+		t.Fatalf("no deadlock finding: %+v", result.Findings)
+	}
+	if cycle.GoroutineID == 0 || cycle.Test != "TestWaitForCycle" || len(cycle.SpawnPath) == 0 {
+		t.Errorf("incomplete finding: %+v", *cycle)
+	}
+	if result.Timeline == nil || len(result.Timeline.Goroutines) == 0 {
+		t.Error("no timeline")
+	}
+
+	// Writers and CompareBaseline convert back to the internal types; the
+	// round trip must not lose anything.
+	if got := fromResult(result.internal()); !reflect.DeepEqual(got, result) {
+		t.Errorf("round trip changed the result:\ngot  %+v\nwant %+v", got, result)
+	}
+	var buf bytes.Buffer
+	if err := WriteJSON(&buf, result); err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(buf.Bytes()) {
+		t.Error("WriteJSON wrote invalid JSON")
+	}
+}
+
+func TestCompareBaseline(t *testing.T) {
+	result, err := AnalyzeFile("../../testdata/chan-receive-leak/trace.out", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Findings) == 0 {
+		t.Fatal("no findings")
+	}
+	path := filepath.Join(t.TempDir(), "baseline.json")
+	if err := SaveBaseline(result.Findings, path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := LoadBaseline(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh := NewFindings(result.Findings, b); len(fresh) != 0 {
+		t.Errorf("NewFindings = %+v, want none", fresh)
+	}
+
+	n := len(result.Findings)
+	CompareBaseline(result, b, path)
+	if len(result.Findings) != 0 || result.Baseline == nil || len(result.Baseline.Persisting) != n {
+		t.Errorf("after CompareBaseline: %d findings, comparison %+v; want 0 and %d persisting", len(result.Findings), result.Baseline, n)
+	}
+}
+
+func TestCompareNilBaseline(t *testing.T) {
+	result, err := AnalyzeFile("../../testdata/chan-receive-leak/trace.out", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := len(result.Findings)
+	if fresh := NewFindings(result.Findings, nil); len(fresh) != n {
+		t.Errorf("NewFindings with a nil baseline = %d findings, want all %d", len(fresh), n)
+	}
+	CompareBaseline(result, nil, "")
+	if len(result.Findings) != n || result.Baseline == nil || len(result.Baseline.Persisting) != 0 || len(result.Baseline.Resolved) != 0 {
+		t.Errorf("after CompareBaseline with a nil baseline: %d findings, comparison %+v; want all %d new", len(result.Findings), result.Baseline, n)
+	}
+}
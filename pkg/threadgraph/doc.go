@@ -0,0 +1,36 @@
+// Package threadgraph is the public Go API of ThreadGraph: it finds goroutine
+// leaks and deadlocks in Go execution traces and goroutine dumps, and renders
+// the findings the way the threadgraph CLI does.
+//
+// Analyze a trace held anywhere — a file, memory, a network stream:
+//
+//	result, err := threadgraph.AnalyzeReader(resp.Body, threadgraph.Options{})
+//	if err != nil { ... }
+//	threadgraph.WriteTerminal(os.Stdout, result)
+//
+// Or check a test for leaks in-process, without the CLI:
+//
+//	func TestServer(t *testing.T) {
+//		threadgraph.VerifyNoLeaks(t)
+//		...
+//	}
+//
+// # Compatibility
+//
+// The API is versioned by APIVersion. Within a major API version:
+//
+//   - exported identifiers are not removed or renamed, and function
+//     signatures do not change;
+//   - fields and methods may be added to Result, Finding, Options and the
+//     other exported structs, so construct them with field names;
+//   - new Kind values may be added; treat unknown kinds as generic findings;
+//   - the exported types depend only on the standard library: goroutine IDs
+//     are uint64, and nothing from internal/ or x/exp/trace is exposed;
+//   - WriteJSON output only gains fields, existing ones keep their names and
+//     meaning;
+//   - the wording of Finding.BlockedOn and Finding.Evidence, the terminal
+//     layout, and which findings a given trace produces may improve and are
+//     not covered.
+//
+// Packages under internal/ carry no guarantees.
+package threadgraph
@@ -0,0 +1,584 @@
+package threadgraph
+
+import (
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/baseline"
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"golang.org/x/exp/trace"
+)
+
+// Kind is the category of a Finding.
+type Kind string
+
+const (
+	KindGoroutineLeak Kind = "goroutine_leak"
+	KindDeadlock      Kind = "deadlock"
+	KindLongBlock     Kind = "long_block"
+	KindLockLeak      Kind = "lock_leak"  // static analysis: lock not released on all paths
+	KindLockOrder     Kind = "lock_order" // static analysis: lock ordering cycle
+	KindDataRace      Kind = "data_race"  // race detector: unsynchronized memory access
+)
+
+// Confidence indicates how certain a Finding is.
+type Confidence string
+
+const (
+	ConfidenceHigh   Confidence = "high"
+	ConfidenceMedium Confidence = "medium"
+	ConfidenceLow    Confidence = "low"
+)
+
+// Result holds all findings from one analysis.
+type Result struct {
+	TraceFile          string
+	DurationMs         int64
+	GoroutinesAnalyzed int
+	Findings           []Finding
+	// Package is the package this result was captured for, if known.
+	Package string
+	// Tests lists the top-level tests the trace or dump shows goroutines of,
+	// sorted. A test that never blocks or starts a goroutine is missing.
+	Tests []string
+	// Packages summarizes each package that contributed to a merged result.
+	Packages []PackageSummary
+	// LeakRates is set for stress runs.
+	LeakRates []LeakRate
+	// PeakHeapBytes is the largest live heap observed while analyzing.
+	PeakHeapBytes uint64
+	// Window is the analyzed part of the trace when Options.From, To or
+	// Region were set; nil when the whole trace was analyzed.
+	Window *Window
+	// Suppressed holds the findings removed by suppression rules, and
+	// UnusedSuppressions describes the rules that matched no finding.
+	Suppressed         []Suppressed
+	UnusedSuppressions []string
+	// Baseline is set by CompareBaseline; Findings then holds only the new
+	// findings.
+	Baseline *BaselineComparison
+	// Repeat describes an analysis repeated by the CLI's --repeat; nil
+	// otherwise.
+	Repeat *Repeat
+	// Timeline is the lifecycle of each goroutine when Options.Timeline was
+	// set; nil otherwise and for goroutine dumps.
+	Timeline *Timeline
+	// LockWait is the lock-wait graph when Options.LockWait was set.
+	LockWait *LockWaitGraph
+}
+
+// Finding is a single detected concurrency issue.
+type Finding struct {
+	Kind        Kind
+	Confidence  Confidence
+	GoroutineID uint64
+	BlockedOn   string
+	BlockedFor  time.Duration
+	Stack       string
+	Function    string // top user-code function
+	Location    string // file:line of top user-code frame
+	// Count is the number of distinct goroutines with the same (kind,
+	// location) signature.
+	Count int
+	// Package is the import path of the package whose trace produced this
+	// finding, in multi-package results.
+	Package string
+	// Test is the top-level test (e.g. "TestFoo") or test-named region
+	// ("TestFoo/sub") the goroutine belongs to. When goroutines of several
+	// tests were merged into the finding, Tests lists them all, sorted;
+	// Tests is nil otherwise. See TestNames.
+	Test  string
+	Tests []string
+	// Evidence lists supporting observations from the trace.
+	Evidence []string
+	// CreationFunction and CreationLocation are the function and file:line
+	// of the 'go' statement that started the goroutine, when known.
+	CreationFunction string
+	CreationLocation string
+	// SpawnPath is the chain of 'go' statements that led to the goroutine,
+	// outermost first. See Patterns.
+	SpawnPath []SpawnSite
+	// Hits is the number of runs of a repeated analysis the finding
+	// appeared in, out of Runs; both are zero otherwise.
+	Hits int
+	Runs int
+}
+
+// Fingerprint identifies f independently of line numbers and goroutine IDs;
+// baselines match findings by it.
+func (f Finding) Fingerprint() string {
+	return f.internal().Fingerprint()
+}
+
+// TestNames returns the tests the finding's goroutines belong to: Tests if
+// goroutines of several tests were merged into it, otherwise Test, if set.
+func (f Finding) TestNames() []string {
+	return f.internal().TestNames()
+}
+
+// Window is the part of a trace that was analyzed, as offsets from its
+// first event; see Options.From.
+type Window struct {
+	From   time.Duration
+	To     time.Duration
+	Region string // user region name the window was taken from, if any
+}
+
+// LeakRate is the per-invocation leak count of one test in a stress run.
+type LeakRate struct {
+	Test          string
+	Package       string
+	Invocations   int
+	PerInvocation []int // goroutines leaked by each invocation, in run order
+	Leaked        int
+	Rate          float64 // Leaked / Invocations
+	// Linear is true when every invocation leaked at least one goroutine.
+	Linear bool
+}
+
+// PackageSummary holds per-package statistics of a multi-package Result.
+type PackageSummary struct {
+	Package            string
+	TraceFile          string
+	DurationMs         int64
+	GoroutinesAnalyzed int
+	Tests              []string
+	Timeline           *Timeline
+}
+
+// Suppressed is a finding removed by a suppression rule.
+type Suppressed struct {
+	Finding Finding
+	Rule    string // description of the matching rule
+	Reason  string // the rule's reason
+}
+
+// Repeat describes an analysis repeated by the CLI's --repeat.
+type Repeat struct {
+	Runs    int
+	MinHits int
+	// Envs is the extra environment of each run, "" for the default one.
+	Envs []string
+	// Flaky holds the findings seen in fewer than MinHits runs.
+	Flaky []Finding
+}
+
+// Thresholds are the detectors' tunable cutoffs; see Options.Thresholds.
+// A zero field selects the default from DefaultThresholds.
+type Thresholds struct {
+	// Deadlock is how long goroutines must be blocked at the same mutex
+	// site to form a deadlock group.
+	Deadlock time.Duration
+	// ABBAStaleWindow is how old a lock acquisition can be and still count
+	// towards a lock-order or channel-lock cycle.
+	ABBAStaleWindow time.Duration
+	// OrphanMaxTrace is the trace length below which goroutines that never
+	// ran are reported.
+	OrphanMaxTrace time.Duration
+	// HighRatio and MediumRatio are the fractions of the trace a leaked
+	// goroutine must have been blocked for high and medium confidence.
+	HighRatio   float64
+	MediumRatio float64
+	// SyncHistory is how many recent lock acquisitions are remembered per
+	// goroutine.
+	SyncHistory int
+}
+
+// Baseline is a saved set of known findings; see LoadBaseline. A nil
+// *Baseline is an empty one.
+type Baseline struct {
+	b *baseline.Baseline
+}
+
+func (b *Baseline) internal() *baseline.Baseline {
+	if b == nil || b.b == nil {
+		return &baseline.Baseline{}
+	}
+	return b.b
+}
+
+// BaselineComparison is Result.Baseline; see CompareBaseline.
+type BaselineComparison struct {
+	File string
+	// Persisting holds the findings the baseline already knew about.
+	Persisting []Finding
+	// Resolved holds the baseline entries no finding matched any more.
+	Resolved []BaselineEntry
+}
+
+// BaselineEntry is a finding recorded in a baseline.
+type BaselineEntry struct {
+	Kind        Kind
+	Function    string
+	Location    string
+	Fingerprint string
+}
+
+// Timeline is the lifecycle of every goroutine in a trace, as offsets from
+// its first event; see Options.Timeline.
+type Timeline struct {
+	Duration   time.Duration
+	Goroutines []GoroutineTimeline // sorted by ID
+}
+
+// GoroutineTimeline is the lifecycle of one goroutine in a Timeline.
+type GoroutineTimeline struct {
+	ID       uint64
+	ParentID uint64
+	Test     string
+	// Function and Location are the goroutine's entry point.
+	Function string
+	Location string
+	// CreatedBy and CreatedAt are the function and file:line of the 'go'
+	// statement that started the goroutine, and CreationStack the stack
+	// that executed it.
+	CreatedBy     string
+	CreatedAt     string
+	CreationStack string
+	// Created is when the goroutine was created, or -1 if it existed before
+	// the trace started. Exited is when it exited, or -1 if it was still
+	// alive when the trace ended.
+	Created time.Duration
+	Exited  time.Duration
+	Spans   []Span
+	// Dropped is the number of earliest spans dropped to bound memory.
+	Dropped int
+	// Kinds lists the kinds of the findings about this goroutine.
+	Kinds []Kind
+}
+
+// Span is a period a goroutine spent in one SpanState.
+type Span struct {
+	State SpanState
+	Start time.Duration
+	End   time.Duration
+	// Reason and Stack are why and where a blocked goroutine was blocked.
+	Reason string
+	Stack  string
+}
+
+// SpanState is the state of a goroutine during a Span.
+type SpanState string
+
+const (
+	SpanRunnable SpanState = "runnable"
+	SpanRunning  SpanState = "running"
+	SpanBlocked  SpanState = "blocked"
+	SpanSyscall  SpanState = "syscall"
+)
+
+// SpawnSite is a 'go' statement on a Finding's SpawnPath: the function that
+// executed it and its file:line.
+type SpawnSite struct {
+	Function string
+	Location string
+}
+
+// Pattern is a group of findings whose goroutines share a spawn path; see
+// Patterns.
+type Pattern struct {
+	SpawnPath []SpawnSite
+	// Goroutines is the number of goroutines affected.
+	Goroutines int
+	Findings   []Finding
+}
+
+// LockWaitGraph is the runtime lock-wait graph of a trace; see
+// Options.LockWait. An edge From→To means a goroutine recently acquired a
+// lock at From and is now blocked on one at To.
+type LockWaitGraph struct {
+	Edges []LockWaitEdge
+	// Cycles are the lock-ordering cycles reported as deadlocks.
+	Cycles [][]string
+}
+
+// LockWaitEdge is an edge of a LockWaitGraph.
+type LockWaitEdge struct {
+	From      string
+	To        string
+	Goroutine uint64
+	// Function is the blocked goroutine's top user-code function.
+	Function string
+}
+
+// The conversions below copy between the public types and
+// internal/detector's, which use the trace package's goroutine IDs.
+
+// convert maps f over s, keeping nil slices nil.
+func convert[S, T any](s []S, f func(S) T) []T {
+	if s == nil {
+		return nil
+	}
+	out := make([]T, len(s))
+	for i, v := range s {
+		out[i] = f(v)
+	}
+	return out
+}
+
+func fromResult(r *detector.Result) *Result {
+	if r == nil {
+		return nil
+	}
+	return &Result{
+		TraceFile:          r.TraceFile,
+		DurationMs:         r.DurationMs,
+		GoroutinesAnalyzed: r.GoroutinesAnalyzed,
+		Findings:           convert(r.Findings, fromFinding),
+		Package:            r.Package,
+		Tests:              r.Tests,
+		Packages:           convert(r.Packages, fromPackageSummary),
+		LeakRates:          convert(r.LeakRates, fromLeakRate),
+		PeakHeapBytes:      r.PeakHeapBytes,
+		Window:             (*Window)(r.Window),
+		Suppressed:         convert(r.Suppressed, fromSuppressed),
+		UnusedSuppressions: r.UnusedSuppressions,
+		Baseline:           fromBaselineComparison(r.Baseline),
+		Repeat:             fromRepeat(r.Repeat),
+		Timeline:           fromTimeline(r.Timeline),
+		LockWait:           fromLockWaitGraph(r.LockWait),
+	}
+}
+
+func (r *Result) internal() *detector.Result {
+	if r == nil {
+		return nil
+	}
+	return &detector.Result{
+		TraceFile:          r.TraceFile,
+		DurationMs:         r.DurationMs,
+		GoroutinesAnalyzed: r.GoroutinesAnalyzed,
+		Findings:           convert(r.Findings, Finding.internal),
+		Package:            r.Package,
+		Tests:              r.Tests,
+		Packages:           convert(r.Packages, PackageSummary.internal),
+		LeakRates:          convert(r.LeakRates, LeakRate.internal),
+		PeakHeapBytes:      r.PeakHeapBytes,
+		Window:             (*detector.Window)(r.Window),
+		Suppressed:         convert(r.Suppressed, Suppressed.internal),
+		UnusedSuppressions: r.UnusedSuppressions,
+		Baseline:           r.Baseline.internal(),
+		Repeat:             r.Repeat.internal(),
+		Timeline:           r.Timeline.internal(),
+		LockWait:           r.LockWait.internal(),
+	}
+}
+
+func fromFinding(f detector.Finding) Finding {
+	return Finding{
+		Kind:             Kind(f.Kind),
+		Confidence:       Confidence(f.Confidence),
+		GoroutineID:      uint64(f.GoroutineID),
+		BlockedOn:        f.BlockedOn,
+		BlockedFor:       f.BlockedFor,
+		Stack:            f.Stack,
+		Function:         f.Function,
+		Location:         f.Location,
+		Count:            f.Count,
+		Package:          f.Package,
+		Test:             f.Test,
+		Tests:            f.Tests,
+		Evidence:         f.Evidence,
+		CreationFunction: f.CreationFunction,
+		CreationLocation: f.CreationLocation,
+		SpawnPath:        convert(f.SpawnPath, fromSpawnSite),
+		Hits:             f.Hits,
+		Runs:             f.Runs,
+	}
+}
+
+func (f Finding) internal() detector.Finding {
+	return detector.Finding{
+		Kind:             detector.Kind(f.Kind),
+		Confidence:       detector.Confidence(f.Confidence),
+		GoroutineID:      trace.GoID(f.GoroutineID),
+		BlockedOn:        f.BlockedOn,
+		BlockedFor:       f.BlockedFor,
+		Stack:            f.Stack,
+		Function:         f.Function,
+		Location:         f.Location,
+		Count:            f.Count,
+		Package:          f.Package,
+		Test:             f.Test,
+		Tests:            f.Tests,
+		Evidence:         f.Evidence,
+		CreationFunction: f.CreationFunction,
+		CreationLocation: f.CreationLocation,
+		SpawnPath:        convert(f.SpawnPath, SpawnSite.internal),
+		Hits:             f.Hits,
+		Runs:             f.Runs,
+	}
+}
+
+func fromSpawnSite(s detector.SpawnSite) SpawnSite { return SpawnSite(s) }
+
+func (s SpawnSite) internal() detector.SpawnSite { return detector.SpawnSite(s) }
+
+func fromLeakRate(l detector.LeakRate) LeakRate { return LeakRate(l) }
+
+func (l LeakRate) internal() detector.LeakRate { return detector.LeakRate(l) }
+
+func fromPackageSummary(p detector.PackageSummary) PackageSummary {
+	return PackageSummary{
+		Package:            p.Package,
+		TraceFile:          p.TraceFile,
+		DurationMs:         p.DurationMs,
+		GoroutinesAnalyzed: p.GoroutinesAnalyzed,
+		Tests:              p.Tests,
+		Timeline:           fromTimeline(p.Timeline),
+	}
+}
+
+func (p PackageSummary) internal() detector.PackageSummary {
+	return detector.PackageSummary{
+		Package:            p.Package,
+		TraceFile:          p.TraceFile,
+		DurationMs:         p.DurationMs,
+		GoroutinesAnalyzed: p.GoroutinesAnalyzed,
+		Tests:              p.Tests,
+		Timeline:           p.Timeline.internal(),
+	}
+}
+
+func fromSuppressed(s detector.Suppressed) Suppressed {
+	return Suppressed{Finding: fromFinding(s.Finding), Rule: s.Rule, Reason: s.Reason}
+}
+
+func (s Suppressed) internal() detector.Suppressed {
+	return detector.Suppressed{Finding: s.Finding.internal(), Rule: s.Rule, Reason: s.Reason}
+}
+
+func fromRepeat(r *detector.Repeat) *Repeat {
+	if r == nil {
+		return nil
+	}
+	return &Repeat{Runs: r.Runs, MinHits: r.MinHits, Envs: r.Envs, Flaky: convert(r.Flaky, fromFinding)}
+}
+
+func (r *Repeat) internal() *detector.Repeat {
+	if r == nil {
+		return nil
+	}
+	return &detector.Repeat{Runs: r.Runs, MinHits: r.MinHits, Envs: r.Envs, Flaky: convert(r.Flaky, Finding.internal)}
+}
+
+func fromBaselineComparison(c *detector.BaselineComparison) *BaselineComparison {
+	if c == nil {
+		return nil
+	}
+	return &BaselineComparison{
+		File:       c.File,
+		Persisting: convert(c.Persisting, fromFinding),
+		Resolved:   convert(c.Resolved, fromBaselineEntry),
+	}
+}
+
+func (c *BaselineComparison) internal() *detector.BaselineComparison {
+	if c == nil {
+		return nil
+	}
+	return &detector.BaselineComparison{
+		File:       c.File,
+		Persisting: convert(c.Persisting, Finding.internal),
+		Resolved:   convert(c.Resolved, BaselineEntry.internal),
+	}
+}
+
+func fromBaselineEntry(e detector.BaselineEntry) BaselineEntry {
+	return BaselineEntry{Kind: Kind(e.Kind), Function: e.Function, Location: e.Location, Fingerprint: e.Fingerprint}
+}
+
+func (e BaselineEntry) internal() detector.BaselineEntry {
+	return detector.BaselineEntry{Kind: detector.Kind(e.Kind), Function: e.Function, Location: e.Location, Fingerprint: e.Fingerprint}
+}
+
+func fromTimeline(t *detector.Timeline) *Timeline {
+	if t == nil {
+		return nil
+	}
+	return &Timeline{Duration: t.Duration, Goroutines: convert(t.Goroutines, fromGoroutineTimeline)}
+}
+
+func (t *Timeline) internal() *detector.Timeline {
+	if t == nil {
+		return nil
+	}
+	return &detector.Timeline{Duration: t.Duration, Goroutines: convert(t.Goroutines, GoroutineTimeline.internal)}
+}
+
+func fromGoroutineTimeline(g detector.GoroutineTimeline) GoroutineTimeline {
+	return GoroutineTimeline{
+		ID:            uint64(g.ID),
+		ParentID:      uint64(g.ParentID),
+		Test:          g.Test,
+		Function:      g.Function,
+		Location:      g.Location,
+		CreatedBy:     g.CreatedBy,
+		CreatedAt:     g.CreatedAt,
+		CreationStack: g.CreationStack,
+		Created:       g.Created,
+		Exited:        g.Exited,
+		Spans:         convert(g.Spans, fromSpan),
+		Dropped:       g.Dropped,
+		Kinds:         convert(g.Kinds, func(k detector.Kind) Kind { return Kind(k) }),
+	}
+}
+
+func (g GoroutineTimeline) internal() detector.GoroutineTimeline {
+	return detector.GoroutineTimeline{
+		ID:            trace.GoID(g.ID),
+		ParentID:      trace.GoID(g.ParentID),
+		Test:          g.Test,
+		Function:      g.Function,
+		Location:      g.Location,
+		CreatedBy:     g.CreatedBy,
+		CreatedAt:     g.CreatedAt,
+		CreationStack: g.CreationStack,
+		Created:       g.Created,
+		Exited:        g.Exited,
+		Spans:         convert(g.Spans, Span.internal),
+		Dropped:       g.Dropped,
+		Kinds:         convert(g.Kinds, func(k Kind) detector.Kind { return detector.Kind(k) }),
+	}
+}
+
+func fromSpan(s detector.Span) Span {
+	return Span{State: SpanState(s.State), Start: s.Start, End: s.End, Reason: s.Reason, Stack: s.Stack}
+}
+
+func (s Span) internal() detector.Span {
+	return detector.Span{State: detector.SpanState(s.State), Start: s.Start, End: s.End, Reason: s.Reason, Stack: s.Stack}
+}
+
+func fromPattern(p detector.Pattern) Pattern {
+	return Pattern{
+		SpawnPath:  convert(p.SpawnPath, fromSpawnSite),
+		Goroutines: p.Goroutines,
+		Findings:   convert(p.Findings, fromFinding),
+	}
+}
+
+func fromLockWaitGraph(g *detector.LockWaitGraph) *LockWaitGraph {
+	if g == nil {
+		return nil
+	}
+	return &LockWaitGraph{Edges: convert(g.Edges, fromLockWaitEdge), Cycles: g.Cycles}
+}
+
+func (g *LockWaitGraph) internal() *detector.LockWaitGraph {
+	if g == nil {
+		return nil
+	}
+	return &detector.LockWaitGraph{Edges: convert(g.Edges, LockWaitEdge.internal), Cycles: g.Cycles}
+}
+
+func fromLockWaitEdge(e detector.LockWaitEdge) LockWaitEdge {
+	return LockWaitEdge{From: e.From, To: e.To, Goroutine: uint64(e.Goroutine), Function: e.Function}
+}
+
+func (e LockWaitEdge) internal() detector.LockWaitEdge {
+	return detector.LockWaitEdge{From: e.From, To: e.To, Goroutine: trace.GoID(e.Goroutine), Function: e.Function}
+}
+
+func fromThresholds(t detector.Thresholds) Thresholds { return Thresholds(t) }
+
+func (t Thresholds) internal() detector.Thresholds { return detector.Thresholds(t) }
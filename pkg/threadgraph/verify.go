@@ -0,0 +1,199 @@
+package threadgraph
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/trace"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Heman10x-NGU/threadgraph/internal/detector"
+	"github.com/Heman10x-NGU/threadgraph/internal/reporter"
+	"github.com/Heman10x-NGU/threadgraph/internal/suppress"
+)
+
+// Option configures VerifyNoLeaks and VerifyTestMain.
+type Option func(*config)
+
+type config struct {
+	minBlock  time.Duration
+	settle    time.Duration
+	ignoreTop map[string]bool
+}
+
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		minBlock:  DefaultMinBlock,
+		settle:    time.Second,
+		ignoreTop: make(map[string]bool),
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// MinBlock sets how long a goroutine blocked on something other than a
+// channel must have been blocked to be reported (default DefaultMinBlock), like the
+// CLI's --min-block.
+func MinBlock(d time.Duration) Option {
+	return func(c *config) { c.minBlock = d }
+}
+
+// Settle sets how long to wait at the end of the test for the goroutine
+// count to fall back to where it was when tracing started (default 1s).
+// Goroutines that are still shutting down are not blocked yet and would
+// otherwise be missed or misreported.
+func Settle(d time.Duration) Option {
+	return func(c *config) { c.settle = d }
+}
+
+// IgnoreTopFunction drops findings whose top user-code function is fn
+// (e.g. "github.com/org/pkg.(*Pool).worker"), for goroutines a test leaves
+// running on purpose.
+func IgnoreTopFunction(fn string) Option {
+	return func(c *config) { c.ignoreTop[fn] = true }
+}
+
+// VerifyNoLeaks starts an in-process execution trace and registers a cleanup
+// that stops it, runs the detectors over it, and fails t with the formatted
+// findings for goroutines started while the test ran. Call it first in the
+// test so that the check runs after the test's other cleanups.
+//
+// Only one execution trace can run at a time, so the check is skipped (with
+// a log message) under `go test -trace`, `threadgraph run`, or when another
+// test calling VerifyNoLeaks runs in parallel.
+func VerifyNoLeaks(t testing.TB, opts ...Option) {
+	t.Helper()
+	cfg := newConfig(opts)
+
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		t.Logf("threadgraph: cannot start execution trace (%v); skipping leak check", err)
+		return
+	}
+	baseline := runtime.NumGoroutine()
+
+	t.Cleanup(func() {
+		t.Helper()
+		settle(baseline, cfg.settle)
+		trace.Stop()
+
+		result, err := analyze(&buf, cfg)
+		if err != nil {
+			t.Errorf("threadgraph: analyze trace: %v", err)
+			return
+		}
+		result.TraceFile = "in-process trace of " + t.Name()
+		result.Findings = ownFindings(result.Findings, t.Name())
+		if len(result.Findings) > 0 {
+			t.Error(report(result))
+		}
+	})
+}
+
+// VerifyTestMain runs m with an in-process execution trace, then exits: with
+// m's exit code if the tests failed, else with 1 and the formatted findings
+// on stderr if any test left goroutines leaked or deadlocked, else 0. Use it
+// as the whole body of TestMain:
+//
+//	func TestMain(m *testing.M) {
+//		threadgraph.VerifyTestMain(m)
+//	}
+func VerifyTestMain(m *testing.M, opts ...Option) {
+	cfg := newConfig(opts)
+
+	// The test binary's own -test.trace would conflict with ours.
+	flag.Parse()
+	if f := flag.Lookup("test.trace"); f != nil && f.Value.String() != "" {
+		os.Exit(m.Run())
+	}
+
+	var buf bytes.Buffer
+	if err := trace.Start(&buf); err != nil {
+		fmt.Fprintf(os.Stderr, "threadgraph: cannot start execution trace (%v); skipping leak check\n", err)
+		os.Exit(m.Run())
+	}
+	baseline := runtime.NumGoroutine()
+
+	code := m.Run()
+	settle(baseline, cfg.settle)
+	trace.Stop()
+
+	if code == 0 {
+		result, err := analyze(&buf, cfg)
+		switch {
+		case err != nil:
+			fmt.Fprintf(os.Stderr, "threadgraph: analyze trace: %v\n", err)
+			code = 1
+		case len(result.Findings) > 0:
+			result.TraceFile = "in-process trace of " + os.Args[0]
+			fmt.Fprintln(os.Stderr, report(result))
+			code = 1
+		}
+	}
+	os.Exit(code)
+}
+
+// settle waits up to d for the goroutine count to drop to baseline.
+func settle(baseline int, d time.Duration) {
+	deadline := time.Now().Add(d)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// analyze runs the detectors over a captured trace. Goroutines that existed
+// before tracing started are not the traced tests' doing and are excluded.
+func analyze(buf *bytes.Buffer, cfg *config) (*detector.Result, error) {
+	result, err := detector.AnalyzeReader(buf, detector.Options{
+		MinBlock:          cfg.minBlock,
+		IgnorePreexisting: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	kept := result.Findings[:0]
+	for _, f := range result.Findings {
+		if !cfg.ignoreTop[f.Function] {
+			kept = append(kept, f)
+		}
+	}
+	result.Findings = kept
+	for _, w := range suppress.ApplyInline(result) {
+		fmt.Fprintf(os.Stderr, "threadgraph: %s\n", w)
+	}
+	return result, nil
+}
+
+// ownFindings keeps the findings attributed to the top-level test of name,
+// or to no test. Tests running in parallel share the trace.
+func ownFindings(findings []detector.Finding, name string) []detector.Finding {
+	top, _, _ := strings.Cut(name, "/")
+	var out []detector.Finding
+	for _, f := range findings {
+		tests := f.TestNames()
+		own := len(tests) == 0
+		for _, t := range tests {
+			if ft, _, _ := strings.Cut(t, "/"); ft == top {
+				own = true
+			}
+		}
+		if own {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// report formats result the way the CLI's terminal output does.
+func report(result *detector.Result) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "threadgraph: found %d concurrency issue(s) in goroutines started by the test:\n", len(result.Findings))
+	reporter.WriteTerminal(&b, result, "")
+	return strings.TrimRight(b.String(), "\n")
+}
@@ -0,0 +1,41 @@
+package regionwindow
+
+import (
+	"context"
+	"runtime/trace"
+	"testing"
+	"time"
+)
+
+// TestRegionWindow runs a "phase" region with a nested "phase" region inside
+// it, for threadgraph --region phase.
+//
+// Timeline:
+//  1. early blocks before the region starts
+//  2. The outer and then the inner "phase" region begin; the inner one ends
+//     after 20ms
+//  3. Still inside the outer region, inside blocks
+//  4. The outer region ends 100ms later, after which late blocks
+//
+// Analyzing the region should report early and inside as leaks, early
+// blocked no longer than the region lasted, and not report late.
+func TestRegionWindow(t *testing.T) {
+	ctx := context.Background()
+
+	early := make(chan int)
+	go func() { <-early }()
+	time.Sleep(50 * time.Millisecond)
+
+	trace.WithRegion(ctx, "phase", func() {
+		trace.WithRegion(ctx, "phase", func() {
+			time.Sleep(20 * time.Millisecond)
+		})
+		inside := make(chan int)
+		go func() { <-inside }()
+		time.Sleep(100 * time.Millisecond)
+	})
+
+	late := make(chan int)
+	go func() { <-late }()
+	time.Sleep(100 * time.Millisecond)
+}
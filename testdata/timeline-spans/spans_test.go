@@ -0,0 +1,36 @@
+package timelinespans
+
+import (
+	"testing"
+	"time"
+)
+
+// TestTimelineSpans records goroutines for the --format html timeline.
+//
+// Timeline:
+//  1. The churner receives 1000 values, blocking and waking for each one,
+//     more state changes than a timeline keeps per goroutine
+//  2. It then waits for a value that never comes
+//  3. Three waiters started by the same 'go' statement block forever
+//
+// ThreadGraph should report the churner's leak, and one leak with Count 3
+// for the waiters.
+func TestTimelineSpans(t *testing.T) {
+	tick := make(chan int)
+	go func() { // churner
+		for range 1000 {
+			<-tick
+		}
+		<-tick
+	}()
+	for i := range 1000 {
+		tick <- i
+		time.Sleep(10 * time.Microsecond)
+	}
+
+	never := make(chan int)
+	for range 3 {
+		go func() { <-never }()
+	}
+	time.Sleep(300 * time.Millisecond)
+}
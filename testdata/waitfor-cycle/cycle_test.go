@@ -0,0 +1,41 @@
+package waitforcycle
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWaitForCycle reproduces a deadlock between two goroutines that wake
+// each other: a ping-pong over two unbuffered channels where the pinger
+// waits for one reply too many.
+//
+// Timeline:
+//  1. The ponger echoes every value it receives on a back on b
+//  2. The pinger sends n+1 values, waiting for each reply
+//  3. The pinger then waits for one more reply, which never comes, while the
+//     ponger waits for another value on a
+//
+// Each goroutine was last woken by the other and is now blocked waiting for
+// it, so the wait-for graph has the cycle pinger → ponger → pinger.
+// ThreadGraph should report a deadlock with "wait-for cycle" besides the two
+// goroutine leaks.
+func TestWaitForCycle(t *testing.T) {
+	a, b := make(chan int), make(chan int)
+	const n = 3
+
+	go func() { // ponger
+		for {
+			v := <-a
+			b <- v
+		}
+	}()
+	go func() { // pinger
+		for i := 0; i < n+1; i++ {
+			a <- i
+			<-b
+		}
+		<-b // waits for the ponger forever
+	}()
+
+	time.Sleep(300 * time.Millisecond)
+}